@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// accessLevels maps names of access levels to their values in GitLab API.
+var accessLevels = map[string]int{ //nolint:gochecknoglobals
+	"no_access":      0,
+	"minimal_access": 5,
+	"guest":          10,
+	"reporter":       20,
+	"developer":      30,
+	"maintainer":     40,
+	"owner":          50,
+	"admin":          60,
+}
+
+// accessLevelFromName replaces the name of the access level in the field
+// of the object with its value. Values which are not strings are kept as-is.
+func accessLevelFromName(object map[string]interface{}, field string) errors.E {
+	name, ok := object[field].(string)
+	if !ok {
+		return nil
+	}
+
+	level, ok := accessLevels[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(accessLevels))
+		for n := range accessLevels {
+			names = append(names, n)
+		}
+		sort.Slice(names, func(i, j int) bool { return accessLevels[names[i]] < accessLevels[names[j]] })
+		errE := errors.Errorf(`unknown access level in field "%s"`, field)
+		errors.Details(errE)["value"] = name
+		errors.Details(errE)["known"] = names
+		return errE
+	}
+
+	object[field] = level
+	return nil
+}
+
+// accessLevelToName replaces the value of the access level in the field
+// of the object with its name. Unknown values are kept as-is.
+func accessLevelToName(object map[string]interface{}, field string) {
+	level, ok := object[field].(int)
+	if !ok {
+		return
+	}
+
+	for name, l := range accessLevels {
+		if l == level {
+			object[field] = name
+			return
+		}
+	}
+}
+
+// allowAccessLevelNames changes descriptions of fields holding access levels
+// so that their type allows names of access levels as well.
+func allowAccessLevelNames(descriptions map[string]string, fields ...string) {
+	for _, field := range fields {
+		description, ok := descriptions[field]
+		if !ok {
+			continue
+		}
+		i := strings.LastIndex(description, "Type: ")
+		if i < 0 {
+			continue
+		}
+		descriptions[field] = fmt.Sprintf("%sType: %s or string", description[:i], description[i+len("Type: "):])
+	}
+}
+
+// accessLevelsToNames replaces values of access levels of access levels listed
+// in the field of the protected branch or tag with their names.
+func accessLevelsToNames(protected map[string]interface{}, field string) {
+	levels, ok := protected[field].([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, level := range levels {
+		l, ok := level.(map[string]interface{})
+		if ok {
+			accessLevelToName(l, "access_level")
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLevelFromName(t *testing.T) {
+	t.Parallel()
+
+	object := map[string]interface{}{"access_level": "Maintainer", "group_access": 30, "other": "developer"}
+	errE := accessLevelFromName(object, "access_level")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	errE = accessLevelFromName(object, "group_access")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]interface{}{"access_level": 40, "group_access": 30, "other": "developer"}, object)
+
+	object = map[string]interface{}{"access_level": "no_access"}
+	errE = accessLevelFromName(object, "access_level")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]interface{}{"access_level": 0}, object)
+
+	object = map[string]interface{}{"access_level": "superuser"}
+	errE = accessLevelFromName(object, "access_level")
+	assert.EqualError(t, errE, `unknown access level in field "access_level"`)
+}
+
+func TestAccessLevelToName(t *testing.T) {
+	t.Parallel()
+
+	protectedBranch := map[string]interface{}{
+		"name": "main",
+		"allowed_to_push": []interface{}{
+			map[string]interface{}{"access_level": 60},
+			map[string]interface{}{"access_level": 0},
+			map[string]interface{}{"access_level": nil, "user_id": 5},
+			map[string]interface{}{"access_level": 35},
+		},
+	}
+	accessLevelsToNames(protectedBranch, "allowed_to_push")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"access_level": "admin"},
+		map[string]interface{}{"access_level": "no_access"},
+		map[string]interface{}{"access_level": nil, "user_id": 5},
+		map[string]interface{}{"access_level": 35},
+	}, protectedBranch["allowed_to_push"])
+}
+
+func TestAllowAccessLevelNames(t *testing.T) {
+	t.Parallel()
+
+	descriptions := map[string]string{
+		"group_access": "The role (access_level) to grant the group. Type: integer",
+		"group_id":     "The ID of the group to share with. Type: integer",
+	}
+	allowAccessLevelNames(descriptions, "group_access", "missing")
+	assert.Equal(t, map[string]string{
+		"group_access": "The role (access_level) to grant the group. Type: integer or string",
+		"group_id":     "The ID of the group to share with. Type: integer",
+	}, descriptions)
+}
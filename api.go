@@ -0,0 +1,215 @@
+package config
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Logger is used to report progress. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Options configures Fetch and Apply.
+type Options struct {
+	// Group is true if configuration of a group is managed instead of a project.
+	Group bool
+
+	// Only and Skip select configuration sections to process, like
+	// --only and --skip command line flags. By default all are processed.
+	Only []string
+	Skip []string
+
+	// Docs is where GitLab's API documentation is obtained from.
+	// If Docs.Ref is empty, DefaultDocsRef is used.
+	Docs Docs
+
+	// IDs makes Fetch reference users, groups, and projects by their IDs
+	// instead of usernames and full paths.
+	IDs bool
+
+	// AccessLevelNames makes Fetch reference access levels by their names
+	// instead of integers.
+	AccessLevelNames bool
+
+	// Avatar is the path where Fetch saves project's avatar. File extension is set
+	// automatically. If empty, the avatar configuration section is not fetched.
+	Avatar string
+
+	// Workers is the maximum number of configuration sections (and pipeline schedules)
+	// fetched concurrently. Values less than 1 mean 1.
+	Workers int
+
+	// Snapshot is the path where Apply saves configuration as it is before any changes.
+	// If empty, the snapshot is not saved.
+	Snapshot string
+
+	// Rollback makes Apply reapply the snapshot to configuration sections
+	// which have already been updated if updating fails. It requires Snapshot.
+	Rollback bool
+
+	// NoPrune makes Apply not delete objects which exist in GitLab but are
+	// missing from the configuration.
+	NoPrune bool
+
+	// RotateBefore makes Apply rotate tokens with "expires_in" field which
+	// expire sooner than this. If zero, tokens are rotated only after they expire.
+	RotateBefore time.Duration
+
+	// TokensOutput is the path where Apply saves values of new tokens.
+	// It is required when tokens are created or rotated, unless Input is set.
+	TokensOutput string
+
+	// Input is the path of the configuration file the configuration passed
+	// to Apply was read from. If TokensOutput is empty, Apply saves values of
+	// new tokens into it, under field names with EncSuffix.
+	Input     string
+	EncSuffix string
+
+	// Ignore maps names of configuration sections to rules matching objects
+	// which Fetch skips, like Ignore field of Configuration.
+	Ignore map[string][]map[string]string
+
+	// Comments makes Fetch keep comments (e.g., descriptions of fields) which
+	// the get command writes into the file. See Configuration for how they are stored.
+	Comments bool
+
+	// Logger is used to report progress. If nil, progress is not reported.
+	Logger Logger
+}
+
+// Result describes changes made by Apply.
+type Result struct {
+	// Applied are names of configuration sections which were updated.
+	Applied []string
+
+	// RolledBack are names of configuration sections which were rolled back
+	// after updating failed.
+	RolledBack []string
+}
+
+// target returns Target for the project (or group, if Group is true).
+func (o *Options) target(project string) Target {
+	t := Target{
+		Project: project,
+		Group:   "",
+		Docs:    o.Docs,
+		Logger:  o.Logger,
+		names:   newNameResolver(),
+	}
+	if o.Group {
+		t.Project = ""
+		t.Group = project
+	}
+	if t.Docs.Ref == "" {
+		t.Docs.Ref = DefaultDocsRef
+	}
+	return t
+}
+
+// resources returns registered group resources if Group is true and registered
+// project resources otherwise, selected by Only and Skip.
+func (o *Options) resources() ([]Resource, errors.E) {
+	all := Resources()
+	if o.Group {
+		all = GroupResources()
+	}
+	sections := Sections{
+		Only: o.Only,
+		Skip: o.Skip,
+	}
+	return sections.resources(all)
+}
+
+// getter returns Getter for the target.
+func (o *Options) getter(target Target) *Getter {
+	return &Getter{
+		Target:           target,
+		IDs:              o.IDs,
+		AccessLevelNames: o.AccessLevelNames,
+		Avatar:           o.Avatar,
+		Workers:          o.workers(),
+	}
+}
+
+// setter returns Setter for the target.
+func (o *Options) setter(target Target) *Setter {
+	return &Setter{
+		Target:       target,
+		NoPrune:      o.NoPrune,
+		RotateBefore: o.RotateBefore,
+		TokensOutput: o.TokensOutput,
+		Input:        o.Input,
+		EncSuffix:    o.EncSuffix,
+	}
+}
+
+// workers returns the number of workers to use.
+func (o *Options) workers() int {
+	return max(o.Workers, 1)
+}
+
+// Fetch fetches configuration of the GitLab project (or group, if opts.Group is true)
+// using client. Project (or group) can be an ID or <namespace/path>.
+//
+// Returned configuration is the same as the one read from the file saved by the get
+// command, so it can be passed to Apply as-is. Configuration sections which were
+// not fetched are nil.
+func Fetch(ctx context.Context, client *gitlab.Client, project string, opts Options) (*Configuration, errors.E) {
+	if opts.Avatar == "" && !opts.Group {
+		opts.Skip = append(slices.Clone(opts.Skip), "avatar")
+	}
+
+	resources, errE := opts.resources()
+	if errE != nil {
+		return nil, errE
+	}
+
+	configuration, errE := getConfiguration(ctx, client, opts.getter(opts.target(project)), resources, opts.Ignore)
+	if errE != nil {
+		return nil, errE
+	}
+
+	if opts.Comments {
+		return configuration, nil
+	}
+
+	// We convert the configuration to YAML and back to get exactly what
+	// would be read from the file (i.e., without comments).
+	data, errE := toConfigurationYAML(configuration, excludedSections(resources))
+	if errE != nil {
+		return nil, errE
+	}
+
+	var result Configuration
+	err := yaml.Unmarshal(data, &result)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot unmarshal configuration")
+	}
+
+	return &result, nil
+}
+
+// Apply updates configuration of the GitLab project (or group, if opts.Group is true)
+// using client based on the configuration. Project (or group) can be an ID or
+// <namespace/path>. Configuration sections which are nil are not updated.
+//
+// If ctx is canceled, the current request is aborted and no further configuration
+// sections are updated. Result is returned also when an error is returned.
+func Apply(ctx context.Context, client *gitlab.Client, project string, configuration *Configuration, opts Options) (Result, errors.E) {
+	if opts.Rollback && opts.Snapshot == "" {
+		return Result{Applied: []string{}, RolledBack: []string{}}, errors.New("rollback requires a snapshot")
+	}
+
+	resources, errE := opts.resources()
+	if errE != nil {
+		return Result{Applied: []string{}, RolledBack: []string{}}, errE
+	}
+
+	return applyConfiguration(ctx, client, opts.target(project), resources, configuration, opts)
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestFetchApply(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v4/projects/group%2Fproject/labels":
+			_, _ = w.Write([]byte(`[{"id":1,"name":"bug","color":"#ff0000","description":"Bugs.","open_issues_count":3}]`))
+		case "PUT /api/v4/projects/group%2Fproject/labels/1":
+			_, _ = w.Write([]byte(`{}`))
+		case "POST /api/v4/projects/group%2Fproject/labels":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := gitlab.NewClient("", gitlab.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	opts := Options{
+		Only:   []string{"labels"},
+		Logger: log.New(&logs, "", 0),
+	}
+
+	configuration, errE := Fetch(t.Context(), client, "group/project", opts)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Nil(t, configuration.Project)
+	assert.Nil(t, configuration.Variables)
+	require.Len(t, configuration.Labels, 1)
+	assert.Equal(t, 1, configuration.Labels[0]["id"])
+	assert.Equal(t, "bug", configuration.Labels[0]["name"])
+	assert.NotContains(t, configuration.Labels[0], "open_issues_count")
+	assert.Equal(t, "Getting project labels...\n", logs.String())
+
+	configuration.Labels = append(configuration.Labels, map[string]interface{}{
+		"name":  "feature",
+		"color": "#00ff00",
+	})
+
+	result, errE := Apply(t.Context(), client, "group/project", configuration, opts)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, Result{Applied: []string{"labels"}, RolledBack: []string{}}, result)
+
+	assert.Equal(t, []string{
+		"GET /api/v4/projects/group%2Fproject/labels",
+		"GET /api/v4/projects/group%2Fproject/labels",
+		"PUT /api/v4/projects/group%2Fproject/labels/1",
+		"POST /api/v4/projects/group%2Fproject/labels",
+	}, requests)
+
+	opts.Rollback = true
+	_, errE = Apply(t.Context(), client, "group/project", configuration, opts)
+	assert.EqualError(t, errE, "rollback requires a snapshot")
+}
+
+func TestApplyNoPrune(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v4/projects/group%2Fproject/labels":
+			_, _ = w.Write([]byte(`[{"id":1,"name":"bug","color":"#ff0000"},{"id":2,"name":"old","color":"#0000ff"}]`))
+		case "PUT /api/v4/projects/group%2Fproject/labels/1", "DELETE /api/v4/projects/group%2Fproject/labels/2":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := gitlab.NewClient("", gitlab.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	configuration := &Configuration{
+		Labels: []map[string]interface{}{
+			{"name": "bug", "color": "#ff0000"},
+		},
+	}
+
+	for _, tt := range []struct {
+		name    string
+		prune   map[string]bool
+		noPrune bool
+		deleted bool
+	}{
+		{"prune", nil, false, true},
+		{"section", map[string]bool{"labels": false}, false, false},
+		{"other section", map[string]bool{"variables": false}, false, true},
+		{"flag", nil, true, false},
+	} {
+		mu.Lock()
+		requests = []string{}
+		mu.Unlock()
+
+		configuration.Prune = tt.prune
+		_, errE := Apply(t.Context(), client, "group/project", configuration, Options{Only: []string{"labels"}, NoPrune: tt.noPrune})
+		require.NoError(t, errE, "% -+#.1v", errE)
+
+		mu.Lock()
+		if tt.deleted {
+			assert.Contains(t, requests, "DELETE /api/v4/projects/group%2Fproject/labels/2", tt.name)
+		} else {
+			assert.NotContains(t, requests, "DELETE /api/v4/projects/group%2Fproject/labels/2", tt.name)
+		}
+		mu.Unlock()
+	}
+}
+
+// testServer is a test GitLab API server which records requests made to it.
+type testServer struct {
+	mu       sync.Mutex
+	requests []string
+	client   *gitlab.Client
+}
+
+// newTestServer starts a test GitLab API server which responds to requests
+// with JSON bodies from responses, keyed by request method and escaped path.
+// An empty body responds with 204 No Content and a missing one with 404 Not Found.
+func newTestServer(t *testing.T, responses map[string]string) *testServer {
+	t.Helper()
+
+	s := &testServer{requests: []string{}} //nolint:exhaustruct
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := r.Method + " " + r.URL.EscapedPath()
+		if r.URL.RawQuery != "" {
+			request += "?" + r.URL.RawQuery
+		}
+		body, _ := io.ReadAll(r.Body)
+		if len(body) > 0 {
+			request += " " + string(body)
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, request)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		response, ok := responses[r.Method+" "+r.URL.EscapedPath()]
+		switch {
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+		case response == "":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = w.Write([]byte(response))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := gitlab.NewClient("", gitlab.WithBaseURL(server.URL))
+	require.NoError(t, err)
+	s.client = client
+
+	return s
+}
+
+// fetchApply fetches the configuration of the project "group/project",
+// passes it to change, and applies the changed configuration.
+// It returns requests made while doing so.
+func (s *testServer) fetchApply(t *testing.T, opts Options, change func(configuration *Configuration)) []string {
+	t.Helper()
+
+	configuration, errE := Fetch(t.Context(), s.client, "group/project", opts)
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	change(configuration)
+
+	_, errE = Apply(t.Context(), s.client, "group/project", configuration, opts)
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
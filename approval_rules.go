@@ -1,9 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"slices"
 	"sort"
 
@@ -12,35 +12,83 @@ import (
 	"gitlab.com/tozd/go/errors"
 )
 
+// approvalRulesResource is a Resource for merge requests approval rules.
+type approvalRulesResource struct{}
+
+// Name implements Resource interface.
+func (approvalRulesResource) Name() string {
+	return "approval_rules"
+}
+
+// Descriptions implements Resource interface.
+func (approvalRulesResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getApprovalRulesDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (approvalRulesResource) Sensitive() []string {
+	return nil
+}
+
+// Keys implements KeyedResource interface.
+func (approvalRulesResource) Keys() [][]string {
+	return [][]string{{"id"}, {"name"}}
+}
+
+// Describe implements KeyedResource interface.
+func (approvalRulesResource) Describe() []string {
+	return []string{"name"}
+}
+
+// Required implements RequiredResource interface.
+func (approvalRulesResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getApprovalRulesRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (approvalRulesResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getApprovalRules(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (approvalRulesResource) Resolve(ctx context.Context, t *Target, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	return t.resolveApprovalRules(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (approvalRulesResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateApprovalRules(ctx, client, configuration)
+}
+
 // getApprovalRules populates configuration struct with GitLab's project's merge requests
 // approval rules available from GitLab approvals API endpoint.
-func (c *GetCommand) getApprovalRules(client *gitlab.Client, configuration *Configuration) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting approval rules...\n")
+func (g *Getter) getApprovalRules(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting approval rules...\n")
 
 	configuration.ApprovalRules = []map[string]interface{}{}
 
-	descriptions, errE := getApprovalRulesDescriptions(c.DocsRef)
+	descriptions, errE := getApprovalRulesDescriptions(ctx, g.Docs)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 	// We need "id" later on.
 	if _, ok := descriptions["id"]; !ok {
-		return false, errors.New(`"id" field is missing in approval rules descriptions`)
+		return errors.New(`"id" field is missing in approval rules descriptions`)
 	}
 	configuration.ApprovalRulesComment = formatDescriptions(descriptions)
 
-	u := fmt.Sprintf("projects/%s/approval_rules", gitlab.PathEscape(c.Project))
+	u := fmt.Sprintf("projects/%s/approval_rules", gitlab.PathEscape(g.Project))
 	options := &gitlab.GetProjectApprovalRulesListsOptions{
 		PerPage: maxGitLabPageSize,
 		Page:    1,
 	}
 
 	for {
-		req, err := client.NewRequest(http.MethodGet, u, options, nil)
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get approval rules")
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		approvalRules := []map[string]interface{}{}
@@ -49,7 +97,7 @@ func (c *GetCommand) getApprovalRules(client *gitlab.Client, configuration *Conf
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get approval rules")
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		if len(approvalRules) == 0 {
@@ -72,10 +120,31 @@ func (c *GetCommand) getApprovalRules(client *gitlab.Client, configuration *Conf
 				if err != nil {
 					errE := errors.WithMessagef(err, `unable to convert "%s" to "%s" for approval rule`, ii.From, ii.To)
 					errors.Details(errE)["approvalRule"] = approvalRule["id"]
-					return false, errE
+					return errE
 				}
 			}
 
+			for _, ii := range []struct {
+				From  string
+				To    string
+				Field string
+			}{
+				{"users", "user_ids", "username"},
+				{"groups", "group_ids", "full_path"},
+			} {
+				if g.IDs {
+					delete(approvalRule, ii.From)
+					continue
+				}
+				approvalRule[ii.From], err = convertNestedObjectsToNames(approvalRule[ii.From], ii.Field)
+				if err != nil {
+					errE := errors.WithMessagef(err, `unable to convert "%s" to names for approval rule`, ii.From)
+					errors.Details(errE)["approvalRule"] = approvalRule["id"]
+					return errE
+				}
+				delete(approvalRule, ii.To)
+			}
+
 			// Protected branches which exist are listed when applies_to_all_protected_branches
 			// is set to true, but they are ignored. So we remove them here as well.
 			appliesToAllProtectedBranches, ok := approvalRule["applies_to_all_protected_branches"]
@@ -97,14 +166,14 @@ func (c *GetCommand) getApprovalRules(client *gitlab.Client, configuration *Conf
 
 			id, ok := approvalRule["id"]
 			if !ok {
-				return false, errors.New(`approval rule is missing field "id"`)
+				return errors.New(`approval rule is missing field "id"`)
 			}
 			_, ok = id.(int)
 			if !ok {
 				errE := errors.New(`approval rule's field "id" is not an integer`)
 				errors.Details(errE)["type"] = fmt.Sprintf("%T", id)
 				errors.Details(errE)["value"] = id
-				return false, errE
+				return errE
 			}
 
 			configuration.ApprovalRules = append(configuration.ApprovalRules, approvalRule)
@@ -123,32 +192,34 @@ func (c *GetCommand) getApprovalRules(client *gitlab.Client, configuration *Conf
 		return configuration.ApprovalRules[i]["id"].(int) < configuration.ApprovalRules[j]["id"].(int) //nolint:forcetypeassert,errcheck
 	})
 
-	return false, nil
+	return nil
+}
+
+// approvalRulesKeyMapper maps fields in GitLab's documentation for approval rules.
+func approvalRulesKeyMapper(key string) string {
+	switch key {
+	case "usernames":
+		// We want only "used_ids".
+		// See: https://gitlab.com/gitlab-org/gitlab/-/issues/419051
+		return ""
+	case "report_type":
+		// "report_type" will be deprecated and is not needed.
+		// See: https://gitlab.com/gitlab-org/gitlab/-/issues/419050
+		return ""
+	default:
+		return key
+	}
 }
 
 // parseApprovalRulesDocumentation parses GitLab's documentation in Markdown for
 // approvals API endpoint and extracts description of fields used to describe
 // payload for project's merge requests approval rules.
 func parseApprovalRulesDocumentation(input []byte) (map[string]string, errors.E) {
-	keyMapper := func(key string) string {
-		switch key {
-		case "usernames":
-			// We want only "used_ids".
-			// See: https://gitlab.com/gitlab-org/gitlab/-/issues/419051
-			return ""
-		case "report_type":
-			// "report_type" will be deprecated and is not needed.
-			// See: https://gitlab.com/gitlab-org/gitlab/-/issues/419050
-			return ""
-		default:
-			return key
-		}
-	}
-	newDescriptions, err := parseTable(input, "Create project-level rule", keyMapper)
+	newDescriptions, err := parseTable(input, "Create project-level rule", approvalRulesKeyMapper)
 	if err != nil {
 		return nil, err
 	}
-	editDescriptions, err := parseTable(input, "Update project-level rule", keyMapper)
+	editDescriptions, err := parseTable(input, "Update project-level rule", approvalRulesKeyMapper)
 	if err != nil {
 		return nil, err
 	}
@@ -159,22 +230,69 @@ func parseApprovalRulesDocumentation(input []byte) (map[string]string, errors.E)
 
 // getApprovalRulesDescriptions obtains description of fields used to describe payload for
 // project's merge requests approval rules from GitLab's documentation for approvals API endpoint.
-func getApprovalRulesDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/merge_request_approvals.md", gitRef))
+func getApprovalRulesDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "merge_request_approvals.md")
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get approval rules descriptions")
 	}
-	return parseApprovalRulesDocumentation(data)
+	descriptions, errE := parseApprovalRulesDocumentation(data)
+	if errE != nil {
+		return nil, errE
+	}
+	// Users and groups can be referenced by their usernames and full paths instead of IDs.
+	descriptions["users"] = "The usernames of users as approvers, instead of user_ids. Type: Array"
+	descriptions["groups"] = "The full paths of groups as approvers, instead of group_ids. Type: Array"
+	return descriptions, nil
+}
+
+// getApprovalRulesRequired obtains fields required to create an individual approval rule
+// from GitLab's documentation for approvals API endpoint.
+func getApprovalRulesRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "merge_request_approvals.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get approval rules required fields")
+	}
+	return parseRequired(data, "Create project-level rule", approvalRulesKeyMapper)
+}
+
+// resolveApprovalRules returns a copy of the configuration struct in which usernames
+// in "users" fields and full paths of groups in "groups" fields of approval rules
+// are replaced with IDs in "user_ids" and "group_ids" fields.
+func (t *Target) resolveApprovalRules(ctx context.Context, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	if configuration.ApprovalRules == nil {
+		return configuration, nil
+	}
+
+	resolved := *configuration
+	resolved.ApprovalRules, _ = deepCopy(configuration.ApprovalRules).([]map[string]interface{})
+	for i, approvalRule := range resolved.ApprovalRules {
+		for _, ii := range []struct {
+			From    string
+			To      string
+			Resolve resolveFunc
+		}{
+			{"users", "user_ids", t.names.userID},
+			{"groups", "group_ids", t.names.groupID},
+		} {
+			errE := resolveField(ctx, client, approvalRule, ii.From, ii.To, ii.Resolve)
+			if errE != nil {
+				errors.Details(errE)["index"] = i
+				return nil, errE
+			}
+		}
+	}
+
+	return &resolved, nil
 }
 
 // updateApprovalRules updates GitLab project's merge requests approvals
 // using GitLab approvals API endpoint based on the configuration struct.
-func (c *SetCommand) updateApprovalRules(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updateApprovalRules(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
 	if configuration.ApprovalRules == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating approval rules...\n")
+	s.Printf("Updating approval rules...\n")
 
 	options := &gitlab.GetProjectApprovalRulesListsOptions{
 		PerPage: maxGitLabPageSize,
@@ -184,7 +302,7 @@ func (c *SetCommand) updateApprovalRules(client *gitlab.Client, configuration *C
 	approvalRules := []*gitlab.ProjectApprovalRule{}
 
 	for {
-		as, response, err := client.Projects.GetProjectApprovalRules(c.Project, options)
+		as, response, err := client.Projects.GetProjectApprovalRules(s.Project, options, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get approval rules")
 			errors.Details(errE)["page"] = options.Page
@@ -203,6 +321,10 @@ func (c *SetCommand) updateApprovalRules(client *gitlab.Client, configuration *C
 	existingApprovalRulesSet := mapset.NewThreadUnsafeSet[int]()
 	namesToIDs := map[string]int{}
 	for _, approvalRule := range approvalRules {
+		// Approval rules matching ignore rules are neither updated nor deleted.
+		if configuration.ignored("approval_rules", map[string]interface{}{"id": approvalRule.ID, "name": approvalRule.Name}) {
+			continue
+		}
 		namesToIDs[approvalRule.Name] = approvalRule.ID
 		existingApprovalRulesSet.Add(approvalRule.ID)
 	}
@@ -259,9 +381,13 @@ func (c *SetCommand) updateApprovalRules(client *gitlab.Client, configuration *C
 	}
 
 	extraApprovalRules := existingApprovalRulesSet.Difference(wantedApprovalRulesSet).ToSlice()
+	if !configuration.prune("approval_rules", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraApprovalRules = nil
+	}
 	slices.Sort(extraApprovalRules)
 	for _, approvalRuleID := range extraApprovalRules {
-		_, err := client.Projects.DeleteProjectApprovalRule(c.Project, approvalRuleID)
+		_, err := client.Projects.DeleteProjectApprovalRule(s.Project, approvalRuleID, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to delete approval rule")
 			errors.Details(errE)["approvalRule"] = approvalRuleID
@@ -280,8 +406,8 @@ func (c *SetCommand) updateApprovalRules(client *gitlab.Client, configuration *C
 
 		id, ok := approvalRule["id"]
 		if !ok { //nolint:dupl
-			u := fmt.Sprintf("projects/%s/approval_rules", gitlab.PathEscape(c.Project))
-			req, err := client.NewRequest(http.MethodPost, u, approvalRule, nil)
+			u := fmt.Sprintf("projects/%s/approval_rules", gitlab.PathEscape(s.Project))
+			req, err := client.NewRequest(http.MethodPost, u, approvalRule, contextOptions(ctx))
 			if err != nil {
 				// We made sure above that all approval rules in configuration without approval rule ID have name.
 				errE := errors.WithMessage(err, "failed to create approval rule")
@@ -301,8 +427,8 @@ func (c *SetCommand) updateApprovalRules(client *gitlab.Client, configuration *C
 			// We made sure above that all approval rules in configuration with approval rule
 			// ID exist and that they are ints.
 			iid := id.(int) //nolint:errcheck,forcetypeassert
-			u := fmt.Sprintf("projects/%s/approval_rules/%d", gitlab.PathEscape(c.Project), iid)
-			req, err := client.NewRequest(http.MethodPut, u, approvalRule, nil)
+			u := fmt.Sprintf("projects/%s/approval_rules/%d", gitlab.PathEscape(s.Project), iid)
+			req, err := client.NewRequest(http.MethodPut, u, approvalRule, contextOptions(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to update approval rule")
 				errors.Details(errE)["index"] = i
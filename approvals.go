@@ -1,37 +1,65 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/xanzy/go-gitlab"
 	"gitlab.com/tozd/go/errors"
 )
 
+// approvalsResource is a Resource for merge requests approvals settings.
+type approvalsResource struct{}
+
+// Name implements Resource interface.
+func (approvalsResource) Name() string {
+	return "approvals"
+}
+
+// Descriptions implements Resource interface.
+func (approvalsResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getApprovalsDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (approvalsResource) Sensitive() []string {
+	return nil
+}
+
+// Get implements Resource interface.
+func (approvalsResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getApprovals(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (approvalsResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateApprovals(ctx, client, configuration)
+}
+
 // getApprovals populates configuration struct with GitLab's project's merge requests
 // approvals available from GitLab approvals API endpoint.
-func (c *GetCommand) getApprovals(client *gitlab.Client, configuration *Configuration) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting approvals...\n")
+func (g *Getter) getApprovals(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting approvals...\n")
 
 	configuration.Approvals = map[string]interface{}{}
 
-	descriptions, errE := getApprovalsDescriptions(c.DocsRef)
+	descriptions, errE := getApprovalsDescriptions(ctx, g.Docs)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 
-	u := fmt.Sprintf("projects/%s/approvals", gitlab.PathEscape(c.Project))
-	req, err := client.NewRequest(http.MethodGet, u, nil, nil)
+	u := fmt.Sprintf("projects/%s/approvals", gitlab.PathEscape(g.Project))
+	req, err := client.NewRequest(http.MethodGet, u, nil, contextOptions(ctx))
 	if err != nil {
-		return false, errors.WithMessage(err, "failed to get approvals")
+		return errors.WithMessage(err, "failed to get approvals")
 	}
 
 	approvals := map[string]interface{}{}
 
 	_, err = client.Do(req, &approvals)
 	if err != nil {
-		return false, errors.WithMessage(err, "failed to get approvals")
+		return errors.WithMessage(err, "failed to get approvals")
 	}
 
 	// Only retain those keys which can be edited through the API
@@ -48,7 +76,7 @@ func (c *GetCommand) getApprovals(client *gitlab.Client, configuration *Configur
 
 	configuration.Approvals = approvals
 
-	return false, nil
+	return nil
 }
 
 // parseApprovalsDocumentation parses GitLab's documentation in Markdown for
@@ -60,8 +88,8 @@ func parseApprovalsDocumentation(input []byte) (map[string]string, errors.E) {
 
 // getApprovalsDescriptions obtains description of fields used to describe payload for
 // project's merge requests approvals from GitLab's documentation for approvals API endpoint.
-func getApprovalsDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/merge_request_approvals.md", gitRef))
+func getApprovalsDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "merge_request_approvals.md")
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get approvals descriptions")
 	}
@@ -70,15 +98,15 @@ func getApprovalsDescriptions(gitRef string) (map[string]string, errors.E) {
 
 // updateApprovals updates GitLab project's merge requests approvals using GitLab
 // approvals API endpoint based on the configuration struct.
-func (c *SetCommand) updateApprovals(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updateApprovals(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
 	if configuration.Approvals == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating approvals...\n")
+	s.Printf("Updating approvals...\n")
 
-	u := fmt.Sprintf("projects/%s/approvals", gitlab.PathEscape(c.Project))
-	req, err := client.NewRequest(http.MethodPost, u, configuration.Approvals, nil)
+	u := fmt.Sprintf("projects/%s/approvals", gitlab.PathEscape(s.Project))
+	req, err := client.NewRequest(http.MethodPost, u, configuration.Approvals, contextOptions(ctx))
 	if err != nil {
 		return errors.WithMessage(err, "failed to update approvals")
 	}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,6 +13,34 @@ import (
 	"gitlab.com/tozd/go/errors"
 )
 
+// avatarResource is a Resource for project avatar.
+type avatarResource struct{}
+
+// Name implements Resource interface.
+func (avatarResource) Name() string {
+	return "avatar"
+}
+
+// Descriptions implements Resource interface.
+func (avatarResource) Descriptions(context.Context, Docs) (map[string]string, errors.E) {
+	return nil, nil //nolint:nilnil
+}
+
+// Sensitive implements Resource interface.
+func (avatarResource) Sensitive() []string {
+	return nil
+}
+
+// Get implements Resource interface.
+func (avatarResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getAvatar(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (avatarResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateAvatar(ctx, client, configuration)
+}
+
 // A reasonable subset of supported file extensions for avatar image.
 // See: https://gitlab.com/gitlab-org/gitlab/-/blob/master/app/uploaders/avatar_uploader.rb
 // See: https://gitlab.com/gitlab-org/gitlab/-/blob/master/lib/gitlab/file_type_detection.rb#L22
@@ -38,10 +67,13 @@ func checkAvatarExtension(ext string) errors.E {
 
 // getAvatar populates configuration struct with GitLab's project avatar available
 // from GitLab projects API endpoint.
-func (c *GetCommand) getAvatar(
-	_ *gitlab.Client, project map[string]interface{}, configuration *Configuration,
-) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting avatar...\n")
+func (g *Getter) getAvatar(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting avatar...\n")
+
+	project, errE := getProject(ctx, client, g.Project)
+	if errE != nil {
+		return errE
+	}
 
 	avatarURLAny, ok := project["avatar_url"]
 	if ok && avatarURLAny != nil {
@@ -50,29 +82,29 @@ func (c *GetCommand) getAvatar(
 			errE := errors.New(`"avatar_url" is not a string`)
 			errors.Details(errE)["type"] = fmt.Sprintf("%T", avatarURLAny)
 			errors.Details(errE)["value"] = avatarURLAny
-			return false, errE
+			return errE
 		}
 		avatarExt := path.Ext(avatarURL)
 		errE := checkAvatarExtension(avatarExt)
 		if errE != nil {
 			errE = errors.WithMessage(errE, `invalid "avatar_url"`)
 			errors.Details(errE)["url"] = avatarURL
-			return false, errE
+			return errE
 		}
 		// TODO: Make this work for private avatars, too.
 		//       See: https://gitlab.com/gitlab-org/gitlab/-/issues/25498
-		avatar, errE := downloadFile(avatarURL)
+		avatar, errE := downloadFile(ctx, avatarURL)
 		if errE != nil {
 			errE = errors.WithMessage(errE, "failed to get project avatar")
 			errors.Details(errE)["url"] = avatarURL
-			return false, errE
+			return errE
 		}
-		avatarPath := strings.TrimSuffix(c.Avatar, path.Ext(c.Avatar)) + avatarExt
+		avatarPath := strings.TrimSuffix(g.Avatar, path.Ext(g.Avatar)) + avatarExt
 		err := os.WriteFile(avatarPath, avatar, fileMode)
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to save avatar")
 			errors.Details(errE)["path"] = avatarPath
-			return false, errE
+			return errE
 		}
 		configuration.Avatar = &avatarPath
 	} else {
@@ -80,24 +112,24 @@ func (c *GetCommand) getAvatar(
 		configuration.Avatar = &noAvatar
 	}
 
-	return false, nil
+	return nil
 }
 
 // updateAvatar updates GitLab project's avatar using GitLab projects API endpoint
 // based on the configuration struct.
-func (c *SetCommand) updateAvatar(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updateAvatar(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
 	if configuration.Avatar == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating avatar...\n")
+	s.Printf("Updating avatar...\n")
 
 	if *configuration.Avatar == "" {
-		u := "projects/" + gitlab.PathEscape(c.Project)
+		u := "projects/" + gitlab.PathEscape(s.Project)
 
 		// TODO: Make it really remove the avatar.
 		//       See: https://gitlab.com/gitlab-org/gitlab/-/issues/348498
-		req, err := client.NewRequest(http.MethodPut, u, map[string]interface{}{"avatar": nil}, nil)
+		req, err := client.NewRequest(http.MethodPut, u, map[string]interface{}{"avatar": nil}, contextOptions(ctx))
 		if err != nil {
 			return errors.WithMessage(err, "failed to delete GitLab project avatar")
 		}
@@ -115,7 +147,7 @@ func (c *SetCommand) updateAvatar(client *gitlab.Client, configuration *Configur
 		}
 		defer file.Close()
 		_, filename := filepath.Split(*configuration.Avatar)
-		_, _, err = client.Projects.UploadAvatar(c.Project, file, filename)
+		_, _, err = client.Projects.UploadAvatar(s.Project, file, filename, gitlab.WithContext(ctx))
 		if err != nil {
 			return errors.WithMessage(err, "failed to upload GitLab project avatar")
 		}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// ErrDrift is returned by the check command when GitLab's configuration
+// differs from the configuration file.
+var ErrDrift = errors.Base("configuration drift")
+
+// CheckCommand describes parameters for the check command.
+//
+//nolint:lll
+type CheckCommand struct {
+	PlanCommand
+
+	OutputFormat string `default:"text" enum:"text,json" help:"Format of the report. Possible: ${enum}. Default is \"${default}\"." placeholder:"FORMAT"`
+}
+
+// driftReport is a machine-readable report of differences between
+// GitLab's configuration and the configuration file.
+type driftReport struct {
+	Drift   bool             `json:"drift"`
+	Paths   []string         `json:"paths"`
+	Changes []resourceChange `json:"changes"`
+}
+
+// Run runs the check command.
+func (c *CheckCommand) Run(_ *Globals) errors.E {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	changes, errE := c.plan(ctx)
+	if errE != nil {
+		return errE
+	}
+
+	fmt.Fprintf(os.Stderr, "Compared everything.\n")
+
+	if c.OutputFormat == "json" {
+		errE = writeDriftReport(os.Stdout, changes)
+	} else {
+		errE = writeChanges(os.Stdout, changes)
+	}
+	if errE != nil {
+		return errE
+	}
+
+	if len(changes) > 0 {
+		return errors.WithStack(ErrDrift)
+	}
+	return nil
+}
+
+// changePaths returns paths of all values which differ.
+//
+// A path starts with the configuration section name, followed by the object's key
+// in brackets for lists of objects, and the field path, e.g., labels[name="bug"].color.
+func changePaths(changes []resourceChange) []string {
+	paths := []string{}
+	for _, change := range changes {
+		base := change.Resource
+		if change.Key != "" {
+			base += "[" + change.Key + "]"
+		}
+		if len(change.Fields) == 0 {
+			paths = append(paths, base)
+			continue
+		}
+		for _, field := range change.Fields {
+			if field.Path == "" {
+				paths = append(paths, base)
+			} else {
+				paths = append(paths, base+"."+field.Path)
+			}
+		}
+	}
+	return paths
+}
+
+// writeDriftReport writes a JSON report of changes to w.
+func writeDriftReport(w io.Writer, changes []resourceChange) errors.E {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(driftReport{
+		Drift:   len(changes) > 0,
+		Paths:   changePaths(changes),
+		Changes: changes,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "cannot write report")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDriftReport(t *testing.T) {
+	t.Parallel()
+
+	changes := []resourceChange{
+		{Resource: "project", Action: changeUpdate, Key: "", Fields: []fieldChange{{Path: "description", Old: "old", New: "new"}}},
+		{Resource: "forked_from_project", Action: changeUpdate, Key: "", Fields: []fieldChange{{Path: "", Old: nil, New: 42}}},
+		{Resource: "labels", Action: changeDelete, Key: `name="old"`, Fields: nil},
+	}
+
+	assert.Equal(t, []string{
+		"project.description",
+		"forked_from_project",
+		`labels[name="old"]`,
+	}, changePaths(changes))
+
+	buffer := bytes.Buffer{}
+	errE := writeDriftReport(&buffer, changes)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.JSONEq(t, `{
+		"drift": true,
+		"paths": ["project.description", "forked_from_project", "labels[name=\"old\"]"],
+		"changes": [
+			{"resource": "project", "action": "update", "fields": [{"path": "description", "old": "old", "new": "new"}]},
+			{"resource": "forked_from_project", "action": "update", "fields": [{"path": "", "old": null, "new": 42}]},
+			{"resource": "labels", "action": "delete", "key": "name=\"old\""}
+		]
+	}`, buffer.String())
+
+	buffer.Reset()
+	errE = writeDriftReport(&buffer, []resourceChange{})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.JSONEq(t, `{"drift": false, "paths": [], "changes": []}`, buffer.String())
+}
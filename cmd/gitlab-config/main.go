@@ -8,11 +8,15 @@ import (
 	"os"
 
 	"github.com/alecthomas/kong"
+	"gitlab.com/tozd/go/errors"
 
 	"gitlab.com/tozd/gitlab/config"
 )
 
-const exitCode = 2
+const (
+	exitCode      = 2
+	driftExitCode = 3
+)
 
 // These variables should be set during build time using "-X" ldflags.
 var (
@@ -40,7 +44,12 @@ func main() {
 	)
 
 	err := ctx.Run(&commands.Globals)
-	if err != nil {
+	if errors.Is(err, config.ErrDrift) {
+		ctx.Exit(driftExitCode)
+	} else if errors.Is(err, config.ErrInvalid) {
+		// Issues have already been written out.
+		ctx.Exit(exitCode)
+	} else if err != nil {
 		fmt.Fprintf(ctx.Stderr, "error: % -+#.1v", err)
 		ctx.Exit(exitCode)
 	}
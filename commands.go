@@ -1,17 +1,194 @@
 package config
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
 	"github.com/alecthomas/kong"
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
 )
 
 const DefaultDocsRef = "v16.4.0-ee"
 
 // GitLab describes parameters needed to connect to GitLab API.
 type GitLab struct {
-	Project string `                             env:"CI_PROJECT_ID"    help:"GitLab project to manage config for. It can be project ID or <namespace/project_path>. By default it infers it from the repository. Environment variable: ${env}."                                           short:"p"`
-	BaseURL string `default:"https://gitlab.com" env:"CI_SERVER_URL"    help:"Base URL for GitLab API to use. Default is \"${default}\". Environment variable: ${env}."                                                                          name:"base" placeholder:"URL"             short:"B"`
-	Token   string `                             env:"GITLAB_API_TOKEN" help:"GitLab API token to use. Environment variable: ${env}."                                                                                                                                          required:"" short:"t"`
-	DocsRef string `default:"${defaultDocsRef}"  env:"DOCS_GIT_REF"     help:"Git reference at which to extract API attributes from GitLab's documentation. Default is \"${default}\". Environment variable: ${env}."                            name:"docs" placeholder:"REF"             short:"D"`
+	Project        string        `                             env:"CI_PROJECT_ID"    help:"GitLab project to manage config for. It can be project ID or <namespace/project_path>. By default it infers it from the repository. Environment variable: ${env}."                                                short:"p"`
+	Group          string        `                                                    help:"GitLab group to manage config for instead of a project. It can be group ID or <namespace/group_path>."                                                                         placeholder:"GROUP"                short:"g"`
+	BaseURL        string        `default:"https://gitlab.com" env:"CI_SERVER_URL"    help:"Base URL for GitLab API to use. Default is \"${default}\". Environment variable: ${env}."                                                                          name:"base" placeholder:"URL"                  short:"B"`
+	Token          string        `                             env:"GITLAB_API_TOKEN" help:"GitLab API token to use. Environment variable: ${env}."                                                                                                                                               required:"" short:"t"`
+	DocsRef        string        `default:"${defaultDocsRef}"  env:"DOCS_GIT_REF"     help:"Git reference at which to extract API attributes from GitLab's documentation. Default is \"${default}\". Environment variable: ${env}."                            name:"docs" placeholder:"REF"                  short:"D"`
+	DocsCache      string        `                             env:"DOCS_CACHE"       help:"Where to cache downloaded GitLab's documentation. By default it is in user's cache directory. Set to \"-\" to disable caching. Environment variable: ${env}."                  placeholder:"PATH"`
+	Timeout        time.Duration `                                                    help:"Maximum duration of the whole command, e.g., 10m. Disabled by default."                                                                                                        placeholder:"DURATION"`
+	RequestTimeout time.Duration `default:"1m"                                        help:"Maximum duration of each request to GitLab API. Set to 0 to disable. Default is ${default}."                                                                                   placeholder:"DURATION"`
+	DocsDir        string        `                             env:"DOCS_DIR"         help:"Extract API attributes from GitLab's documentation in a local checkout of GitLab's repository instead. Environment variable: ${env}."                                          placeholder:"PATH"`
+
+	events *eventWriter
+	// client is shared by all resources so that they share the limit of concurrent requests.
+	client *gitlab.Client
+	logger Logger
+}
+
+// setup prepares the client to make at most workers concurrent requests
+// and to write events to events, if not nil. Progress is reported to stderr.
+func (g *GitLab) setup(workers int, events *eventWriter) errors.E {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert,errcheck
+	transport.MaxIdleConnsPerHost = workers
+	var roundTripper http.RoundTripper = &limitedTransport{
+		transport: transport,
+		slots:     make(chan struct{}, workers),
+	}
+	if events != nil {
+		roundTripper = &eventTransport{
+			transport: roundTripper,
+			events:    events,
+		}
+	}
+	httpClient := &http.Client{ //nolint:exhaustruct
+		Transport: roundTripper,
+		Timeout:   g.RequestTimeout,
+	}
+	client, err := gitlab.NewClient(g.Token, gitlab.WithBaseURL(g.BaseURL), gitlab.WithHTTPClient(httpClient))
+	if err != nil {
+		return errors.WithMessage(err, "failed to create GitLab API client instance")
+	}
+	g.client = client
+	g.events = events
+	g.logger = log.New(os.Stderr, "", 0)
+	return nil
+}
+
+// newContext returns a context which is canceled on interrupt
+// or when Timeout (if set) expires.
+func (g *GitLab) newContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if g.Timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, g.Timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// docs returns where GitLab's API documentation is obtained from.
+func (g *GitLab) docs() Docs {
+	return newDocs(g.DocsRef, g.DocsDir, g.DocsCache)
+}
+
+// newDocs returns Docs for the Git reference, the local checkout of GitLab's
+// repository, and the cache directory as provided through command line flags.
+func newDocs(ref, dir, cache string) Docs {
+	cacheDir := cache
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err == nil {
+			cacheDir = filepath.Join(userCacheDir, "gitlab-config", "docs")
+		}
+	} else if cacheDir == "-" {
+		cacheDir = ""
+	}
+
+	return Docs{
+		Ref:      ref,
+		Dir:      dir,
+		CacheDir: cacheDir,
+	}
+}
+
+// registeredResources returns registered group resources if Group is set
+// and registered project resources otherwise.
+func (g *GitLab) registeredResources() []Resource {
+	if g.Group != "" {
+		return GroupResources()
+	}
+	return Resources()
+}
+
+// owner returns the group if Group is set and the project otherwise.
+func (g *GitLab) owner() string {
+	if g.Group != "" {
+		return g.Group
+	}
+	return g.Project
+}
+
+// Documentation describes parameters to obtain GitLab's API documentation
+// for commands which do not use GitLab API.
+//
+//nolint:lll
+type Documentation struct {
+	DocsRef   string `default:"${defaultDocsRef}" env:"DOCS_GIT_REF" help:"Git reference at which to extract API attributes from GitLab's documentation. Default is \"${default}\". Environment variable: ${env}."                       name:"docs" placeholder:"REF"  short:"D"`
+	DocsCache string `                            env:"DOCS_CACHE"   help:"Where to cache downloaded GitLab's documentation. By default it is in user's cache directory. Set to \"-\" to disable caching. Environment variable: ${env}."             placeholder:"PATH"`
+	DocsDir   string `                            env:"DOCS_DIR"     help:"Extract API attributes from GitLab's documentation in a local checkout of GitLab's repository instead. Environment variable: ${env}."                                     placeholder:"PATH"`
+}
+
+// docs returns where GitLab's API documentation is obtained from.
+func (d *Documentation) docs() Docs {
+	return newDocs(d.DocsRef, d.DocsDir, d.DocsCache)
+}
+
+// newContext returns a context which is canceled on interrupt.
+func (d *Documentation) newContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// Sections describes parameters to select configuration sections to process.
+type Sections struct {
+	Only []string `help:"Process only the configuration section. Can be provided multiple times or as a comma-separated list." placeholder:"SECTION"`
+	Skip []string `help:"Do not process the configuration section. Can be provided multiple times or as a comma-separated list." placeholder:"SECTION"`
+}
+
+// resources returns resources from all selected by Only and Skip,
+// in the order they are processed.
+func (s *Sections) resources(all []Resource) ([]Resource, errors.E) {
+	names := []string{}
+	for _, resource := range all {
+		names = append(names, resource.Name())
+	}
+	for _, name := range append(append([]string{}, s.Only...), s.Skip...) {
+		if !slices.Contains(names, name) {
+			errE := errors.New("unknown configuration section")
+			errors.Details(errE)["section"] = name
+			return nil, errE
+		}
+	}
+
+	selected := []Resource{}
+	for _, resource := range all {
+		if len(s.Only) > 0 && !slices.Contains(s.Only, resource.Name()) {
+			continue
+		}
+		if slices.Contains(s.Skip, resource.Name()) {
+			continue
+		}
+		selected = append(selected, resource)
+	}
+	return selected, nil
+}
+
+// excludedSections returns names of registered (project and group) resources
+// which are not among resources.
+func excludedSections(resources []Resource) []string {
+	excluded := []string{}
+	for _, resource := range append(Resources(), GroupResources()...) {
+		name := resource.Name()
+		if slices.Contains(excluded, name) {
+			continue
+		}
+		if !slices.ContainsFunc(resources, func(r Resource) bool { return r.Name() == name }) {
+			excluded = append(excluded, name)
+		}
+	}
+	return excluded
 }
 
 // Globals describes top-level (global) flags.
@@ -26,7 +203,11 @@ type Globals struct {
 type Commands struct {
 	Globals
 
-	Get  GetCommand  `cmd:"" help:"Save GitLab project's configuration to a local file."`
-	Set  SetCommand  `cmd:"" help:"Update GitLab project's configuration based on a local file."`
-	Sops SopsCommand `cmd:"" help:"Run SOPS, an editor of encrypted files. See: https://github.com/tozd/sops" passthrough:""`
+	Get      GetCommand      `cmd:"" help:"Save GitLab project's configuration to a local file."`
+	Set      SetCommand      `cmd:"" help:"Update GitLab project's configuration based on a local file."`
+	Plan     PlanCommand     `cmd:"" help:"Show changes which would be made to GitLab project's configuration based on a local file."`
+	Check    CheckCommand    `cmd:"" help:"Check if GitLab project's configuration differs from a local file."`
+	Validate ValidateCommand `cmd:"" help:"Check a local file for unknown fields, wrong types, and duplicates without using GitLab API."`
+	Schema   SchemaCommand   `cmd:"" help:"Generate JSON Schema for the configuration file."`
+	Sops     SopsCommand     `cmd:"" help:"Run SOPS, an editor of encrypted files. See: https://github.com/tozd/sops"                    passthrough:""`
 }
@@ -1,6 +1,6 @@
 package config
 
-// Configuration represents GitLab's project configuration supported.
+// Configuration represents GitLab's project or group configuration supported.
 //
 // Some fields have type map[string]interface{} because they are passed almost as-is
 // to GitLab API. This allows for potential customization in behavior beyond what
@@ -9,26 +9,62 @@ package config
 // All fields with prefix "comment:" are moved into YAML comments before they are
 // written out. Similarly, fields which have "Comment" suffix are moved into
 // YAML comments and are not used for project configuration.
+//
+// ForkedFromProject field is the ID or the full path of the project the project is
+// forked from, or 0 if the project is not a fork.
+//
+// Group field is used only for group configuration, together with Labels
+// and Variables fields which are then used for group labels and variables.
+//
+// Prune field maps names of configuration sections to false to not delete
+// objects which exist in GitLab but are missing from those configuration sections.
+//
+// Ignore field maps names of configuration sections to rules matching objects
+// which are not managed: they are not fetched, updated, or deleted.
+//
+// Configuration sections of resources registered using RegisterResource
+// or RegisterGroupResource are stored in Extra.
+//
+// Relative paths in objects (e.g., to files with public keys of deploy keys)
+// are relative to the configuration file the object was read from.
 type Configuration struct {
-	Project                  map[string]interface{}   `json:"project"                               yaml:"project"`
-	Avatar                   *string                  `json:"avatar"                                yaml:"avatar"`
-	SharedWithGroups         []map[string]interface{} `json:"shared_with_groups"                    yaml:"shared_with_groups"`
-	SharedWithGroupsComment  string                   `json:"comment:shared_with_groups,omitempty"  yaml:"comment:shared_with_groups,omitempty"`
-	Approvals                map[string]interface{}   `json:"approvals"                             yaml:"approvals"`
-	ApprovalRules            []map[string]interface{} `json:"approval_rules"                        yaml:"approval_rules"`
-	ApprovalRulesComment     string                   `json:"comment:approval_rules,omitempty"      yaml:"comment:approval_rules,omitempty"`
-	PushRules                map[string]interface{}   `json:"push_rules"                            yaml:"push_rules"`
-	PushRulesComment         string                   `json:"comment:push_rules,omitempty"          yaml:"comment:push_rules,omitempty"`
-	ForkedFromProject        *int                     `json:"forked_from_project"                   yaml:"forked_from_project"`
-	ForkedFromProjectComment string                   `json:"comment:forked_from_project,omitempty" yaml:"comment:forked_from_project,omitempty"`
-	Labels                   []map[string]interface{} `json:"labels"                                yaml:"labels"`
-	LabelsComment            string                   `json:"comment:labels,omitempty"              yaml:"comment:labels,omitempty"`
-	ProtectedBranches        []map[string]interface{} `json:"protected_branches"                    yaml:"protected_branches"`
-	ProtectedBranchesComment string                   `json:"comment:protected_branches,omitempty"  yaml:"comment:protected_branches,omitempty"`
-	ProtectedTags            []map[string]interface{} `json:"protected_tags"                        yaml:"protected_tags"`
-	ProtectedTagsComment     string                   `json:"comment:protected_tags,omitempty"      yaml:"comment:protected_tags,omitempty"`
-	Variables                []map[string]interface{} `json:"variables"                             yaml:"variables"`
-	VariablesComment         string                   `json:"comment:variables,omitempty"           yaml:"comment:variables,omitempty"`
-	PipelineSchedules        []map[string]interface{} `json:"pipeline_schedules"                    yaml:"pipeline_schedules"`
-	PipelineSchedulesComment string                   `json:"comment:pipeline_schedules,omitempty"  yaml:"comment:pipeline_schedules,omitempty"`
+	Group                      map[string]interface{}         `json:"group,omitempty"                         yaml:"group,omitempty"`
+	Project                    map[string]interface{}         `json:"project"                                 yaml:"project"`
+	Avatar                     *string                        `json:"avatar"                                  yaml:"avatar"`
+	SharedWithGroups           []map[string]interface{}       `json:"shared_with_groups"                      yaml:"shared_with_groups"`
+	SharedWithGroupsComment    string                         `json:"comment:shared_with_groups,omitempty"    yaml:"comment:shared_with_groups,omitempty"`
+	Approvals                  map[string]interface{}         `json:"approvals"                               yaml:"approvals"`
+	ApprovalRules              []map[string]interface{}       `json:"approval_rules"                          yaml:"approval_rules"`
+	ApprovalRulesComment       string                         `json:"comment:approval_rules,omitempty"        yaml:"comment:approval_rules,omitempty"`
+	PushRules                  map[string]interface{}         `json:"push_rules"                              yaml:"push_rules"`
+	PushRulesComment           string                         `json:"comment:push_rules,omitempty"            yaml:"comment:push_rules,omitempty"`
+	ForkedFromProject          interface{}                    `json:"forked_from_project"                     yaml:"forked_from_project"`
+	ForkedFromProjectComment   string                         `json:"comment:forked_from_project,omitempty"   yaml:"comment:forked_from_project,omitempty"`
+	Labels                     []map[string]interface{}       `json:"labels"                                  yaml:"labels"`
+	LabelsComment              string                         `json:"comment:labels,omitempty"                yaml:"comment:labels,omitempty"`
+	ProtectedBranches          []map[string]interface{}       `json:"protected_branches"                      yaml:"protected_branches"`
+	ProtectedBranchesComment   string                         `json:"comment:protected_branches,omitempty"    yaml:"comment:protected_branches,omitempty"`
+	ProtectedTags              []map[string]interface{}       `json:"protected_tags"                          yaml:"protected_tags"`
+	ProtectedTagsComment       string                         `json:"comment:protected_tags,omitempty"        yaml:"comment:protected_tags,omitempty"`
+	Variables                  []map[string]interface{}       `json:"variables"                               yaml:"variables"`
+	VariablesComment           string                         `json:"comment:variables,omitempty"             yaml:"comment:variables,omitempty"`
+	PipelineSchedules          []map[string]interface{}       `json:"pipeline_schedules"                      yaml:"pipeline_schedules"`
+	PipelineSchedulesComment   string                         `json:"comment:pipeline_schedules,omitempty"    yaml:"comment:pipeline_schedules,omitempty"`
+	Hooks                      []map[string]interface{}       `json:"hooks"                                   yaml:"hooks"`
+	HooksComment               string                         `json:"comment:hooks,omitempty"                 yaml:"comment:hooks,omitempty"`
+	DeployKeys                 []map[string]interface{}       `json:"deploy_keys"                             yaml:"deploy_keys"`
+	DeployKeysComment          string                         `json:"comment:deploy_keys,omitempty"           yaml:"comment:deploy_keys,omitempty"`
+	DeployTokens               []map[string]interface{}       `json:"deploy_tokens"                           yaml:"deploy_tokens"`
+	DeployTokensComment        string                         `json:"comment:deploy_tokens,omitempty"         yaml:"comment:deploy_tokens,omitempty"`
+	ProjectAccessTokens        []map[string]interface{}       `json:"project_access_tokens"                   yaml:"project_access_tokens"`
+	ProjectAccessTokensComment string                         `json:"comment:project_access_tokens,omitempty" yaml:"comment:project_access_tokens,omitempty"`
+	Members                    []map[string]interface{}       `json:"members"                                 yaml:"members"`
+	MembersComment             string                         `json:"comment:members,omitempty"               yaml:"comment:members,omitempty"`
+	Prune                      map[string]bool                `json:"prune,omitempty"                         yaml:"prune,omitempty"`
+	Ignore                     map[string][]map[string]string `json:"ignore,omitempty"                        yaml:"ignore,omitempty"`
+	Extra                      map[string]interface{}         `json:"-"                                       yaml:",inline"`
+
+	// sourceDirs maps names of configuration sections which are lists of objects
+	// to directories of configuration files their objects were read from, by index.
+	sourceDirs map[string][]string
 }
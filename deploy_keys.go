@@ -0,0 +1,511 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+// keyFileField is the field of a deploy key with the path to a file
+// with the public key, which can be used instead of the key itself.
+const keyFileField = "key_file"
+
+// deployKeysResource is a Resource for project deploy keys.
+type deployKeysResource struct{}
+
+// Name implements Resource interface.
+func (deployKeysResource) Name() string {
+	return "deploy_keys"
+}
+
+// Descriptions implements Resource interface.
+func (deployKeysResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getDeployKeysDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (deployKeysResource) Sensitive() []string {
+	return nil
+}
+
+// Keys implements KeyedResource interface.
+func (deployKeysResource) Keys() [][]string {
+	return [][]string{{"key"}, {keyFileField}}
+}
+
+// Describe implements KeyedResource interface.
+func (deployKeysResource) Describe() []string {
+	return []string{"title"}
+}
+
+// WriteOnly implements WriteOnlyResource interface.
+func (deployKeysResource) WriteOnly() []string {
+	// GitLab returns only the key itself.
+	return []string{keyFileField}
+}
+
+// Identity implements IdentifiedResource interface.
+//
+// Deploy keys are identified by fingerprints of their public keys.
+func (deployKeysResource) Identity(deployKey map[string]interface{}, dir string) string {
+	key, ok := deployKey["key"].(string)
+	if !ok {
+		path, ok := deployKey[keyFileField].(string)
+		if !ok {
+			return ""
+		}
+		var errE errors.E
+		key, errE = readKeyFile(path, dir)
+		if errE != nil {
+			return ""
+		}
+	}
+	fingerprint, errE := keyFingerprint(key)
+	if errE != nil {
+		return ""
+	}
+	return fingerprint
+}
+
+// Required implements RequiredResource interface.
+func (deployKeysResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getDeployKeysRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (deployKeysResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getDeployKeys(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (deployKeysResource) Resolve(_ context.Context, _ *Target, _ *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	return resolveDeployKeys(configuration)
+}
+
+// Update implements Resource interface.
+func (deployKeysResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateDeployKeys(ctx, client, configuration)
+}
+
+// getDeployKeys populates configuration struct with configuration available
+// from GitLab deploy keys API endpoint.
+func (g *Getter) getDeployKeys(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting deploy keys...\n")
+
+	configuration.DeployKeys = []map[string]interface{}{}
+
+	descriptions, errE := getDeployKeysDescriptions(ctx, g.Docs)
+	if errE != nil {
+		return errE
+	}
+	// We need "key" later on.
+	if _, ok := descriptions["key"]; !ok {
+		return errors.New(`"key" field is missing in deploy keys descriptions`)
+	}
+	configuration.DeployKeysComment = formatDescriptions(descriptions)
+
+	u := fmt.Sprintf("projects/%s/deploy_keys", gitlab.PathEscape(g.Project))
+	options := &gitlab.ListProjectDeployKeysOptions{
+		PerPage: maxGitLabPageSize,
+		Page:    1,
+	}
+
+	for {
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get deploy keys")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		deployKeys := []map[string]interface{}{}
+
+		response, err := client.Do(req, &deployKeys)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get deploy keys")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		if len(deployKeys) == 0 {
+			break
+		}
+
+		for _, deployKey := range deployKeys {
+			// Only retain those keys which can be edited through the API
+			// (which are those available in descriptions).
+			for key := range deployKey {
+				_, ok := descriptions[key]
+				if !ok {
+					delete(deployKey, key)
+				}
+			}
+
+			key, ok := deployKey["key"]
+			if !ok {
+				return errors.New(`deploy key is missing field "key"`)
+			}
+			_, ok = key.(string)
+			if !ok {
+				errE := errors.New(`deploy key's field "key" is not a string`)
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", key)
+				errors.Details(errE)["value"] = key
+				return errE
+			}
+
+			configuration.DeployKeys = append(configuration.DeployKeys, deployKey)
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	// We sort by title and key so that we have deterministic order.
+	sort.SliceStable(configuration.DeployKeys, func(i, j int) bool {
+		ti, _ := configuration.DeployKeys[i]["title"].(string)
+		tj, _ := configuration.DeployKeys[j]["title"].(string)
+		if ti != tj {
+			return ti < tj
+		}
+		// We checked that key is string above.
+		return configuration.DeployKeys[i]["key"].(string) < configuration.DeployKeys[j]["key"].(string) //nolint:forcetypeassert,errcheck
+	})
+
+	return nil
+}
+
+// parseDeployKeysDocumentation parses GitLab's documentation in Markdown for
+// deploy keys API endpoint and extracts description of fields used to describe
+// an individual deploy key.
+func parseDeployKeysDocumentation(input []byte) (map[string]string, errors.E) {
+	return parseTable(input, "Add deploy key", nil)
+}
+
+// getDeployKeysDescriptions obtains description of fields used to describe an individual
+// deploy key from GitLab's documentation for deploy keys API endpoint.
+func getDeployKeysDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "deploy_keys.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get deploy keys descriptions")
+	}
+	descriptions, errE := parseDeployKeysDocumentation(data)
+	if errE != nil {
+		return nil, errE
+	}
+	// The key can be read from a file instead.
+	descriptions[keyFileField] = "Path to a file with the public key, relative to the configuration file. Used instead of key. Type: string"
+	return descriptions, nil
+}
+
+// getDeployKeysRequired obtains fields required to create an individual deploy key
+// from GitLab's documentation for deploy keys API endpoint.
+func getDeployKeysRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "deploy_keys.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get deploy keys required fields")
+	}
+	required, errE := parseRequired(data, "Add deploy key", nil)
+	if errE != nil {
+		return nil, errE
+	}
+	// The key can be read from a file instead, so it is not strictly required.
+	return slices.DeleteFunc(required, func(field string) bool { return field == "key" }), nil
+}
+
+// keyFingerprint returns the SHA256 fingerprint of the public SSH key
+// in the OpenSSH format (e.g., "ssh-ed25519 AAAA... comment").
+func keyFingerprint(key string) (string, errors.E) {
+	fields := strings.Fields(key)
+	if len(fields) < 2 { //nolint:mnd
+		return "", errors.New("invalid public key")
+	}
+	data, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid public key")
+	}
+	sum := sha256.Sum256(data)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// readKeyFile reads the public key from the file at path.
+// Relative paths are relative to dir.
+func readKeyFile(path, dir string) (string, errors.E) {
+	if !filepath.IsAbs(path) && !strings.HasPrefix(path, "~") {
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(kong.ExpandPath(path))
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot read deploy key")
+		errors.Details(errE)["path"] = path
+		return "", errE
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// resolveDeployKeys returns a copy of the configuration in which "key_file" fields
+// of deploy keys are replaced with "key" fields holding contents of those files.
+func resolveDeployKeys(configuration *Configuration) (*Configuration, errors.E) {
+	if configuration.DeployKeys == nil {
+		return configuration, nil
+	}
+
+	resolved := *configuration
+	resolved.DeployKeys, _ = deepCopy(configuration.DeployKeys).([]map[string]interface{})
+	for i, deployKey := range resolved.DeployKeys {
+		keyFile, ok := deployKey[keyFileField]
+		if !ok {
+			continue
+		}
+		delete(deployKey, keyFileField)
+		path, ok := keyFile.(string)
+		if !ok {
+			errE := errors.Errorf(`deploy key's field "%s" is not a string`, keyFileField)
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["type"] = fmt.Sprintf("%T", keyFile)
+			errors.Details(errE)["value"] = keyFile
+			return nil, errE
+		}
+		if _, ok := deployKey["key"]; ok {
+			errE := errors.Errorf(`deploy key has both "key" and "%s" fields`, keyFileField)
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+		key, errE := readKeyFile(path, configuration.sourceDir("deploy_keys", i))
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+		deployKey["key"] = key
+	}
+
+	return &resolved, nil
+}
+
+// publicDeployKeys returns instance-wide public deploy keys by their fingerprints.
+//
+// Listing them requires administrator access. Without it, no keys are returned.
+func (s *Setter) publicDeployKeys(ctx context.Context, client *gitlab.Client) (map[string]int, errors.E) {
+	options := &gitlab.ListInstanceDeployKeysOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: maxGitLabPageSize,
+			Page:    1,
+		},
+		Public: gitlab.Bool(true),
+	}
+
+	fingerprints := map[string]int{}
+
+	for {
+		keys, response, err := client.DeployKeys.ListAllDeployKeys(options, gitlab.WithContext(ctx))
+		if err != nil {
+			if response != nil && response.StatusCode == http.StatusForbidden && options.Page == 1 {
+				return fingerprints, nil
+			}
+			errE := errors.WithMessage(err, "failed to get instance deploy keys")
+			errors.Details(errE)["page"] = options.Page
+			return nil, errE
+		}
+
+		for _, key := range keys {
+			fingerprint, errE := keyFingerprint(key.Key)
+			if errE != nil {
+				// We skip keys we cannot parse.
+				continue
+			}
+			fingerprints[fingerprint] = key.ID
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	return fingerprints, nil
+}
+
+// updateDeployKeys updates GitLab project's deploy keys using GitLab deploy keys
+// API endpoint based on the configuration struct.
+//
+// Deploy keys are matched to existing deploy keys based on fingerprints of their
+// public keys. Unmatched deploy keys are enabled if they exist as instance-wide
+// public deploy keys and are created as new otherwise.
+func (s *Setter) updateDeployKeys(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	if configuration.DeployKeys == nil {
+		return nil
+	}
+
+	s.Printf("Updating deploy keys...\n")
+
+	options := &gitlab.ListProjectDeployKeysOptions{
+		PerPage: maxGitLabPageSize,
+		Page:    1,
+	}
+
+	deployKeys := []*gitlab.ProjectDeployKey{}
+
+	for {
+		ks, response, err := client.DeployKeys.ListProjectDeployKeys(s.Project, options, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get deploy keys")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		deployKeys = append(deployKeys, ks...)
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	existingDeployKeysSet := mapset.NewThreadUnsafeSet[int]()
+	fingerprintsToIDs := map[string]int{}
+	for _, deployKey := range deployKeys {
+		fingerprint, errE := keyFingerprint(deployKey.Key)
+		if errE != nil {
+			errors.Details(errE)["deployKey"] = deployKey.ID
+			return errE
+		}
+		// Deploy keys matching ignore rules are neither updated nor deleted.
+		if configuration.ignored("deploy_keys", map[string]interface{}{"title": deployKey.Title, "key": deployKey.Key}) {
+			continue
+		}
+		fingerprintsToIDs[fingerprint] = deployKey.ID
+		existingDeployKeysSet.Add(deployKey.ID)
+	}
+
+	wantedIDs := make([]int, len(configuration.DeployKeys))
+	wantedDeployKeysSet := mapset.NewThreadUnsafeSet[int]()
+	fingerprints := make([]string, len(configuration.DeployKeys))
+	for i, deployKey := range configuration.DeployKeys {
+		key, ok := deployKey["key"]
+		if !ok {
+			errE := errors.New(`deploy key is missing field "key"`)
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+		k, ok := key.(string)
+		if !ok {
+			errE := errors.New(`deploy key's field "key" is not a string`)
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["type"] = fmt.Sprintf("%T", key)
+			errors.Details(errE)["value"] = key
+			return errE
+		}
+		fingerprint, errE := keyFingerprint(k)
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+		fingerprints[i] = fingerprint
+		id, ok := fingerprintsToIDs[fingerprint]
+		if ok {
+			wantedIDs[i] = id
+			wantedDeployKeysSet.Add(id)
+		}
+	}
+
+	extraDeployKeys := existingDeployKeysSet.Difference(wantedDeployKeysSet).ToSlice()
+	if !configuration.prune("deploy_keys", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraDeployKeys = nil
+	}
+	slices.Sort(extraDeployKeys)
+	for _, deployKeyID := range extraDeployKeys {
+		// For deploy keys shared with other projects, this only disables them for the project.
+		_, err := client.DeployKeys.DeleteDeployKey(s.Project, deployKeyID, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to delete deploy key")
+			errors.Details(errE)["deployKey"] = deployKeyID
+			return errE
+		}
+	}
+
+	// Instance-wide public deploy keys are fetched only when needed.
+	var publicDeployKeys map[string]int
+
+	for i, deployKey := range configuration.DeployKeys {
+		id := wantedIDs[i]
+		if id == 0 {
+			if publicDeployKeys == nil {
+				var errE errors.E
+				publicDeployKeys, errE = s.publicDeployKeys(ctx, client)
+				if errE != nil {
+					return errE
+				}
+			}
+			publicID, ok := publicDeployKeys[fingerprints[i]]
+			if !ok {
+				u := fmt.Sprintf("projects/%s/deploy_keys", gitlab.PathEscape(s.Project))
+				req, err := client.NewRequest(http.MethodPost, u, deployKey, contextOptions(ctx))
+				if err != nil {
+					errE := errors.WithMessage(err, "failed to create deploy key")
+					errors.Details(errE)["index"] = i
+					errors.Details(errE)["fingerprint"] = fingerprints[i]
+					return errE
+				}
+				_, err = client.Do(req, nil)
+				if err != nil {
+					errE := errors.WithMessage(err, "failed to create deploy key")
+					errors.Details(errE)["index"] = i
+					errors.Details(errE)["fingerprint"] = fingerprints[i]
+					return errE
+				}
+				continue
+			}
+			_, _, err := client.DeployKeys.EnableDeployKey(s.Project, publicID, gitlab.WithContext(ctx))
+			if err != nil {
+				errE := errors.WithMessage(err, "failed to enable deploy key")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["deployKey"] = publicID
+				return errE
+			}
+			// Enabled deploy key is then updated with the configured fields.
+			id = publicID
+		}
+
+		// The public key and the expiration cannot be changed.
+		update := map[string]interface{}{}
+		for field, value := range deployKey {
+			if field != "key" && field != "expires_at" {
+				update[field] = value
+			}
+		}
+		u := fmt.Sprintf("projects/%s/deploy_keys/%d", gitlab.PathEscape(s.Project), id)
+		req, err := client.NewRequest(http.MethodPut, u, update, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to update deploy key")
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["deployKey"] = id
+			return errE
+		}
+		_, err = client.Do(req, nil)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to update deploy key")
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["deployKey"] = id
+			return errE
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Deploy keys file is from: https://gitlab.com/gitlab-org/gitlab/-/raw/master/doc/api/deploy_keys.md
+//
+//go:embed testdata/deploy_keys.md
+var testDeployKeys []byte
+
+const (
+	testDeployKey       = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMYlQvsbRWdE1kA1q+FuHUGEWnI3VlTOvWdzmKGaOuD/ deploy@example.com"
+	testDeployKeyCI     = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIN+mcF/erBD0dnvsPeLt1GdSYvAKseajMDIKHOn1TFam ci"
+	testDeployKeyPublic = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIPHXRO8AObwyJnigCaNsdesNB/MNcneJsYg6dpTOu7W9 public"
+	testDeployKeyOld    = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIPnmx8afC6ecSMPyOVraxch5+XmjfQNJlxRsJg93MOZF old"
+)
+
+func TestParseDeployKeysDocumentation(t *testing.T) {
+	t.Parallel()
+
+	data, errE := parseDeployKeysDocumentation(testDeployKeys)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]string{
+		"can_push":   "Can deploy key push to the project's repository. Type: boolean",
+		"expires_at": "Expiration date for the deploy key. Does not expire if no value is provided. Expected in ISO 8601 format (2019-03-15T08:00:00Z) Type: datetime",
+		"key":        "New deploy key. Type: string",
+		"title":      "New deploy key's title. Type: string",
+	}, data)
+
+	required, errE := parseRequired(testDeployKeys, "Add deploy key", nil)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []string{"key", "title"}, required)
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	t.Parallel()
+
+	// Expected fingerprints are as reported by "ssh-keygen -l".
+	for _, tt := range []struct {
+		Key         string
+		Fingerprint string
+	}{
+		{testDeployKey, "SHA256:E3+L+CZ7D9wT+kh5Duafa7OcLa8/zcTo72lBVCIQ44U"},
+		{testDeployKeyCI, "SHA256:7Tk0YVXCOUgIbqxR7m54xYBIukPlEcg7sn4H7D/uhd4"},
+		// The comment does not matter.
+		{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIN+mcF/erBD0dnvsPeLt1GdSYvAKseajMDIKHOn1TFam", "SHA256:7Tk0YVXCOUgIbqxR7m54xYBIukPlEcg7sn4H7D/uhd4"},
+	} {
+		fingerprint, errE := keyFingerprint(tt.Key)
+		require.NoError(t, errE, "% -+#.1v", errE)
+		assert.Equal(t, tt.Fingerprint, fingerprint)
+	}
+
+	for _, key := range []string{"", "ssh-ed25519", "ssh-ed25519 not-base64!"} {
+		_, errE := keyFingerprint(key)
+		assert.ErrorContains(t, errE, "invalid public key", key)
+	}
+}
+
+func TestReadConfigurationKeyFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "keys"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "keys", "ci.pub"), []byte(testDeployKeyCI+"\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "base", "keys"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base", "keys", "public.pub"), []byte(testDeployKeyPublic+"\n"), 0o600))
+	// Paths in a base file are relative to the base file.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base", "base.yml"), []byte("deploy_keys:\n  - title: Public\n    key_file: keys/public.pub\n"), 0o600))
+	input := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(input, []byte("extends: base/base.yml\ndeploy_keys:\n  - title: CI\n    key_file: keys/ci.pub\n  - title: Deploy\n    key: "+testDeployKey+"\n"), 0o600))
+
+	configuration, errE := readConfiguration(input, true, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []map[string]interface{}{
+		{"title": "Public", "key_file": "keys/public.pub"},
+		{"title": "CI", "key_file": "keys/ci.pub"},
+		{"title": "Deploy", "key": testDeployKey},
+	}, configuration.DeployKeys)
+
+	resolved, errE := resolveDeployKeys(configuration)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []map[string]interface{}{
+		{"title": "Public", "key": testDeployKeyPublic},
+		{"title": "CI", "key": testDeployKeyCI},
+		{"title": "Deploy", "key": testDeployKey},
+	}, resolved.DeployKeys)
+	// The original configuration is not modified.
+	assert.Equal(t, "keys/ci.pub", configuration.DeployKeys[1]["key_file"])
+
+	// Directories stay aligned with deploy keys which are not ignored.
+	configuration.Ignore = map[string][]map[string]string{"deploy_keys": {{"title": "Public"}}}
+	configuration, errE = configuration.withoutIgnored("deploy_keys")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	resolved, errE = resolveDeployKeys(configuration)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []map[string]interface{}{
+		{"title": "CI", "key": testDeployKeyCI},
+		{"title": "Deploy", "key": testDeployKey},
+	}, resolved.DeployKeys)
+
+	_, errE = resolveDeployKeys(&Configuration{DeployKeys: []map[string]interface{}{ //nolint:exhaustruct
+		{"title": "CI", "key": testDeployKeyCI, "key_file": filepath.Join(dir, "keys", "ci.pub")},
+	}})
+	assert.EqualError(t, errE, `deploy key has both "key" and "key_file" fields`)
+
+	_, errE = resolveDeployKeys(&Configuration{DeployKeys: []map[string]interface{}{ //nolint:exhaustruct
+		{"title": "CI", "key_file": filepath.Join(dir, "keys", "missing.pub")},
+	}})
+	assert.ErrorIs(t, errE, os.ErrNotExist)
+}
+
+func TestFetchApplyDeployKeys(t *testing.T) {
+	t.Parallel()
+
+	keyFile := filepath.Join(t.TempDir(), "public.pub")
+	require.NoError(t, os.WriteFile(keyFile, []byte(testDeployKeyPublic+"\n"), 0o600))
+
+	server := newTestServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Fproject/deploy_keys": `[` +
+			`{"id":1,"title":"Deploy","key":"` + testDeployKey + `","fingerprint_sha256":"SHA256:E3+L+CZ7D9wT+kh5Duafa7OcLa8/zcTo72lBVCIQ44U","can_push":false,"created_at":"2013-10-02T10:12:29Z"},` +
+			`{"id":2,"title":"Old","key":"` + testDeployKeyOld + `","can_push":true,"created_at":"2013-10-02T10:12:29Z"}` +
+			`]`,
+		"GET /api/v4/deploy_keys":                                    `[{"id":7,"title":"Public","key":"` + testDeployKeyPublic + `"}]`,
+		"PUT /api/v4/projects/group%2Fproject/deploy_keys/1":         `{}`,
+		"PUT /api/v4/projects/group%2Fproject/deploy_keys/7":         `{}`,
+		"POST /api/v4/projects/group%2Fproject/deploy_keys/7/enable": `{}`,
+		"POST /api/v4/projects/group%2Fproject/deploy_keys":          `{}`,
+		"DELETE /api/v4/projects/group%2Fproject/deploy_keys/2":      "",
+	})
+
+	requests := server.fetchApply(t, Options{Only: []string{"deploy_keys"}}, func(configuration *Configuration) { //nolint:exhaustruct
+		assert.Equal(t, []map[string]interface{}{
+			{"title": "Deploy", "key": testDeployKey, "can_push": false},
+			{"title": "Old", "key": testDeployKeyOld, "can_push": true},
+		}, configuration.DeployKeys)
+
+		configuration.DeployKeys = []map[string]interface{}{
+			{"title": "Deploy", "key": testDeployKey, "can_push": true},
+			{"title": "Public", "key_file": keyFile},
+			{"title": "New", "key": "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIN+mcF/erBD0dnvsPeLt1GdSYvAKseajMDIKHOn1TFam", "expires_at": "2030-01-01T00:00:00Z"},
+		}
+	})
+
+	assert.Equal(t, []string{
+		"GET /api/v4/projects/group%2Fproject/deploy_keys?page=1&per_page=100",
+		"GET /api/v4/projects/group%2Fproject/deploy_keys?page=1&per_page=100",
+		"DELETE /api/v4/projects/group%2Fproject/deploy_keys/2",
+		`PUT /api/v4/projects/group%2Fproject/deploy_keys/1 {"can_push":true,"title":"Deploy"}`,
+		"GET /api/v4/deploy_keys?page=1&per_page=100&public=true",
+		"POST /api/v4/projects/group%2Fproject/deploy_keys/7/enable",
+		`PUT /api/v4/projects/group%2Fproject/deploy_keys/7 {"title":"Public"}`,
+		`POST /api/v4/projects/group%2Fproject/deploy_keys {"expires_at":"2030-01-01T00:00:00Z","key":"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIN+mcF/erBD0dnvsPeLt1GdSYvAKseajMDIKHOn1TFam","title":"New"}`,
+	}, requests)
+}
@@ -0,0 +1,355 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+// deployTokensResource is a Resource for project deploy tokens.
+type deployTokensResource struct{}
+
+// Name implements Resource interface.
+func (deployTokensResource) Name() string {
+	return "deploy_tokens"
+}
+
+// Descriptions implements Resource interface.
+func (deployTokensResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getDeployTokensDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (deployTokensResource) Sensitive() []string {
+	return []string{tokenField}
+}
+
+// WriteOnly implements WriteOnlyResource interface.
+func (deployTokensResource) WriteOnly() []string {
+	return []string{expiresInField}
+}
+
+// Keys implements KeyedResource interface.
+func (deployTokensResource) Keys() [][]string {
+	return [][]string{{"name"}}
+}
+
+// Describe implements KeyedResource interface.
+func (deployTokensResource) Describe() []string {
+	return nil
+}
+
+// Required implements RequiredResource interface.
+func (deployTokensResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getDeployTokensRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (deployTokensResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getDeployTokens(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (deployTokensResource) Resolve(_ context.Context, _ *Target, _ *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	if configuration.DeployTokens == nil {
+		return configuration, nil
+	}
+
+	deployTokens, errE := resolveTokens(configuration.DeployTokens, "")
+	if errE != nil {
+		return nil, errE
+	}
+	resolved := *configuration
+	resolved.DeployTokens = deployTokens
+	return &resolved, nil
+}
+
+// Update implements Resource interface.
+func (deployTokensResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateDeployTokens(ctx, client, configuration)
+}
+
+// getDeployTokens populates configuration struct with configuration available
+// from GitLab deploy tokens API endpoint.
+//
+// Only active deploy tokens are returned. GitLab does not return values
+// of deploy tokens, so they are not populated.
+func (g *Getter) getDeployTokens(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting deploy tokens...\n")
+
+	configuration.DeployTokens = []map[string]interface{}{}
+
+	descriptions, errE := getDeployTokensDescriptions(ctx, g.Docs)
+	if errE != nil {
+		return errE
+	}
+	// We need "name" later on.
+	if _, ok := descriptions["name"]; !ok {
+		return errors.New(`"name" field is missing in deploy tokens descriptions`)
+	}
+	configuration.DeployTokensComment = formatDescriptions(descriptions)
+
+	u := fmt.Sprintf("projects/%s/deploy_tokens", gitlab.PathEscape(g.Project))
+	options := &gitlab.ListProjectDeployTokensOptions{
+		PerPage: maxGitLabPageSize,
+		Page:    1,
+	}
+
+	for {
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get deploy tokens")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		deployTokens := []map[string]interface{}{}
+
+		response, err := client.Do(req, &deployTokens)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get deploy tokens")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		if len(deployTokens) == 0 {
+			break
+		}
+
+		for _, deployToken := range deployTokens {
+			if deployToken["revoked"] == true || deployToken["expired"] == true {
+				continue
+			}
+
+			// Only retain those keys which can be edited through the API
+			// (which are those available in descriptions).
+			for key := range deployToken {
+				_, ok := descriptions[key]
+				if !ok {
+					delete(deployToken, key)
+				}
+			}
+
+			name, ok := deployToken["name"]
+			if !ok {
+				return errors.New(`deploy token is missing field "name"`)
+			}
+			_, ok = name.(string)
+			if !ok {
+				errE := errors.New(`deploy token's field "name" is not a string`)
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", name)
+				errors.Details(errE)["value"] = name
+				return errE
+			}
+
+			configuration.DeployTokens = append(configuration.DeployTokens, deployToken)
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	// We sort by deploy token name so that we have deterministic order.
+	sort.SliceStable(configuration.DeployTokens, func(i, j int) bool {
+		// We checked that name is string above.
+		return configuration.DeployTokens[i]["name"].(string) < configuration.DeployTokens[j]["name"].(string) //nolint:forcetypeassert,errcheck
+	})
+
+	return nil
+}
+
+// parseDeployTokensDocumentation parses GitLab's documentation in Markdown for
+// deploy tokens API endpoint and extracts description of fields used to describe
+// an individual deploy token.
+func parseDeployTokensDocumentation(input []byte) (map[string]string, errors.E) {
+	return parseTable(input, "Create a project deploy token", nil)
+}
+
+// getDeployTokensDescriptions obtains description of fields used to describe an individual
+// deploy token from GitLab's documentation for deploy tokens API endpoint.
+func getDeployTokensDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "deploy_tokens.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get deploy tokens descriptions")
+	}
+	descriptions, errE := parseDeployTokensDocumentation(data)
+	if errE != nil {
+		return nil, errE
+	}
+	addTokenDescriptions(descriptions)
+	return descriptions, nil
+}
+
+// getDeployTokensRequired obtains fields required to create an individual deploy token
+// from GitLab's documentation for deploy tokens API endpoint.
+func getDeployTokensRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "deploy_tokens.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get deploy tokens required fields")
+	}
+	return parseRequired(data, "Create a project deploy token", nil)
+}
+
+// updateDeployTokens updates GitLab project's deploy tokens using GitLab deploy tokens
+// API endpoint based on the configuration struct.
+//
+// Deploy tokens are matched to existing active deploy tokens based on the name.
+// Deploy tokens cannot be changed, so when their fields do not match or when they
+// are about to expire, new deploy tokens are created and existing ones revoked.
+// Values of new deploy tokens are saved.
+func (s *Setter) updateDeployTokens(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	if configuration.DeployTokens == nil {
+		return nil
+	}
+
+	s.Printf("Updating deploy tokens...\n")
+
+	options := &gitlab.ListProjectDeployTokensOptions{
+		PerPage: maxGitLabPageSize,
+		Page:    1,
+	}
+
+	deployTokens := []*gitlab.DeployToken{}
+
+	for {
+		ts, response, err := client.DeployTokens.ListProjectDeployTokens(s.Project, options, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get deploy tokens")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		deployTokens = append(deployTokens, ts...)
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	existingDeployTokensSet := mapset.NewThreadUnsafeSet[int]()
+	namesToDeployTokens := map[string]*existingToken{}
+	for _, deployToken := range deployTokens {
+		if deployToken.Revoked || deployToken.Expired {
+			continue
+		}
+		// Deploy tokens matching ignore rules are neither updated nor revoked.
+		if configuration.ignored("deploy_tokens", map[string]interface{}{"name": deployToken.Name}) {
+			continue
+		}
+		existingDeployTokensSet.Add(deployToken.ID)
+		// If there are multiple deploy tokens with the same name, the first one is matched.
+		if _, ok := namesToDeployTokens[deployToken.Name]; !ok {
+			namesToDeployTokens[deployToken.Name] = &existingToken{
+				ID:        deployToken.ID,
+				Scopes:    deployToken.Scopes,
+				ExpiresAt: deployToken.ExpiresAt,
+				Fields:    map[string]interface{}{"username": deployToken.Username},
+			}
+		}
+	}
+
+	now := time.Now()
+
+	changes := make([]tokenChange, len(configuration.DeployTokens))
+	names := make([]string, len(configuration.DeployTokens))
+	wantedDeployTokensSet := mapset.NewThreadUnsafeSet[int]()
+	newDeployTokens := []string{}
+	for i, deployToken := range configuration.DeployTokens {
+		name, errE := tokenName(deployToken, i)
+		if errE != nil {
+			return errE
+		}
+		names[i] = name
+		existing := namesToDeployTokens[name]
+		if existing != nil {
+			wantedDeployTokensSet.Add(existing.ID)
+		}
+		changes[i], errE = s.tokenChange(existing, deployToken, now)
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+		if changes[i] != tokenKeep {
+			newDeployTokens = append(newDeployTokens, name)
+		}
+	}
+
+	// We check this before making any changes because values are revealed only once.
+	errE := s.checkTokensOutput("deploy_tokens", newDeployTokens)
+	if errE != nil {
+		return errE
+	}
+
+	extraDeployTokens := existingDeployTokensSet.Difference(wantedDeployTokensSet).ToSlice()
+	if !configuration.prune("deploy_tokens", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraDeployTokens = nil
+	}
+	slices.Sort(extraDeployTokens)
+	for _, deployTokenID := range extraDeployTokens {
+		_, err := client.DeployTokens.DeleteProjectDeployToken(s.Project, deployTokenID, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to revoke deploy token")
+			errors.Details(errE)["deployToken"] = deployTokenID
+			return errE
+		}
+	}
+
+	u := fmt.Sprintf("projects/%s/deploy_tokens", gitlab.PathEscape(s.Project))
+
+	for i, deployToken := range configuration.DeployTokens {
+		if changes[i] == tokenKeep {
+			continue
+		}
+
+		// Deploy tokens cannot be rotated, so a new one is created instead.
+		req, err := client.NewRequest(http.MethodPost, u, newTokenFields(deployToken, now), contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to create deploy token")
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["deployToken"] = names[i]
+			return errE
+		}
+		created := new(gitlab.DeployToken)
+		_, err = client.Do(req, created)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to create deploy token")
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["deployToken"] = names[i]
+			return errE
+		}
+		errE := s.saveToken("deploy_tokens", names[i], created.Token)
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+
+		if changes[i] == tokenCreate {
+			continue
+		}
+
+		// The existing deploy token is revoked only after the new one has been saved.
+		id := namesToDeployTokens[names[i]].ID
+		_, err = client.DeployTokens.DeleteProjectDeployToken(s.Project, id, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to revoke deploy token")
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["deployToken"] = id
+			return errE
+		}
+	}
+
+	return nil
+}
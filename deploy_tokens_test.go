@@ -0,0 +1,73 @@
+package config
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Deploy tokens file is from: https://gitlab.com/gitlab-org/gitlab/-/raw/master/doc/api/deploy_tokens.md
+//
+//go:embed testdata/deploy_tokens.md
+var testDeployTokens []byte
+
+func TestParseDeployTokensDocumentation(t *testing.T) {
+	t.Parallel()
+
+	data, errE := parseDeployTokensDocumentation(testDeployTokens)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]string{
+		"expires_at": "Expiration date for the deploy token. Does not expire if no value is provided. Expected in ISO 8601 format (2019-03-15T08:00:00Z) Type: datetime",
+		"name":       "New deploy token's name. Type: string",
+		"scopes":     "Indicates the deploy token scopes. Must be at least one of read_repository, read_registry, write_registry, read_package_registry, or write_package_registry. Type: array of strings",
+		"username":   "Username for deploy token. Default is gitlab+deploy-token-{n}. Type: string",
+	}, data)
+}
+
+func TestFetchApplyDeployTokens(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Fproject/deploy_tokens": `[` +
+			`{"id":1,"name":"ci","username":"ci","expires_at":"2099-01-01T00:00:00.000Z","scopes":["read_repository"],"revoked":false,"expired":false},` +
+			`{"id":2,"name":"registry","username":"gitlab+deploy-token-2","expires_at":null,"scopes":["read_registry"],"revoked":false,"expired":false},` +
+			`{"id":3,"name":"old","username":"gitlab+deploy-token-3","expires_at":null,"scopes":["read_registry"],"revoked":true,"expired":false},` +
+			`{"id":4,"name":"unused","username":"gitlab+deploy-token-4","expires_at":null,"scopes":["read_registry"],"revoked":false,"expired":false}` +
+			`]`,
+		"POST /api/v4/projects/group%2Fproject/deploy_tokens":     `{"id":5,"token":"new-token"}`,
+		"DELETE /api/v4/projects/group%2Fproject/deploy_tokens/2": "",
+		"DELETE /api/v4/projects/group%2Fproject/deploy_tokens/4": "",
+	})
+
+	tokensOutput := filepath.Join(t.TempDir(), "tokens.yml")
+	opts := Options{Only: []string{"deploy_tokens"}, TokensOutput: tokensOutput} //nolint:exhaustruct
+
+	requests := server.fetchApply(t, opts, func(configuration *Configuration) {
+		assert.Equal(t, []map[string]interface{}{
+			{"name": "ci", "username": "ci", "expires_at": "2099-01-01T00:00:00.000Z", "scopes": []interface{}{"read_repository"}},
+			{"name": "registry", "username": "gitlab+deploy-token-2", "expires_at": nil, "scopes": []interface{}{"read_registry"}},
+			{"name": "unused", "username": "gitlab+deploy-token-4", "expires_at": nil, "scopes": []interface{}{"read_registry"}},
+		}, configuration.DeployTokens)
+
+		configuration.DeployTokens = []map[string]interface{}{
+			{"name": "ci", "username": "ci", "expires_at": "2099-01-01", "scopes": []interface{}{"read_repository"}, "token": "secret"},
+			{"name": "registry", "scopes": []interface{}{"read_registry", "write_registry"}},
+		}
+	})
+
+	assert.Equal(t, []string{
+		"GET /api/v4/projects/group%2Fproject/deploy_tokens?page=1&per_page=100",
+		"GET /api/v4/projects/group%2Fproject/deploy_tokens?page=1&per_page=100",
+		"DELETE /api/v4/projects/group%2Fproject/deploy_tokens/4",
+		`POST /api/v4/projects/group%2Fproject/deploy_tokens {"name":"registry","scopes":["read_registry","write_registry"]}`,
+		"DELETE /api/v4/projects/group%2Fproject/deploy_tokens/2",
+	}, requests)
+
+	data, err := os.ReadFile(tokensOutput)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy_tokens:\n  registry: new-token\n", string(data))
+}
@@ -0,0 +1,530 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+const (
+	changeCreate = "create"
+	changeUpdate = "update"
+	changeDelete = "delete"
+
+	redactedValue = "<redacted>"
+)
+
+// sectionSpec describes how objects in a configuration section which is
+// a list of objects are matched between two configurations.
+//
+// See KeyedResource for description of Keys and Describe.
+// Sensitive are fields with sensitive values which are redacted.
+// WriteOnly are fields which GitLab does not return.
+// See IdentifiedResource for description of Identity.
+// See DefaultedResource for description of KeyDefaults.
+type sectionSpec struct {
+	Keys        [][]string
+	Describe    []string
+	Sensitive   []string
+	WriteOnly   []string
+	Identity    func(object map[string]interface{}, dir string) string
+	KeyDefaults map[string]interface{}
+}
+
+// getSectionSpec returns the spec for the resource's configuration section.
+func getSectionSpec(resource Resource) sectionSpec {
+	spec := sectionSpec{
+		Keys:        nil,
+		Describe:    nil,
+		Sensitive:   resource.Sensitive(),
+		WriteOnly:   nil,
+		Identity:    nil,
+		KeyDefaults: nil,
+	}
+	keyed, ok := resource.(KeyedResource)
+	if ok {
+		spec.Keys = keyed.Keys()
+		spec.Describe = keyed.Describe()
+	}
+	writeOnly, ok := resource.(WriteOnlyResource)
+	if ok {
+		spec.WriteOnly = writeOnly.WriteOnly()
+	}
+	identified, ok := resource.(IdentifiedResource)
+	if ok {
+		spec.Identity = identified.Identity
+	}
+	defaulted, ok := resource.(DefaultedResource)
+	if ok {
+		spec.KeyDefaults = defaulted.KeyDefaults()
+	}
+	return spec
+}
+
+// fieldChange describes a change of a value at Path.
+type fieldChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// resourceChange describes a change to a configuration section (when Key is empty)
+// or to an object inside a configuration section which is a list of objects.
+type resourceChange struct {
+	Resource string        `json:"resource"`
+	Action   string        `json:"action"`
+	Key      string        `json:"key,omitempty"`
+	Fields   []fieldChange `json:"fields,omitempty"`
+}
+
+// diffConfiguration compares live configuration (as returned by get command) with
+// wanted configuration (as read by set command) for resources and returns changes
+// set command would make to make live configuration match the wanted configuration.
+//
+// Sections which are nil in wanted configuration are skipped, the same as set
+// command does. Only fields present in wanted configuration are compared because
+// set command does not change other fields. Objects missing from wanted configuration
+// are not reported as deleted if noPrune is true or pruning is disabled for their section.
+// Objects matching ignore rules of wanted configuration are not compared.
+func diffConfiguration(resources []Resource, live, wanted *Configuration, noPrune bool) ([]resourceChange, errors.E) {
+	changes := []resourceChange{}
+
+	for _, resource := range resources {
+		name := resource.Name()
+		spec := getSectionSpec(resource)
+
+		wantedSection := wanted.Section(name)
+		if wantedSection == nil {
+			continue
+		}
+		liveSection := live.Section(name)
+
+		rules, errE := wanted.ignoreRules(name)
+		if errE != nil {
+			return nil, errE
+		}
+		wantedSection = removeIgnored(wantedSection, rules)
+		liveSection = removeIgnored(liveSection, rules)
+
+		switch w := wantedSection.(type) {
+		case map[string]interface{}:
+			l, _ := liveSection.(map[string]interface{})
+			fields := diffValue("", removeComments(l), w)
+			if len(fields) > 0 {
+				changes = append(changes, resourceChange{Resource: name, Action: changeUpdate, Key: "", Fields: redactFields(spec, fields)})
+			}
+		case []map[string]interface{}:
+			l, _ := liveSection.([]map[string]interface{})
+			changes = append(changes, diffList(name, spec, l, w, wanted.prune(name, noPrune))...)
+		case []interface{}:
+			l, _ := liveSection.([]interface{})
+			changes = append(changes, diffList(name, spec, toObjects(l), toObjects(w), wanted.prune(name, noPrune))...)
+		case *string:
+			l, _ := liveSection.(*string)
+			change, errE := diffAvatar(name, l, w)
+			if errE != nil {
+				return nil, errE
+			}
+			if change != nil {
+				changes = append(changes, *change)
+			}
+		default:
+			fields := diffValue("", removeComments(liveSection), w)
+			if len(fields) > 0 {
+				changes = append(changes, resourceChange{Resource: name, Action: changeUpdate, Key: "", Fields: redactFields(spec, fields)})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// toObjects converts a list of objects decoded as []interface{}
+// to []map[string]interface{}. Non-objects are skipped.
+func toObjects(list []interface{}) []map[string]interface{} {
+	if list == nil {
+		return nil
+	}
+	objects := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		obj, ok := item.(map[string]interface{})
+		if ok {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+// diffAvatar compares avatar files. Live avatar is expected to be a path
+// to the file with the current avatar.
+func diffAvatar(name string, live, wanted *string) (*resourceChange, errors.E) {
+	liveAvatar := ""
+	if live != nil {
+		liveAvatar = *live
+	}
+
+	if liveAvatar == "" && *wanted == "" {
+		return nil, nil //nolint:nilnil
+	} else if liveAvatar != "" && *wanted != "" {
+		liveData, err := os.ReadFile(liveAvatar)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to read current avatar")
+			errors.Details(errE)["path"] = liveAvatar
+			return nil, errE
+		}
+		wantedData, err := os.ReadFile(*wanted)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to read avatar")
+			errors.Details(errE)["path"] = *wanted
+			return nil, errE
+		}
+		if bytes.Equal(liveData, wantedData) {
+			return nil, nil //nolint:nilnil
+		}
+	}
+
+	var from, to interface{}
+	if liveAvatar != "" {
+		from = "<current avatar>"
+	}
+	if *wanted != "" {
+		to = *wanted
+	}
+
+	return &resourceChange{
+		Resource: name,
+		Action:   changeUpdate,
+		Key:      "",
+		Fields:   []fieldChange{{Path: "", Old: from, New: to}},
+	}, nil
+}
+
+// diffList compares two lists of objects, matching objects based on section's spec.
+// Sections without keys in their spec are compared as a whole. Live objects which
+// do not match any wanted object are reported as deleted only if prune is true.
+func diffList(name string, spec sectionSpec, live, wanted []map[string]interface{}, prune bool) []resourceChange {
+	if len(spec.Keys) == 0 {
+		liveList := removeComments(live)
+		if equalValues(liveList, wanted) {
+			return nil
+		}
+		return []resourceChange{{Resource: name, Action: changeUpdate, Key: "", Fields: []fieldChange{{Path: "", Old: liveList, New: wanted}}}}
+	}
+
+	changes := []resourceChange{}
+
+	cleanLive := make([]map[string]interface{}, 0, len(live))
+	for _, item := range live {
+		cleanLive = append(cleanLive, removeComments(item))
+	}
+	matched := make([]bool, len(cleanLive))
+
+	for _, wantedItem := range wanted {
+		index := matchItem(spec, cleanLive, matched, wantedItem)
+		if index < 0 {
+			changes = append(changes, resourceChange{
+				Resource: name,
+				Action:   changeCreate,
+				Key:      describeItem(spec, wantedItem),
+				Fields:   nil,
+			})
+			continue
+		}
+		matched[index] = true
+		fields := diffValue("", cleanLive[index], wantedItem)
+		// Sensitive values which GitLab does not return (e.g., secret
+		// tokens of hooks) and write-only values cannot be compared.
+		fields = slices.DeleteFunc(fields, func(field fieldChange) bool {
+			_, ok := cleanLive[index][field.Path]
+			return !ok && (slices.Contains(spec.Sensitive, field.Path) || slices.Contains(spec.WriteOnly, field.Path))
+		})
+		if len(fields) > 0 {
+			changes = append(changes, resourceChange{
+				Resource: name,
+				Action:   changeUpdate,
+				Key:      describeItem(spec, cleanLive[index]),
+				Fields:   redactFields(spec, fields),
+			})
+		}
+	}
+
+	for i, liveItem := range cleanLive {
+		if matched[i] || !prune {
+			continue
+		}
+		changes = append(changes, resourceChange{
+			Resource: name,
+			Action:   changeDelete,
+			Key:      describeItem(spec, liveItem),
+			Fields:   nil,
+		})
+	}
+
+	return changes
+}
+
+// matchItem returns the index of the live object which matches the wanted object,
+// or -1 if there is none.
+//
+// Missing fields with default values are matched as if they were set to them.
+func matchItem(spec sectionSpec, live []map[string]interface{}, matched []bool, wanted map[string]interface{}) int {
+KEYS:
+	for _, keys := range spec.Keys {
+		for _, key := range keys {
+			if _, ok := keyValue(spec, wanted, key); !ok {
+				continue KEYS
+			}
+		}
+	ITEMS:
+		for i, item := range live {
+			if matched[i] {
+				continue
+			}
+			for _, key := range keys {
+				itemValue, _ := keyValue(spec, item, key)
+				wantedValue, _ := keyValue(spec, wanted, key)
+				if !equalValues(itemValue, wantedValue) {
+					continue ITEMS
+				}
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// keyValue returns the value of the key field of the object, or its default
+// value if the object does not have the field.
+func keyValue(spec sectionSpec, object map[string]interface{}, key string) (interface{}, bool) {
+	value, ok := object[key]
+	if ok {
+		return value, true
+	}
+	value, ok = spec.KeyDefaults[key]
+	return value, ok
+}
+
+// describeItem returns a human readable identification of an object.
+func describeItem(spec sectionSpec, item map[string]interface{}) string {
+	fields := spec.Describe
+	if len(fields) == 0 {
+		for _, keys := range spec.Keys {
+			for _, key := range keys {
+				if !slices.Contains(fields, key) {
+					fields = append(fields, key)
+				}
+			}
+		}
+	}
+	parts := []string{}
+	for _, field := range fields {
+		value, ok := item[field]
+		if !ok {
+			continue
+		}
+		parts = append(parts, field+"="+formatValue(value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// diffValue compares from and to values and returns changes needed to
+// make from value match the to value. Only fields present in to objects
+// are compared.
+func diffValue(path string, from, to interface{}) []fieldChange {
+	switch t := to.(type) {
+	case map[string]interface{}:
+		o, ok := from.(map[string]interface{})
+		if !ok {
+			return []fieldChange{{Path: path, Old: from, New: to}}
+		}
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		changes := []fieldChange{}
+		for _, key := range keys {
+			p := key
+			if path != "" {
+				p = path + "." + key
+			}
+			changes = append(changes, diffValue(p, o[key], t[key])...)
+		}
+		return changes
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(t))
+		for _, v := range t {
+			list = append(list, v)
+		}
+		return diffValue(path, from, list)
+	case []interface{}:
+		o, ok := from.([]interface{})
+		if !ok || len(o) != len(t) {
+			return []fieldChange{{Path: path, Old: from, New: to}}
+		}
+		changes := []fieldChange{}
+		for i := range t {
+			changes = append(changes, diffValue(fmt.Sprintf("%s[%d]", path, i), o[i], t[i])...)
+		}
+		return changes
+	default:
+		if equalValues(from, to) {
+			return nil
+		}
+		return []fieldChange{{Path: path, Old: from, New: to}}
+	}
+}
+
+// equalValues compares two values, treating all numbers as equal
+// if they have the same numeric value.
+func equalValues(a, b interface{}) bool {
+	aNumber, aOk := toFloat(a)
+	bNumber, bOk := toFloat(b)
+	if aOk && bOk {
+		return aNumber == bNumber
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// toFloat converts a numeric value to float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// removeComments returns a copy of the input with all fields with "comment:" prefix
+// and all strings in lists with "comment:" prefix removed, even if they are nested
+// inside other maps or slices.
+func removeComments[T any](input T) T {
+	return removeCommentsAny(input).(T) //nolint:errcheck,forcetypeassert
+}
+
+func removeCommentsAny(input interface{}) interface{} {
+	switch in := input.(type) {
+	case []interface{}:
+		if in == nil {
+			return in
+		}
+		out := make([]interface{}, 0, len(in))
+		for _, v := range in {
+			if s, ok := v.(string); ok && strings.HasPrefix(s, "comment:") {
+				continue
+			}
+			out = append(out, removeCommentsAny(v))
+		}
+		return out
+	case []map[string]interface{}:
+		if in == nil {
+			return in
+		}
+		out := make([]map[string]interface{}, 0, len(in))
+		for _, v := range in {
+			out = append(out, removeCommentsAny(v).(map[string]interface{})) //nolint:errcheck,forcetypeassert
+		}
+		return out
+	case map[string]interface{}:
+		if in == nil {
+			return in
+		}
+		out := make(map[string]interface{}, len(in))
+		for key, value := range in {
+			if strings.HasPrefix(key, "comment:") {
+				continue
+			}
+			out[key] = removeCommentsAny(value)
+		}
+		return out
+	default:
+		return input
+	}
+}
+
+// redactFields replaces non-nil values of changes of sensitive fields with a placeholder.
+func redactFields(spec sectionSpec, fields []fieldChange) []fieldChange {
+	for i, field := range fields {
+		if !slices.Contains(spec.Sensitive, field.Path) {
+			continue
+		}
+		if field.Old != nil {
+			fields[i].Old = redactedValue
+		}
+		if field.New != nil {
+			fields[i].New = redactedValue
+		}
+	}
+	return fields
+}
+
+// formatValue formats a value for display to the user.
+func formatValue(value interface{}) string {
+	if value == redactedValue {
+		return redactedValue
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// writeChanges writes a human readable list of changes to w.
+func writeChanges(w io.Writer, changes []resourceChange) errors.E {
+	symbols := map[string]string{
+		changeCreate: "+",
+		changeUpdate: "~",
+		changeDelete: "-",
+	}
+	counts := map[string]int{}
+
+	buffer := bytes.Buffer{}
+	resource := ""
+	for _, change := range changes {
+		if change.Resource != resource {
+			resource = change.Resource
+			fmt.Fprintf(&buffer, "%s:\n", resource)
+		}
+		counts[change.Action]++
+		line := "  " + symbols[change.Action] + " " + change.Action
+		if change.Key != "" {
+			line += " " + change.Key
+		}
+		fmt.Fprintf(&buffer, "%s\n", line)
+		for _, field := range change.Fields {
+			path := field.Path
+			if path == "" {
+				path = resource
+			}
+			fmt.Fprintf(&buffer, "      %s: %s => %s\n", path, formatValue(field.Old), formatValue(field.New))
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintf(&buffer, "No changes.\n")
+	} else {
+		fmt.Fprintf(&buffer, "Plan: %d to create, %d to update, %d to delete.\n", counts[changeCreate], counts[changeUpdate], counts[changeDelete])
+	}
+
+	_, err := w.Write(buffer.Bytes())
+	if err != nil {
+		return errors.WithMessage(err, "cannot write changes")
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfiguration(t *testing.T) {
+	t.Parallel()
+
+	forkedFromProject := 0
+	newForkedFromProject := 42
+
+	live := &Configuration{
+		Project: map[string]interface{}{
+			"description":         "old",
+			"comment:description": "Short project description. Type: string",
+			"lfs_enabled":         true,
+		},
+		ForkedFromProject: forkedFromProject,
+		Labels: []map[string]interface{}{
+			{"id": 1, "name": "bug", "color": "#FF0000"},
+			{"id": 2, "name": "feature", "color": "#00FF00"},
+			{"id": 3, "name": "old", "color": "#0000FF"},
+		},
+		Variables: []map[string]interface{}{
+			{"key": "SECRET", "environment_scope": "*", "value": "foo", "comment:value": "sops:enc"},
+		},
+		ProtectedBranches: []map[string]interface{}{
+			{
+				"name": "main",
+				"allowed_to_push": []interface{}{
+					map[string]interface{}{"id": 10, "access_level": 40, "comment:": "Maintainers"},
+				},
+			},
+		},
+	}
+
+	wanted := &Configuration{
+		Project: map[string]interface{}{
+			"description": "new",
+			"lfs_enabled": true,
+		},
+		ForkedFromProject: newForkedFromProject,
+		Labels: []map[string]interface{}{
+			{"id": 1, "name": "bug", "color": "#FF0000"},
+			{"name": "feature", "color": "#FFFFFF"},
+			{"name": "new", "color": "#000000"},
+		},
+		Variables: []map[string]interface{}{
+			{"key": "SECRET", "environment_scope": "*", "value": "bar"},
+		},
+		ProtectedBranches: []map[string]interface{}{
+			{
+				"name": "main",
+				"allowed_to_push": []interface{}{
+					map[string]interface{}{"access_level": 40},
+				},
+			},
+		},
+	}
+
+	changes, errE := diffConfiguration(Resources(), live, wanted, false)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []resourceChange{
+		{Resource: "project", Action: changeUpdate, Key: "", Fields: []fieldChange{{Path: "description", Old: "old", New: "new"}}},
+		{Resource: "forked_from_project", Action: changeUpdate, Key: "", Fields: []fieldChange{{Path: "", Old: 0, New: 42}}},
+		{Resource: "labels", Action: changeUpdate, Key: `name="feature"`, Fields: []fieldChange{{Path: "color", Old: "#00FF00", New: "#FFFFFF"}}},
+		{Resource: "labels", Action: changeCreate, Key: `name="new"`, Fields: nil},
+		{Resource: "labels", Action: changeDelete, Key: `name="old"`, Fields: nil},
+		{Resource: "variables", Action: changeUpdate, Key: `key="SECRET" environment_scope="*"`, Fields: []fieldChange{{Path: "value", Old: redactedValue, New: redactedValue}}},
+	}, changes)
+
+	buffer := bytes.Buffer{}
+	errE = writeChanges(&buffer, changes)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, ""+
+		"project:\n"+
+		"  ~ update\n"+
+		"      description: \"old\" => \"new\"\n"+
+		"forked_from_project:\n"+
+		"  ~ update\n"+
+		"      forked_from_project: 0 => 42\n"+
+		"labels:\n"+
+		"  ~ update name=\"feature\"\n"+
+		"      color: \"#00FF00\" => \"#FFFFFF\"\n"+
+		"  + create name=\"new\"\n"+
+		"  - delete name=\"old\"\n"+
+		"variables:\n"+
+		"  ~ update key=\"SECRET\" environment_scope=\"*\"\n"+
+		"      value: <redacted> => <redacted>\n"+
+		"Plan: 1 to create, 4 to update, 1 to delete.\n", buffer.String())
+}
+
+func TestDiffConfigurationNoChanges(t *testing.T) {
+	t.Parallel()
+
+	live := &Configuration{
+		Labels: []map[string]interface{}{
+			{"id": 1, "name": "bug", "priority": 1},
+		},
+	}
+	wanted := &Configuration{
+		Labels: []map[string]interface{}{
+			{"id": 1, "name": "bug", "priority": 1.0},
+		},
+	}
+
+	changes, errE := diffConfiguration(Resources(), live, wanted, false)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Empty(t, changes)
+
+	buffer := bytes.Buffer{}
+	errE = writeChanges(&buffer, changes)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, "No changes.\n", buffer.String())
+}
+
+func TestDiffConfigurationNoPrune(t *testing.T) {
+	t.Parallel()
+
+	live := &Configuration{
+		Labels: []map[string]interface{}{
+			{"id": 1, "name": "bug"},
+			{"id": 2, "name": "old"},
+		},
+		Variables: []map[string]interface{}{
+			{"key": "DEPLOY_TOKEN", "environment_scope": "*", "value": "foo"},
+		},
+	}
+	wanted := &Configuration{
+		Labels: []map[string]interface{}{
+			{"name": "bug"},
+		},
+		Variables: []map[string]interface{}{},
+		Prune:     map[string]bool{"variables": false},
+	}
+
+	changes, errE := diffConfiguration(Resources(), live, wanted, false)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []resourceChange{
+		{Resource: "labels", Action: changeDelete, Key: `name="old"`, Fields: nil},
+	}, changes)
+
+	changes, errE = diffConfiguration(Resources(), live, wanted, true)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Empty(t, changes)
+}
+
+func TestDiffConfigurationUnreadableSensitive(t *testing.T) {
+	t.Parallel()
+
+	live := &Configuration{
+		Hooks: []map[string]interface{}{
+			{"url": "https://example.com/hook", "push_events": true},
+		},
+	}
+	wanted := &Configuration{
+		Hooks: []map[string]interface{}{
+			{"url": "https://example.com/hook", "push_events": false, "token": "secret"},
+		},
+	}
+
+	changes, errE := diffConfiguration(Resources(), live, wanted, false)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []resourceChange{
+		{Resource: "hooks", Action: changeUpdate, Key: `url="https://example.com/hook"`, Fields: []fieldChange{{Path: "push_events", Old: true, New: false}}},
+	}, changes)
+}
+
+func TestDiffConfigurationWriteOnly(t *testing.T) {
+	t.Parallel()
+
+	live := &Configuration{
+		ProjectAccessTokens: []map[string]interface{}{
+			{"name": "bot", "scopes": []interface{}{"api"}, "expires_at": "2030-01-01"},
+		},
+	}
+	wanted := &Configuration{
+		ProjectAccessTokens: []map[string]interface{}{
+			{"name": "bot", "scopes": []interface{}{"api"}, "expires_in": 30, "token": "secret"},
+		},
+	}
+
+	changes, errE := diffConfiguration(Resources(), live, wanted, false)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Empty(t, changes)
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kong"
+	"gitlab.com/tozd/go/errors"
+)
+
+// GitLab's API documentation files at DefaultDocsRef.
+//
+//go:embed testdata/*.md
+var embeddedDocumentation embed.FS
+
+// Downloaded documentation files are cached so that they are downloaded only
+// once per process, even when they are used by multiple resources or for multiple projects.
+//
+// documentationMu guards only the map. Each file has its own lock which is held
+// while the file is being downloaded, so different files are downloaded concurrently.
+var (
+	documentationMu sync.Mutex                        //nolint:gochecknoglobals
+	documentation   = map[string]*documentationFile{} //nolint:gochecknoglobals
+)
+
+// documentationFile is a downloaded documentation file.
+type documentationFile struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+// Release tags and commit SHAs do not change, so documentation
+// at them can be cached without revalidation.
+var immutableRefRegexp = regexp.MustCompile(`^(v\d+\.\d+\.\d+(-ee)?|[0-9a-f]{40})$`)
+
+// Docs describes where GitLab's API documentation is obtained from.
+type Docs struct {
+	// Ref is the git reference of GitLab's repository at which to obtain the documentation.
+	Ref string
+
+	// Dir is the path to a local checkout of GitLab's repository. If set,
+	// the documentation is read from it and Ref is ignored.
+	Dir string
+
+	// CacheDir is the path to a directory where downloaded documentation is cached.
+	// If empty, downloaded documentation is not cached between runs.
+	CacheDir string
+}
+
+// Get returns GitLab's API documentation file (e.g., "labels.md").
+//
+// The file is read from Dir if it is set. Otherwise files embedded into the program
+// are used if Ref is DefaultDocsRef. Only when neither is available the file is
+// downloaded from gitlab.com (or read from the cache).
+func (d Docs) Get(ctx context.Context, file string) ([]byte, errors.E) {
+	if d.Dir != "" {
+		p := filepath.Join(kong.ExpandPath(d.Dir), "doc", "api", file)
+		data, err := os.ReadFile(p)
+		if err != nil {
+			errE := errors.WithMessage(err, "cannot read documentation")
+			errors.Details(errE)["path"] = p
+			return nil, errE
+		}
+		return data, nil
+	}
+
+	if d.Ref == DefaultDocsRef {
+		data, err := embeddedDocumentation.ReadFile(path.Join("testdata", file))
+		if err == nil {
+			return data, nil
+		}
+	}
+
+	url := fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/%s", d.Ref, file)
+
+	documentationMu.Lock()
+	f, ok := documentation[url]
+	if !ok {
+		f = &documentationFile{} //nolint:exhaustruct
+		documentation[url] = f
+	}
+	documentationMu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.data != nil {
+		return f.data, nil
+	}
+
+	data, errE := d.download(ctx, url, file)
+	if errE != nil {
+		errors.Details(errE)["url"] = url
+		return nil, errE
+	}
+	f.data = data
+	return data, nil
+}
+
+// download downloads the file from url using the cache in CacheDir, if set.
+//
+// Files cached for release tags and commit SHAs are used as they are. Files cached
+// for other git references (i.e., branches) are revalidated using their ETag.
+func (d Docs) download(ctx context.Context, url, file string) ([]byte, errors.E) {
+	if d.CacheDir == "" {
+		return downloadFile(ctx, url)
+	}
+
+	p := filepath.Join(kong.ExpandPath(d.CacheDir), neturl.PathEscape(d.Ref), file)
+	etagPath := p + ".etag"
+
+	etag := ""
+	cached, err := os.ReadFile(p)
+	if err == nil {
+		if immutableRefRegexp.MatchString(d.Ref) {
+			return cached, nil
+		}
+		e, err := os.ReadFile(etagPath) //nolint:govet
+		if err == nil {
+			etag = strings.TrimSpace(string(e))
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			errE := errors.WithMessage(err, "cannot read cached documentation")
+			errors.Details(errE)["path"] = etagPath
+			return nil, errE
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		errE := errors.WithMessage(err, "cannot read cached documentation")
+		errors.Details(errE)["path"] = p
+		return nil, errE
+	}
+
+	data, etag, errE := downloadFileIfModified(ctx, url, etag)
+	if errE != nil {
+		return nil, errE
+	}
+	if data == nil {
+		return cached, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), dirMode)
+	if err == nil {
+		err = os.WriteFile(p, data, fileMode)
+	}
+	if err == nil {
+		if etag != "" {
+			err = os.WriteFile(etagPath, []byte(etag), fileMode)
+		} else {
+			err = os.Remove(etagPath)
+			if errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		}
+	}
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot cache documentation")
+		errors.Details(errE)["path"] = p
+		return nil, errE
+	}
+
+	return data, nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocsEmbedded(t *testing.T) {
+	t.Parallel()
+
+	data, errE := Docs{Ref: DefaultDocsRef, Dir: ""}.Get(t.Context(), "labels.md")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, testLabels, data)
+
+	descriptions, errE := getProjectDescriptions(t.Context(), Docs{Ref: DefaultDocsRef, Dir: ""})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Contains(t, descriptions, "description")
+}
+
+func TestDocsEmbeddedResources(t *testing.T) {
+	t.Parallel()
+
+	// We use only embedded files so that a file missing from them
+	// fails the test instead of being downloaded.
+	embedded, err := fs.Sub(embeddedDocumentation, "testdata")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.CopyFS(filepath.Join(dir, "doc", "api"), embedded))
+	docs := Docs{Ref: DefaultDocsRef, Dir: dir} //nolint:exhaustruct
+
+	for _, resource := range append(Resources(), GroupResources()...) {
+		_, errE := resource.Descriptions(t.Context(), docs)
+		assert.NoError(t, errE, "%s: % -+#.1v", resource.Name(), errE)
+		if r, ok := resource.(RequiredResource); ok {
+			_, errE := r.Required(t.Context(), docs)
+			assert.NoError(t, errE, "%s: % -+#.1v", resource.Name(), errE)
+		}
+	}
+}
+
+func TestDocsDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "doc", "api"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc", "api", "labels.md"), []byte("# Labels\n"), 0o600))
+
+	data, errE := Docs{Ref: DefaultDocsRef, Dir: dir}.Get(t.Context(), "labels.md")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []byte("# Labels\n"), data)
+
+	_, errE = Docs{Ref: DefaultDocsRef, Dir: dir}.Get(t.Context(), "projects.md")
+	assert.ErrorContains(t, errE, "cannot read documentation")
+}
+
+func TestDocsCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "v16.0.0-ee"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "v16.0.0-ee", "labels.md"), []byte("# Labels\n"), 0o600))
+
+	// Documentation cached for a release tag is used without downloading it.
+	data, errE := Docs{Ref: "v16.0.0-ee", Dir: "", CacheDir: dir}.Get(t.Context(), "labels.md")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []byte("# Labels\n"), data)
+}
+
+func TestDownloadFileIfModified(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"abc"`)
+		_, _ = w.Write([]byte("# Labels\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	data, etag, errE := downloadFileIfModified(t.Context(), server.URL, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []byte("# Labels\n"), data)
+	assert.Equal(t, `"abc"`, etag)
+
+	data, etag, errE = downloadFileIfModified(t.Context(), server.URL, `"abc"`)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Nil(t, data)
+	assert.Equal(t, `"abc"`, etag)
+
+	data, etag, errE = downloadFileIfModified(t.Context(), server.URL, `"old"`)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []byte("# Labels\n"), data)
+	assert.Equal(t, `"abc"`, etag)
+}
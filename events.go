@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// event is a JSON event about an operation on a configuration section.
+type event struct {
+	Project  string                 `json:"project,omitempty"`
+	Group    string                 `json:"group,omitempty"`
+	Resource string                 `json:"resource,omitempty"`
+	Key      string                 `json:"key,omitempty"`
+	Action   string                 `json:"action"`
+	Status   int                    `json:"status,omitempty"`
+	Duration float64                `json:"duration,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// eventWriter writes events as JSON lines. It is safe for concurrent use.
+type eventWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// write writes the event. Errors are ignored because events
+// are informational and should not fail the command.
+func (e *eventWriter) write(ev event) {
+	if e == nil {
+		return
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		// Details might contain values which cannot be marshaled.
+		details := map[string]interface{}{}
+		for key, value := range ev.Details {
+			details[key] = fmt.Sprintf("%v", value)
+		}
+		ev.Details = details
+		data, err = json.Marshal(ev)
+		if err != nil {
+			return
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, _ = e.w.Write(append(data, '\n'))
+}
+
+// error writes an error event for errE, including all its details.
+func (e *eventWriter) error(project, group string, errE errors.E) {
+	e.write(event{
+		Project:  project,
+		Group:    group,
+		Resource: "",
+		Key:      "",
+		Action:   "error",
+		Status:   0,
+		Duration: 0,
+		Error:    errE.Error(),
+		Details:  errors.AllDetails(errE),
+	})
+}
+
+// requestActions maps HTTP methods to actions reported in events.
+var requestActions = map[string]string{ //nolint:gochecknoglobals
+	http.MethodGet:    "get",
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodDelete: "delete",
+}
+
+// eventTagsKey is the context key for eventTags.
+type eventTagsKey struct{}
+
+// eventTags describe for which project (or group) and configuration section
+// are requests made with a context.
+type eventTags struct {
+	Project  string
+	Group    string
+	Resource string
+}
+
+// eventTransport is a http.RoundTripper which writes an event for every request,
+// tagged with eventTags from the request's context.
+type eventTransport struct {
+	transport http.RoundTripper
+	events    *eventWriter
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *eventTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.transport.RoundTrip(req)
+
+	action, ok := requestActions[req.Method]
+	if !ok {
+		action = strings.ToLower(req.Method)
+	}
+	// Requests made with an untagged context have empty tags.
+	tags, _ := req.Context().Value(eventTagsKey{}).(eventTags)
+	ev := event{
+		Project:  tags.Project,
+		Group:    tags.Group,
+		Resource: tags.Resource,
+		Key:      requestKey(req.URL),
+		Action:   action,
+		Status:   0,
+		Duration: time.Since(start).Seconds(),
+		Error:    "",
+		Details:  nil,
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	} else {
+		ev.Status = res.StatusCode
+	}
+	t.events.write(ev)
+
+	return res, err //nolint:wrapcheck
+}
+
+// requestKey returns the part of the API path which identifies the object
+// the request is about, e.g., "main" for "projects/123/protected_branches/main".
+// It returns an empty string if the request is not about an individual object.
+func requestKey(u *url.URL) string {
+	_, p, ok := strings.Cut(u.EscapedPath(), "/api/v4/")
+	if !ok {
+		return ""
+	}
+	segments := strings.Split(p, "/")
+	// We skip "projects/<id>" or "groups/<id>", and the endpoint.
+	if len(segments) < 3 || (segments[0] != "projects" && segments[0] != "groups") { //nolint:mnd
+		return ""
+	}
+	segments = segments[3:]
+	for i, segment := range segments {
+		s, err := url.PathUnescape(segment)
+		if err == nil {
+			segments[i] = s
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/tozd/go/errors"
+)
+
+func TestRequestKey(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		url string
+		key string
+	}{
+		{"https://gitlab.com/api/v4/projects/123", ""},
+		{"https://gitlab.com/api/v4/projects/group%2Fproject/labels", ""},
+		{"https://gitlab.com/api/v4/projects/group%2Fproject/protected_branches/release%2F1.0", "release/1.0"},
+		{"https://gitlab.com/api/v4/projects/123/pipeline_schedules/5/variables/FOO", "5/variables/FOO"},
+		{"https://gitlab.com/api/v4/groups/123/labels/42", "42"},
+		{"https://gitlab.com/gitlab-org/gitlab/-/raw/master/doc/api/labels.md", ""},
+	} {
+		t.Run(tt.url, func(t *testing.T) {
+			t.Parallel()
+
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.key, requestKey(u))
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	var buffer bytes.Buffer
+	g := GitLab{Project: "group/project", BaseURL: server.URL}
+	errE := g.setup(1, &eventWriter{w: &buffer})
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	target := Target{Project: g.Project} //nolint:exhaustruct
+	ctx := target.withResource(t.Context(), "labels")
+
+	req, err := g.client.NewRequest(http.MethodPut, "projects/group%2Fproject/labels/bug", nil, contextOptions(ctx))
+	require.NoError(t, err)
+	_, err = g.client.Do(req, nil)
+	require.NoError(t, err)
+
+	req, err = g.client.NewRequest(http.MethodDelete, "projects/group%2Fproject/labels/old", nil, contextOptions(ctx))
+	require.NoError(t, err)
+	_, err = g.client.Do(req, nil)
+	require.Error(t, err)
+
+	errE = errors.New("update failed")
+	errors.Details(errE)["section"] = "labels"
+	g.events.error(g.Project, g.Group, errE)
+
+	decoder := json.NewDecoder(&buffer)
+	events := []event{}
+	for decoder.More() {
+		var ev event
+		require.NoError(t, decoder.Decode(&ev))
+		events = append(events, ev)
+	}
+	require.Len(t, events, 3)
+
+	assert.Equal(t, "group/project", events[0].Project)
+	assert.Equal(t, "labels", events[0].Resource)
+	assert.Equal(t, "bug", events[0].Key)
+	assert.Equal(t, "update", events[0].Action)
+	assert.Equal(t, http.StatusOK, events[0].Status)
+
+	assert.Equal(t, "old", events[1].Key)
+	assert.Equal(t, "delete", events[1].Action)
+	assert.Equal(t, http.StatusNotFound, events[1].Status)
+
+	assert.Equal(t, "error", events[2].Action)
+	assert.Equal(t, "update failed", events[2].Error)
+	assert.Equal(t, map[string]interface{}{"section": "labels"}, events[2].Details)
+}
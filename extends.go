@@ -0,0 +1,263 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const extendsKey = "extends"
+
+// sourceDirField is the field in which the directory of the configuration file
+// an object was read from is temporarily recorded while merging configuration files.
+const sourceDirField = "source:dir"
+
+// resolveExtends deep-merges base configuration files listed under top-level
+// "extends" key of configuration data read from input and returns the merged
+// configuration data.
+//
+// Base files are merged in the order listed, with later files and then the
+// configuration itself overriding earlier ones. Base files can extend other files.
+// Relative paths are relative to the file which lists them.
+//
+// Objects in configuration sections which are lists of objects have the directory
+// of the configuration file they were read from recorded in sourceDirField.
+func resolveExtends(input string, data []byte, noDecrypt bool) ([]byte, errors.E) {
+	var config map[string]interface{}
+	err := yaml.Unmarshal(data, &config)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot unmarshal configuration")
+		errors.Details(errE)["path"] = input
+		return nil, errE
+	}
+
+	var seen []string
+	if input != "-" {
+		seen = []string{filepath.Clean(kong.ExpandPath(input))}
+	}
+
+	merged, errE := extendConfiguration(input, config, noDecrypt, seen)
+	if errE != nil {
+		return nil, errE
+	}
+
+	data, err = yaml.Marshal(merged)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot marshal merged configuration")
+		errors.Details(errE)["path"] = input
+		return nil, errE
+	}
+	return data, nil
+}
+
+// extendConfiguration returns config read from input deep-merged on top of
+// base files it extends. Seen contains paths of files currently being extended
+// and is used to detect cycles.
+func extendConfiguration(input string, config map[string]interface{}, noDecrypt bool, seen []string) (map[string]interface{}, errors.E) {
+	extends, errE := getExtends(config)
+	if errE != nil {
+		errors.Details(errE)["path"] = input
+		return nil, errE
+	}
+	delete(config, extendsKey)
+
+	dir := configurationDir(input)
+	recordSourceDir(config, dir)
+
+	merged := map[string]interface{}{}
+	for _, base := range extends {
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(dir, base)
+		}
+		base = filepath.Clean(base)
+		if slices.Contains(seen, base) {
+			errE := errors.New("configuration extends itself")
+			errors.Details(errE)["path"] = base
+			return nil, errE
+		}
+
+		data, errE := readConfigurationData(base, noDecrypt)
+		if errE != nil {
+			return nil, errE
+		}
+		var baseConfig map[string]interface{}
+		err := yaml.Unmarshal(data, &baseConfig)
+		if err != nil {
+			errE := errors.WithMessage(err, "cannot unmarshal configuration")
+			errors.Details(errE)["path"] = base
+			return nil, errE
+		}
+		baseConfig, errE = extendConfiguration(base, baseConfig, noDecrypt, append(slices.Clone(seen), base))
+		if errE != nil {
+			return nil, errE
+		}
+
+		merged = mergeConfigurations(merged, baseConfig)
+	}
+
+	return mergeConfigurations(merged, config), nil
+}
+
+// configurationDir returns the directory of the configuration file at input,
+// or "." if it is read from stdin.
+func configurationDir(input string) string {
+	if input == "-" {
+		return "."
+	}
+	return filepath.Dir(kong.ExpandPath(input))
+}
+
+// recordSourceDir records dir in sourceDirField of objects in configuration
+// sections of config which are lists of objects.
+func recordSourceDir(config map[string]interface{}, dir string) {
+	for _, section := range config {
+		for _, object := range sectionObjects(section) {
+			if object != nil {
+				object[sourceDirField] = dir
+			}
+		}
+	}
+}
+
+// takeSourceDirs removes directories recorded in sourceDirField from objects
+// in configuration sections of the configuration and stores them in sourceDirs.
+func (c *Configuration) takeSourceDirs() {
+	names := []string{}
+	t := reflect.TypeOf(*c)
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag != "" {
+			names = append(names, tag)
+		}
+	}
+	for name := range c.Extra {
+		names = append(names, name)
+	}
+
+	c.sourceDirs = map[string][]string{}
+	for _, name := range names {
+		objects := sectionObjects(c.Section(name))
+		if objects == nil {
+			continue
+		}
+		dirs := make([]string, len(objects))
+		for i, object := range objects {
+			dirs[i], _ = object[sourceDirField].(string)
+			delete(object, sourceDirField)
+		}
+		c.sourceDirs[name] = dirs
+	}
+}
+
+// getExtends returns paths listed under "extends" key in config.
+// It can be a string or a list of strings.
+func getExtends(config map[string]interface{}) ([]string, errors.E) {
+	switch extends := config[extendsKey].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{extends}, nil
+	case []interface{}:
+		paths := []string{}
+		for i, e := range extends {
+			path, ok := e.(string)
+			if !ok {
+				errE := errors.New(`"extends" is not a list of strings`)
+				errors.Details(errE)["index"] = i
+				return nil, errE
+			}
+			paths = append(paths, path)
+		}
+		return paths, nil
+	default:
+		return nil, errors.New(`"extends" is not a string or a list of strings`)
+	}
+}
+
+// mergeConfigurations deep-merges override configuration into base configuration.
+//
+// Configuration sections which are lists of objects of a KeyedResource are merged
+// by matching objects using their keys (e.g., labels by name, variables by key and
+// environment scope). Matched objects are deep-merged and other objects are appended.
+// Other lists are replaced.
+func mergeConfigurations(base, override map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(base))
+	for key, value := range base {
+		result[key] = value
+	}
+	for key, value := range override {
+		b, ok := result[key]
+		if !ok {
+			result[key] = value
+			continue
+		}
+		baseList, ok1 := b.([]interface{})
+		overrideList, ok2 := value.([]interface{})
+		if ok1 && ok2 {
+			result[key] = mergeLists(getMergeSpec(key), baseList, overrideList)
+		} else {
+			result[key] = mergeValues(b, value)
+		}
+	}
+	return result
+}
+
+// getMergeSpec returns the spec of the registered (project or group)
+// resource with the name.
+func getMergeSpec(name string) sectionSpec {
+	for _, resource := range append(Resources(), GroupResources()...) {
+		if resource.Name() == name {
+			return getSectionSpec(resource)
+		}
+	}
+	return sectionSpec{Keys: nil, Describe: nil, Sensitive: nil, WriteOnly: nil, Identity: nil, KeyDefaults: nil}
+}
+
+// mergeValues deep-merges maps and otherwise returns override.
+func mergeValues(base, override interface{}) interface{} {
+	b, ok1 := base.(map[string]interface{})
+	o, ok2 := override.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return override
+	}
+	result := make(map[string]interface{}, len(b))
+	for key, value := range b {
+		result[key] = value
+	}
+	for key, value := range o {
+		if v, ok := result[key]; ok {
+			result[key] = mergeValues(v, value)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// mergeLists merges lists of objects by matching objects using spec's keys.
+// If there are no keys or lists do not contain only objects, override is returned.
+func mergeLists(spec sectionSpec, base, override []interface{}) []interface{} {
+	baseObjects := toObjects(base)
+	overrideObjects := toObjects(override)
+	if len(spec.Keys) == 0 || len(baseObjects) != len(base) || len(overrideObjects) != len(override) {
+		return override
+	}
+
+	result := slices.Clone(base)
+	matched := make([]bool, len(baseObjects))
+	for _, obj := range overrideObjects {
+		index := matchItem(spec, baseObjects, matched, obj)
+		if index < 0 {
+			result = append(result, obj)
+			continue
+		}
+		matched[index] = true
+		result[index] = mergeValues(baseObjects[index], obj)
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigurationExtends(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "base"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base", "company.yml"), []byte(""+
+		"project:\n"+
+		"  lfs_enabled: true\n"+
+		"  container_expiration_policy:\n"+
+		"    enabled: true\n"+
+		"    keep_n: 10\n"+
+		"labels:\n"+
+		"  - name: bug\n"+
+		"    color: '#FF0000'\n"+
+		"  - name: feature\n"+
+		"    color: '#00FF00'\n"+
+		"protected_branches:\n"+
+		"  - name: main\n"+
+		"    allow_force_push: false\n",
+	), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base", "ci.yml"), []byte(""+
+		"extends: company.yml\n"+
+		"variables:\n"+
+		"  - key: FOO\n"+
+		"    environment_scope: '*'\n"+
+		"    value: base\n"+
+		"  - key: FOO\n"+
+		"    environment_scope: production\n"+
+		"    value: production\n",
+	), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "project.yml"), []byte(""+
+		"extends:\n"+
+		"  - base/ci.yml\n"+
+		"project:\n"+
+		"  description: Project\n"+
+		"  container_expiration_policy:\n"+
+		"    keep_n: 5\n"+
+		"labels:\n"+
+		"  - name: feature\n"+
+		"    color: '#0000FF'\n"+
+		"  - name: project\n"+
+		"    color: '#FFFFFF'\n"+
+		"variables:\n"+
+		"  - key: FOO\n"+
+		"    environment_scope: '*'\n"+
+		"    value: project\n"+
+		"approval_rules:\n"+
+		"  - name: Reviewers\n",
+	), 0o600))
+
+	configuration, errE := readConfiguration(filepath.Join(dir, "project.yml"), true, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	assert.Equal(t, map[string]interface{}{
+		"lfs_enabled": true,
+		"description": "Project",
+		"container_expiration_policy": map[string]interface{}{
+			"enabled": true,
+			"keep_n":  5,
+		},
+	}, configuration.Project)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "bug", "color": "#FF0000"},
+		{"name": "feature", "color": "#0000FF"},
+		{"name": "project", "color": "#FFFFFF"},
+	}, configuration.Labels)
+	assert.Equal(t, []map[string]interface{}{
+		{"key": "FOO", "environment_scope": "*", "value": "project"},
+		{"key": "FOO", "environment_scope": "production", "value": "production"},
+	}, configuration.Variables)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "main", "allow_force_push": false},
+	}, configuration.ProtectedBranches)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "Reviewers"},
+	}, configuration.ApprovalRules)
+	assert.Nil(t, configuration.PushRules)
+	assert.NotContains(t, configuration.Extra, "extends")
+}
+
+func TestReadConfigurationExtendsDefaultKeys(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.yml"), []byte(""+
+		"variables:\n"+
+		"  - key: FOO\n"+
+		"    environment_scope: '*'\n"+
+		"    value: base\n"+
+		"    protected: true\n"+
+		"  - key: BAR\n"+
+		"    value: base\n",
+	), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "project.yml"), []byte(""+
+		"extends: base.yml\n"+
+		"variables:\n"+
+		"  - key: FOO\n"+
+		"    value: project\n"+
+		"  - key: BAR\n"+
+		"    environment_scope: '*'\n"+
+		"    value: project\n"+
+		"  - key: BAR\n"+
+		"    environment_scope: production\n"+
+		"    value: production\n",
+	), 0o600))
+
+	configuration, errE := readConfiguration(filepath.Join(dir, "project.yml"), true, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	// A missing environment scope matches the default "*" environment scope.
+	assert.Equal(t, []map[string]interface{}{
+		{"key": "FOO", "environment_scope": "*", "value": "project", "protected": true},
+		{"key": "BAR", "environment_scope": "*", "value": "project"},
+		{"key": "BAR", "environment_scope": "production", "value": "production"},
+	}, configuration.Variables)
+}
+
+func TestReadConfigurationExtendsCycle(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yml"), []byte("extends: b.yml\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yml"), []byte("extends: a.yml\n"), 0o600))
+
+	_, errE := readConfiguration(filepath.Join(dir, "a.yml"), true, "")
+	assert.EqualError(t, errE, "configuration extends itself")
+}
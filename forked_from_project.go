@@ -1,97 +1,164 @@
 package config
 
 import (
+	"context"
 	"fmt"
-	"os"
 
 	"github.com/xanzy/go-gitlab"
 	"gitlab.com/tozd/go/errors"
 )
 
+// forkedFromProjectResource is a Resource for project fork relation.
+type forkedFromProjectResource struct{}
+
+// Name implements Resource interface.
+func (forkedFromProjectResource) Name() string {
+	return "forked_from_project"
+}
+
+// Descriptions implements Resource interface.
+func (forkedFromProjectResource) Descriptions(context.Context, Docs) (map[string]string, errors.E) {
+	return nil, nil //nolint:nilnil
+}
+
+// Sensitive implements Resource interface.
+func (forkedFromProjectResource) Sensitive() []string {
+	return nil
+}
+
+// Get implements Resource interface.
+func (forkedFromProjectResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getForkedFromProject(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (forkedFromProjectResource) Resolve(ctx context.Context, t *Target, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	return t.resolveForkedFromProject(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (forkedFromProjectResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateForkedFromProject(ctx, client, configuration)
+}
+
 // getForkedFromProject populates configuration struct with GitLab's project fork relation
 // available from GitLab projects API endpoint.
-func (c *GetCommand) getForkedFromProject(
-	_ *gitlab.Client, project map[string]interface{}, configuration *Configuration,
-) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting project fork relation...\n")
+func (g *Getter) getForkedFromProject(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting project fork relation...\n")
+
+	project, errE := getProject(ctx, client, g.Project)
+	if errE != nil {
+		return errE
+	}
 
 	forkedFromProject, ok := project["forked_from_project"]
 	if ok && forkedFromProject != nil {
 		forkedFromProject, ok := forkedFromProject.(map[string]interface{})
 		if !ok {
-			return false, errors.New(`invalid "forked_from_project"`)
+			return errors.New(`invalid "forked_from_project"`)
 		}
 		forkIDAny, ok := forkedFromProject["id"]
 		if !ok {
-			return false, errors.New(`"forked_from_project" is missing field "id"`)
+			return errors.New(`"forked_from_project" is missing field "id"`)
 		}
 		forkIDFloat, ok := forkIDAny.(float64)
 		if !ok {
 			errE := errors.New(`"forked_from_project"'s field "id" is not a float`)
 			errors.Details(errE)["type"] = fmt.Sprintf("%T", forkIDAny)
 			errors.Details(errE)["value"] = forkIDAny
-			return false, errE
+			return errE
 		}
 		// Making sure it is an integer.
 		forkID := int(forkIDFloat)
-		configuration.ForkedFromProject = &forkID
 		forkPathWithNamespace := forkedFromProject["path_with_namespace"]
+		var forkPath string
 		if forkPathWithNamespace != nil {
-			configuration.ForkedFromProjectComment, ok = forkPathWithNamespace.(string)
+			forkPath, ok = forkPathWithNamespace.(string)
 			if !ok {
 				errE := errors.New(`"forked_from_project"'s field "path_with_namespace" is not a string`)
 				errors.Details(errE)["type"] = fmt.Sprintf("%T", forkPathWithNamespace)
 				errors.Details(errE)["value"] = forkPathWithNamespace
-				return false, errE
+				return errE
 			}
 		}
+		if g.IDs || forkPath == "" {
+			configuration.ForkedFromProject = forkID
+			configuration.ForkedFromProjectComment = forkPath
+		} else {
+			configuration.ForkedFromProject = forkPath
+		}
 	} else {
-		noProject := 0
-		configuration.ForkedFromProject = &noProject
+		configuration.ForkedFromProject = 0
 	}
 
-	return false, nil
+	return nil
+}
+
+// resolveForkedFromProject returns a copy of the configuration struct in which
+// the full path of the project the project is forked from is replaced with its ID.
+func (t *Target) resolveForkedFromProject(ctx context.Context, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	path, ok := configuration.ForkedFromProject.(string)
+	if !ok {
+		return configuration, nil
+	}
+
+	id, errE := t.names.projectID(ctx, client, path)
+	if errE != nil {
+		return nil, errE
+	}
+
+	resolved := *configuration
+	resolved.ForkedFromProject = id
+	return &resolved, nil
 }
 
 // updateForkedFromProject updates GitLab project's fork relation using GitLab project's
 // fork relation API endpoint based on the configuration struct.
-func (c *SetCommand) updateForkedFromProject(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updateForkedFromProject(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
 	if configuration.ForkedFromProject == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating project fork relation...\n")
+	forkID, ok := configuration.ForkedFromProject.(int)
+	if !ok {
+		errE := errors.New(`"forked_from_project" is not an integer`)
+		errors.Details(errE)["type"] = fmt.Sprintf("%T", configuration.ForkedFromProject)
+		errors.Details(errE)["value"] = configuration.ForkedFromProject
+		return errE
+	}
+
+	s.Printf("Updating project fork relation...\n")
 
-	project, _, err := client.Projects.GetProject(c.Project, nil)
+	project, _, err := client.Projects.GetProject(s.Project, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return errors.WithMessage(err, "failed to get project")
 	}
 
-	if *configuration.ForkedFromProject == 0 {
+	if forkID == 0 {
 		if project.ForkedFromProject != nil {
-			_, err := client.Projects.DeleteProjectForkRelation(c.Project)
+			_, err := client.Projects.DeleteProjectForkRelation(s.Project, gitlab.WithContext(ctx))
 			if err != nil {
 				return errors.WithMessage(err, "failed to delete fork relation")
 			}
 		}
 	} else if project.ForkedFromProject == nil {
-		_, _, err := client.Projects.CreateProjectForkRelation(c.Project, *configuration.ForkedFromProject)
+		_, _, err := client.Projects.CreateProjectForkRelation(s.Project, forkID, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to create fork relation")
-			errors.Details(errE)["to"] = *configuration.ForkedFromProject
+			errors.Details(errE)["to"] = forkID
 			return errE
 		}
-	} else if project.ForkedFromProject.ID != *configuration.ForkedFromProject {
-		_, err := client.Projects.DeleteProjectForkRelation(c.Project)
+	} else if project.ForkedFromProject.ID != forkID {
+		_, err := client.Projects.DeleteProjectForkRelation(s.Project, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to delete fork relation before creating new")
-			errors.Details(errE)["to"] = *configuration.ForkedFromProject
+			errors.Details(errE)["to"] = forkID
 			return errE
 		}
-		_, _, err = client.Projects.CreateProjectForkRelation(c.Project, *configuration.ForkedFromProject)
+		_, _, err = client.Projects.CreateProjectForkRelation(s.Project, forkID, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to create fork relation")
-			errors.Details(errE)["to"] = *configuration.ForkedFromProject
+			errors.Details(errE)["to"] = forkID
 			return errE
 		}
 	}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -14,6 +16,7 @@ import (
 
 const (
 	fileMode = 0o600
+	dirMode  = 0o700
 )
 
 // We do not use type=path for Output because we want a relative path.
@@ -23,16 +26,60 @@ const (
 //nolint:lll
 type GetCommand struct {
 	GitLab
+	Sections
 
-	Output     string `default:".gitlab-conf.yml"   help:"Where to save the configuration to. Can be \"-\" for stdout. Default is \"${default}\"."                                                          placeholder:"PATH"   short:"o"`
-	Avatar     string `default:".gitlab-avatar.img" help:"Where to save the avatar to. File extension is set automatically. Default is \"${default}\"."                                                     placeholder:"PATH"   short:"a"`
-	EncComment string `default:"sops:enc"           help:"Annotate sensitive values with the comment, marking them for encryption with SOPS. Set to an empty string to disable. Default is \"${default}\"." placeholder:"STRING" short:"E"`
-	EncSuffix  string `                             help:"Add the suffix to field names of sensitive values, marking them for encryption with SOPS. Disabled by default."                                                        short:"S"`
+	Output           string `default:".gitlab-conf.yml"                    help:"Where to save the configuration to. Can be \"-\" for stdout. Default is \"${default}\"."                                                                     placeholder:"PATH"   short:"o"`
+	Avatar           string `default:".gitlab-avatar.img"                  help:"Where to save the avatar to. File extension is set automatically. Default is \"${default}\"."                                                                placeholder:"PATH"   short:"a"`
+	EncComment       string `default:"sops:enc"                            help:"Annotate sensitive values with the comment, marking them for encryption with SOPS. Set to an empty string to disable. Default is \"${default}\"."            placeholder:"STRING" short:"E"`
+	EncSuffix        string `                                              help:"Add the suffix to field names of sensitive values, marking them for encryption with SOPS. Disabled by default."                                                                   short:"S"`
+	Projects         string `                                              help:"Get configurations of multiple projects instead. PATH is a manifest file or a directory with <namespace>/<project_path>.yml files."                          placeholder:"PATH"   short:"P"`
+	Workers          int    `default:"4"                                   help:"Maximum number of concurrent requests to GitLab API. Default is ${default}."                                                                                 placeholder:"INT"`
+	OutputFormat     string `default:"text"               enum:"text,json" help:"Format of progress and results. With json, events are written to stdout. Possible: ${enum}. Default is \"${default}\"."                                      placeholder:"FORMAT"`
+	Merge            bool   `                                              help:"Merge into the existing file instead of overwriting it, keeping its comments, order of fields, and SOPS metadata."`
+	IDs              bool   `                                              help:"Reference users, groups, and projects by their IDs instead of usernames and full paths."                                                          name:"ids"`
+	AccessLevelNames bool   `                                              help:"Output access levels as names (e.g., maintainer) instead of integers."`
 }
 
 // Run runs the get command.
 func (c *GetCommand) Run(globals *Globals) errors.E {
-	if c.Project == "" {
+	if c.Group != "" && c.Projects != "" {
+		return errors.New("group cannot be used with projects")
+	}
+	if c.Workers < 1 {
+		errE := errors.New("invalid number of workers")
+		errors.Details(errE)["workers"] = c.Workers
+		return errE
+	}
+
+	if c.Merge && c.Output == "-" && c.Projects == "" {
+		return errors.New("merge cannot be used when saving the configuration to stdout")
+	}
+
+	var events *eventWriter
+	if c.OutputFormat == "json" {
+		if c.Output == "-" && c.Projects == "" {
+			return errors.New("json output format cannot be used when saving the configuration to stdout")
+		}
+		events = &eventWriter{w: os.Stdout} //nolint:exhaustruct
+	}
+	errE := c.setup(c.Workers, events)
+	if errE != nil {
+		return errE
+	}
+
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	errE = c.run(ctx, globals)
+	if errE != nil {
+		c.events.error(c.Project, c.Group, errE)
+	}
+	return errE
+}
+
+// run runs the get command after c has been set up.
+func (c *GetCommand) run(ctx context.Context, globals *Globals) errors.E {
+	if c.Project == "" && c.Group == "" && c.Projects == "" {
 		projectID, errE := x.InferGitLabProjectID(".")
 		if errE != nil {
 			return errE
@@ -40,99 +87,262 @@ func (c *GetCommand) Run(globals *Globals) errors.E {
 		c.Project = projectID
 	}
 
-	client, err := gitlab.NewClient(c.Token, gitlab.WithBaseURL(c.BaseURL))
-	if err != nil {
-		return errors.WithMessage(err, "failed to create GitLab API client instance")
+	resources, errE := c.resources(c.registeredResources())
+	if errE != nil {
+		return errE
 	}
 
-	var configuration Configuration
-	hasSensitive := false
+	if c.Projects != "" {
+		return c.runProjects(ctx, globals, resources)
+	}
 
-	s, errE := c.getProject(client, &configuration)
+	ignore, errE := readIgnore(c.Output, c.EncSuffix)
 	if errE != nil {
 		return errE
 	}
-	hasSensitive = hasSensitive || s
 
-	s, errE = c.getApprovals(client, &configuration)
+	hasSensitive, errE := c.save(ctx, resources, ignore)
 	if errE != nil {
 		return errE
 	}
-	hasSensitive = hasSensitive || s
 
-	s, errE = c.getApprovalRules(client, &configuration)
-	if errE != nil {
-		return errE
+	fmt.Fprintf(os.Stderr, "Got everything.\n")
+	if hasSensitive {
+		fmt.Fprintf(os.Stderr, "WARNING: Configuration includes sensitive values. Consider encrypting the file. You can use SOPS, e.g.:\n  %s\n", c.sopsCommand(globals)) //nolint:lll
 	}
-	hasSensitive = hasSensitive || s
 
-	s, errE = c.getPushRules(client, &configuration)
+	return nil
+}
+
+// runProjects gets configurations of all projects listed in c.Projects,
+// continuing with other projects if getting configuration of a project fails.
+func (c *GetCommand) runProjects(ctx context.Context, globals *Globals, resources []Resource) errors.E {
+	projects, errE := readProjects(c.Projects)
 	if errE != nil {
 		return errE
 	}
-	hasSensitive = hasSensitive || s
 
-	s, errE = c.getLabels(client, &configuration)
-	if errE != nil {
-		return errE
+	failed := []string{}
+	skipped := []string{}
+	var sensitive *GetCommand
+	for i, project := range projects {
+		if ctx.Err() != nil {
+			for _, p := range projects[i:] {
+				skipped = append(skipped, p.Project)
+			}
+			break
+		}
+
+		command := *c
+		command.Project = project.Project
+		command.Output = project.File
+		command.Avatar = project.Avatar
+
+		fmt.Fprintf(os.Stderr, "Getting project %s...\n", project.Project)
+		hasSensitive, errE := command.saveProject(ctx, resources)
+		if errE != nil {
+			fmt.Fprintf(os.Stderr, "Project %s failed: %s\n", project.Project, errE.Error())
+			c.events.error(project.Project, "", errE)
+			failed = append(failed, project.Project)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Project %s done.\n", project.Project)
+		if hasSensitive && sensitive == nil {
+			sensitive = &command
+		}
 	}
-	hasSensitive = hasSensitive || s
 
-	s, errE = c.getProtectedBranches(client, &configuration)
-	if errE != nil {
+	fmt.Fprintf(os.Stderr, "Got %d projects, %d failed, %d skipped.\n", len(projects)-len(failed)-len(skipped), len(failed), len(skipped))
+	if sensitive != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: Configurations include sensitive values. Consider encrypting the files. You can use SOPS, e.g.:\n  %s\n", sensitive.sopsCommand(globals)) //nolint:lll
+	}
+
+	if len(failed) > 0 || len(skipped) > 0 {
+		errE := errors.New("failed to get some projects")
+		errors.Details(errE)["failed"] = failed
+		if len(skipped) > 0 {
+			errors.Details(errE)["skipped"] = skipped
+		}
 		return errE
 	}
-	hasSensitive = hasSensitive || s
+	return nil
+}
 
-	s, errE = c.getProtectedTags(client, &configuration)
+// saveProject is like save, but first makes sure the directory for c.Output exists.
+func (c *GetCommand) saveProject(ctx context.Context, resources []Resource) (bool, errors.E) {
+	dir := filepath.Dir(kong.ExpandPath(c.Output))
+	err := os.MkdirAll(dir, dirMode)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot create directory")
+		errors.Details(errE)["path"] = dir
+		return false, errE
+	}
+	ignore, errE := readIgnore(c.Output, c.EncSuffix)
 	if errE != nil {
-		return errE
+		return false, errE
 	}
-	hasSensitive = hasSensitive || s
+	return c.save(ctx, resources, ignore)
+}
+
+// options returns Options for Fetch with ignore rules.
+func (c *GetCommand) options(ignore map[string][]map[string]string) Options {
+	return Options{
+		Group:            c.Group != "",
+		Only:             c.Only,
+		Skip:             c.Skip,
+		Docs:             c.docs(),
+		IDs:              c.IDs,
+		AccessLevelNames: c.AccessLevelNames,
+		Avatar:           c.Avatar,
+		Workers:          c.Workers,
+		Snapshot:         "",
+		Rollback:         false,
+		NoPrune:          false,
+		RotateBefore:     0,
+		TokensOutput:     "",
+		Input:            "",
+		EncSuffix:        "",
+		Ignore:           ignore,
+		// Comments are written into the file.
+		Comments: true,
+		Logger:   c.logger,
+	}
+}
 
-	s, errE = c.getVariables(client, &configuration)
+// save fetches GitLab project's configuration for resources and saves it to c.Output.
+//
+// Objects matching ignore rules are not saved. Ignore rules themselves are saved, too,
+// unless merging into the existing file which already has them.
+//
+// It returns true if configuration includes sensitive values.
+func (c *GetCommand) save(ctx context.Context, resources []Resource, ignore map[string][]map[string]string) (bool, errors.E) {
+	configuration, errE := Fetch(ctx, c.client, c.owner(), c.options(ignore))
 	if errE != nil {
-		return errE
+		return false, errE
+	}
+	hasSensitive := annotateSections(configuration, resources, c.EncComment, c.EncSuffix)
+	if !c.Merge {
+		configuration.Ignore = ignore
 	}
-	hasSensitive = hasSensitive || s
 
-	s, errE = c.getPipelineSchedules(client, &configuration)
+	// Sections which were not fetched are omitted so that
+	// set does not see them as empty and remove everything.
+	data, errE := toConfigurationYAML(configuration, excludedSections(resources))
 	if errE != nil {
-		return errE
+		return false, errE
+	}
+
+	if c.Merge && c.Output != "-" {
+		data, errE = mergeConfigurationFile(c.Output, data, resources, c.EncSuffix)
+		if errE != nil {
+			return false, errE
+		}
 	}
-	hasSensitive = hasSensitive || s
 
-	data, errE := toConfigurationYAML(&configuration)
+	errE = writeConfiguration(c.Output, data)
 	if errE != nil {
-		return errE
+		return false, errE
 	}
 
-	if c.Output != "-" {
-		err = os.WriteFile(kong.ExpandPath(c.Output), data, fileMode)
+	return hasSensitive, nil
+}
+
+// writeConfiguration writes configuration data to the output file (or stdout if output is "-").
+func writeConfiguration(output string, data []byte) errors.E {
+	var err error
+	if output != "-" {
+		err = os.WriteFile(kong.ExpandPath(output), data, fileMode)
 	} else {
 		_, err = os.Stdout.Write(data)
 	}
 	if err != nil {
 		errE := errors.WithMessage(err, "cannot write configuration")
-		errors.Details(errE)["path"] = c.Output
+		errors.Details(errE)["path"] = output
 		return errE
 	}
+	return nil
+}
+
+// sopsCommand returns the command which can be used to encrypt sensitive values in c.Output.
+func (c *GetCommand) sopsCommand(globals *Globals) string {
+	args := []string{os.Args[0]}
+	if globals.ChangeTo != "" {
+		args = append(args, "-C", string(globals.ChangeTo))
+	}
+	args = append(args, "sops", "--encrypt", "--mac-only-encrypted", "--in-place")
+	if c.EncSuffix != "" {
+		args = append(args, "--encrypted-suffix", c.EncSuffix)
+	} else if c.EncComment != "" {
+		args = append(args, "--encrypted-comment-regex", regexp.QuoteMeta(c.EncComment))
+	}
+	args = append(args, c.Output)
+	return strings.Join(args, " ")
+}
 
-	fmt.Fprintf(os.Stderr, "Got everything.\n")
-	if hasSensitive {
-		args := []string{os.Args[0]}
-		if globals.ChangeTo != "" {
-			args = append(args, "-C", string(globals.ChangeTo))
+// getConfiguration fetches GitLab project's configuration for resources using g.
+//
+// Resources are fetched concurrently, each into its own configuration struct,
+// which are then combined in the order of resources. Objects matching ignore
+// rules are removed.
+func getConfiguration(ctx context.Context, client *gitlab.Client, g *Getter, resources []Resource, ignore map[string][]map[string]string) (*Configuration, errors.E) {
+	configurations := make([]Configuration, len(resources))
+	errE := parallel(g.Workers, len(resources), func(i int) errors.E {
+		ctx := g.withResource(ctx, resources[i].Name())
+		errE := resources[i].Get(ctx, g, client, &configurations[i])
+		if errE != nil {
+			errors.Details(errE)["section"] = resources[i].Name()
 		}
-		args = append(args, "sops", "--encrypt", "--mac-only-encrypted", "--in-place")
-		if c.EncSuffix != "" {
-			args = append(args, "--encrypted-suffix", c.EncSuffix)
-		} else if c.EncComment != "" {
-			args = append(args, "--encrypted-comment-regex", regexp.QuoteMeta(c.EncComment))
+		return errE
+	})
+	if errE != nil {
+		return nil, errE
+	}
+
+	var configuration Configuration
+
+	rulesConfiguration := Configuration{Ignore: ignore} //nolint:exhaustruct
+	for i, resource := range resources {
+		configuration.copySection(&configurations[i], resource.Name())
+
+		rules, errE := rulesConfiguration.ignoreRules(resource.Name())
+		if errE != nil {
+			return nil, errE
 		}
-		args = append(args, c.Output)
-		fmt.Fprintf(os.Stderr, "WARNING: Configuration includes sensitive values. Consider encrypting the file. You can use SOPS, e.g.:\n  %s\n", strings.Join(args, " ")) //nolint:lll
+		configuration.setSection(resource.Name(), removeIgnored(configuration.Section(resource.Name()), rules))
 	}
 
-	return nil
+	return &configuration, nil
+}
+
+// annotateSections annotates sensitive values in configuration sections of resources
+// with encComment or adds encSuffix to their field names.
+//
+// It returns true if configuration includes sensitive values.
+func annotateSections(configuration *Configuration, resources []Resource, encComment, encSuffix string) bool {
+	hasSensitive := false
+	for _, resource := range resources {
+		s := annotateSensitive(configuration.Section(resource.Name()), resource.Sensitive(), encComment, encSuffix)
+		hasSensitive = hasSensitive || s
+	}
+	return hasSensitive
+}
+
+// readIgnore returns ignore rules from the existing configuration file at path,
+// so that get skips the same objects as set does. It returns nil if the file
+// does not exist or path is "-".
+//
+// The file is not decrypted because ignore rules are not sensitive.
+func readIgnore(path, encSuffix string) (map[string][]map[string]string, errors.E) {
+	if path == "-" {
+		return nil, nil
+	}
+	_, err := os.Stat(kong.ExpandPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	configuration, errE := readConfiguration(path, true, encSuffix)
+	if errE != nil {
+		return nil, errE
+	}
+	return configuration.Ignore, nil
 }
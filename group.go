@@ -0,0 +1,143 @@
+package config
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+// groupResource is a Resource for group settings.
+type groupResource struct{}
+
+// Name implements Resource interface.
+func (groupResource) Name() string {
+	return "group"
+}
+
+// Descriptions implements Resource interface.
+func (groupResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getGroupDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (groupResource) Sensitive() []string {
+	return nil
+}
+
+// Get implements Resource interface.
+func (groupResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getGroup(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (groupResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateGroup(ctx, client, configuration)
+}
+
+// getGroup fetches the group from GitLab groups API endpoint.
+func getGroup(ctx context.Context, client *gitlab.Client, group string) (map[string]interface{}, errors.E) {
+	u := "groups/" + gitlab.PathEscape(group)
+	options := &gitlab.GetGroupOptions{ //nolint:exhaustruct
+		WithProjects: gitlab.Bool(false),
+	}
+
+	req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
+	if err != nil {
+		return nil, errors.WithMessage(err, `failed to get group`)
+	}
+
+	g := map[string]interface{}{}
+
+	_, err = client.Do(req, &g)
+	if err != nil {
+		return nil, errors.WithMessage(err, `failed to get group`)
+	}
+
+	return g, nil
+}
+
+// getGroup populates configuration struct with configuration available
+// from GitLab groups API endpoint.
+func (g *Getter) getGroup(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting group...\n")
+
+	descriptions, errE := getGroupDescriptions(ctx, g.Docs)
+	if errE != nil {
+		return errE
+	}
+
+	group, errE := getGroup(ctx, client, g.Group)
+	if errE != nil {
+		return errE
+	}
+
+	// Only retain those keys which can be edited through the API
+	// (which are those available in descriptions).
+	for key := range group {
+		_, ok := descriptions[key]
+		if !ok {
+			delete(group, key)
+		}
+	}
+
+	// Add comments for keys. We process these keys before writing YAML out.
+	describeKeys(group, descriptions)
+
+	configuration.Group = group
+
+	return nil
+}
+
+// parseGroupDocumentation parses GitLab's documentation in Markdown for
+// groups API endpoint and extracts description of fields used to describe
+// an individual group.
+func parseGroupDocumentation(input []byte) (map[string]string, errors.E) {
+	return parseTable(input, "Update group", func(key string) string {
+		switch key {
+		case "avatar":
+			// Avatar has to be uploaded as a file.
+			return ""
+		case "path":
+			// Changing the path changes group's URL and
+			// we want to be able to find the group next time.
+			return ""
+		default:
+			return key
+		}
+	})
+}
+
+// getGroupDescriptions obtains description of fields used to describe
+// an individual group from GitLab's documentation for groups API endpoint.
+func getGroupDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "groups.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get group configuration descriptions")
+	}
+	return parseGroupDocumentation(data)
+}
+
+// updateGroup updates GitLab group's configuration using GitLab groups API endpoint
+// based on the configuration struct.
+func (s *Setter) updateGroup(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	if configuration.Group == nil {
+		return nil
+	}
+
+	s.Printf("Updating group...\n")
+
+	u := "groups/" + gitlab.PathEscape(s.Group)
+
+	req, err := client.NewRequest(http.MethodPut, u, configuration.Group, contextOptions(ctx))
+	if err != nil {
+		return errors.WithMessage(err, "failed to update GitLab group")
+	}
+	_, err = client.Do(req, nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed to update GitLab group")
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	_ "embed"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Groups file is from: https://gitlab.com/gitlab-org/gitlab/-/raw/master/doc/api/groups.md
+//
+//go:embed testdata/groups.md
+var testGroups []byte
+
+// Group level variables file is from: https://gitlab.com/gitlab-org/gitlab/-/raw/master/doc/api/group_level_variables.md
+//
+//go:embed testdata/group_level_variables.md
+var testGroupVariables []byte
+
+// Group labels file is from: https://gitlab.com/gitlab-org/gitlab/-/raw/master/doc/api/group_labels.md
+//
+//go:embed testdata/group_labels.md
+var testGroupLabels []byte
+
+func TestParseGroupDocumentation(t *testing.T) {
+	t.Parallel()
+
+	data, errE := parseGroupDocumentation(testGroups)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]string{
+		"auto_devops_enabled":                      "Default to Auto DevOps pipeline for all projects within this group. Type: boolean",
+		"default_branch_protection":                "See Options for default_branch_protection. Type: integer",
+		"description":                              "The description of the group. Type: string",
+		"emails_disabled":                          "Disable email notifications. Type: boolean",
+		"extra_shared_runners_minutes_limit":       "Can be set by administrators only. Additional compute minutes for this group. Type: integer",
+		"file_template_project_id":                 "The ID of a project to load custom file templates from. Type: integer",
+		"ip_restriction_ranges":                    "Comma-separated list of IP addresses or subnet masks to restrict group access. Introduced in GitLab 15.1. Type: string",
+		"lfs_enabled":                              "Enable/disable Large File Storage (LFS) for the projects in this group. Type: boolean",
+		"membership_lock":                          "Users cannot be added to projects in this group. Type: boolean",
+		"mentions_disabled":                        "Disable the capability of a group from getting mentioned. Type: boolean",
+		"name":                                     "The name of the group. Type: string",
+		"prevent_forking_outside_group":            "When enabled, users can not fork projects from this group to external namespaces. Type: boolean",
+		"prevent_sharing_groups_outside_hierarchy": "See Prevent group sharing outside the group hierarchy. This attribute is only available on top-level groups. Introduced in GitLab 14.1. Type: boolean",
+		"project_creation_level":                   "Determine if developers can create projects in the group. Can be noone (No one), maintainer (users with the Maintainer role), or developer (users with the Developer or Maintainer role). Type: string",
+		"request_access_enabled":                   "Allow users to request member access. Type: boolean",
+		"require_two_factor_authentication":        "Require all users in this group to set up two-factor authentication. Type: boolean",
+		"share_with_group_lock":                    "Prevent sharing a project with another group within this group. Type: boolean",
+		"shared_runners_minutes_limit":             "Can be set by administrators only. Maximum number of monthly compute minutes for this group. Can be nil (default; inherit system default), 0 (unlimited), or > 0. Type: integer",
+		"shared_runners_setting":                   "See Options for shared_runners_setting. Enable or disable shared runners for a group's subgroups and projects. Type: string",
+		"subgroup_creation_level":                  "Allowed to create subgroups. Can be owner (Owners), or maintainer (users with the Maintainer role). Type: string",
+		"two_factor_grace_period":                  "Time before Two-factor authentication is enforced (in hours). Type: integer",
+		"visibility":                               "The visibility level of the group. Can be private, internal, or public. Type: string",
+		"wiki_access_level":                        "The wiki access level. Can be disabled, private, or enabled. Introduced in GitLab 15.0. Type: string",
+	}, data)
+}
+
+func TestParseGroupVariablesDocumentation(t *testing.T) {
+	t.Parallel()
+
+	data, errE := parseVariablesDocumentation(testGroupVariables, groupVariables)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]string{
+		"description":       "The description of the variable. Default: null. Introduced in GitLab 16.2. Type: string",
+		"environment_scope": "The environment scope of a variable. Type: string",
+		"key":               "The key of a variable; must have no more than 255 characters; only A-Z, a-z, 0-9, and _ are allowed. Type: string",
+		"masked":            "Whether the variable is masked. Type: boolean",
+		"protected":         "Whether the variable is protected. Type: boolean",
+		"raw":               "Whether the variable is treated as a raw string. Default: false. When true, variables in the value are not expanded. Type: boolean",
+		"value":             "The value of a variable. Type: string",
+		"variable_type":     "The type of a variable. Available types are: env_var (default) and file. Type: string",
+	}, data)
+}
+
+func TestParseGroupLabelsDocumentation(t *testing.T) {
+	t.Parallel()
+
+	data, errE := parseLabelsDocumentation(testGroupLabels, groupLabels)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]string{
+		"color":       "The color of the label given in 6-digit hex notation with leading '#' sign (for example, #FFAABB) or one of the CSS color names. Type: string",
+		"description": "The description of the label,. Type: string",
+		"id":          "The ID or title of a group's label. Type: integer or string",
+		"name":        "The name of the label. Type: string",
+		"priority":    "The priority of the label. Must be greater or equal than zero or null to remove the priority. Type: integer",
+	}, data)
+}
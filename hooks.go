@@ -0,0 +1,299 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+// hooksResource is a Resource for project webhooks.
+type hooksResource struct{}
+
+// Name implements Resource interface.
+func (hooksResource) Name() string {
+	return "hooks"
+}
+
+// Descriptions implements Resource interface.
+func (hooksResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getHooksDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (hooksResource) Sensitive() []string {
+	return []string{"token"}
+}
+
+// Keys implements KeyedResource interface.
+func (hooksResource) Keys() [][]string {
+	return [][]string{{"url"}}
+}
+
+// Describe implements KeyedResource interface.
+func (hooksResource) Describe() []string {
+	return nil
+}
+
+// Required implements RequiredResource interface.
+func (hooksResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getHooksRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (hooksResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getHooks(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (hooksResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateHooks(ctx, client, configuration)
+}
+
+// getHooks populates configuration struct with configuration available
+// from GitLab project hooks API endpoint.
+//
+// GitLab does not return hooks' secret tokens, so they are not populated.
+func (g *Getter) getHooks(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting project hooks...\n")
+
+	configuration.Hooks = []map[string]interface{}{}
+
+	descriptions, errE := getHooksDescriptions(ctx, g.Docs)
+	if errE != nil {
+		return errE
+	}
+	// We need "url" later on.
+	if _, ok := descriptions["url"]; !ok {
+		return errors.New(`"url" field is missing in project hooks descriptions`)
+	}
+	configuration.HooksComment = formatDescriptions(descriptions)
+
+	u := fmt.Sprintf("projects/%s/hooks", gitlab.PathEscape(g.Project))
+	options := &gitlab.ListProjectHooksOptions{
+		PerPage: maxGitLabPageSize,
+		Page:    1,
+	}
+
+	for {
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project hooks")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		hooks := []map[string]interface{}{}
+
+		response, err := client.Do(req, &hooks)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project hooks")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		if len(hooks) == 0 {
+			break
+		}
+
+		for _, hook := range hooks {
+			// Only retain those keys which can be edited through the API
+			// (which are those available in descriptions).
+			for key := range hook {
+				_, ok := descriptions[key]
+				if !ok {
+					delete(hook, key)
+				}
+			}
+
+			url, ok := hook["url"]
+			if !ok {
+				return errors.New(`project hook is missing field "url"`)
+			}
+			_, ok = url.(string)
+			if !ok {
+				errE := errors.New(`project hook's field "url" is not a string`)
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", url)
+				errors.Details(errE)["value"] = url
+				return errE
+			}
+
+			configuration.Hooks = append(configuration.Hooks, hook)
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	// We sort by hook URL so that we have deterministic order.
+	sort.SliceStable(configuration.Hooks, func(i, j int) bool {
+		// We checked that url is string above.
+		return configuration.Hooks[i]["url"].(string) < configuration.Hooks[j]["url"].(string) //nolint:forcetypeassert,errcheck
+	})
+
+	return nil
+}
+
+// parseHooksDocumentation parses GitLab's documentation in Markdown for
+// projects API endpoint and extracts description of fields used to describe
+// an individual project hook.
+func parseHooksDocumentation(input []byte) (map[string]string, errors.E) {
+	return parseTable(input, "Add project hook", nil)
+}
+
+// getHooksDescriptions obtains description of fields used to describe an individual
+// project hook from GitLab's documentation for projects API endpoint.
+func getHooksDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "projects.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get project hooks descriptions")
+	}
+	return parseHooksDocumentation(data)
+}
+
+// getHooksRequired obtains fields required to create an individual project hook
+// from GitLab's documentation for projects API endpoint.
+func getHooksRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "projects.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get project hooks required fields")
+	}
+	return parseRequired(data, "Add project hook", nil)
+}
+
+// updateHooks updates GitLab project's hooks using GitLab project hooks API endpoint
+// based on the configuration struct.
+//
+// Hooks are matched to existing hooks based on the URL. Unmatched hooks are created as new.
+func (s *Setter) updateHooks(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	if configuration.Hooks == nil {
+		return nil
+	}
+
+	s.Printf("Updating project hooks...\n")
+
+	options := &gitlab.ListProjectHooksOptions{
+		PerPage: maxGitLabPageSize,
+		Page:    1,
+	}
+
+	hooks := []*gitlab.ProjectHook{}
+
+	for {
+		hs, response, err := client.Projects.ListProjectHooks(s.Project, options, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project hooks")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		hooks = append(hooks, hs...)
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	existingHooksSet := mapset.NewThreadUnsafeSet[int]()
+	urlsToIDs := map[string][]int{}
+	for _, hook := range hooks {
+		// Hooks matching ignore rules are neither updated nor deleted.
+		if configuration.ignored("hooks", map[string]interface{}{"url": hook.URL}) {
+			continue
+		}
+		urlsToIDs[hook.URL] = append(urlsToIDs[hook.URL], hook.ID)
+		existingHooksSet.Add(hook.ID)
+	}
+
+	// Match hooks to existing hooks by URL. If there are multiple
+	// hooks with the same URL, they are matched in order.
+	wantedIDs := make([]int, len(configuration.Hooks))
+	wantedHooksSet := mapset.NewThreadUnsafeSet[int]()
+	for i, hook := range configuration.Hooks {
+		url, ok := hook["url"]
+		if !ok {
+			errE := errors.New(`project hook is missing field "url"`)
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+		u, ok := url.(string)
+		if !ok {
+			errE := errors.New(`project hook's field "url" is not a string`)
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["type"] = fmt.Sprintf("%T", url)
+			errors.Details(errE)["value"] = url
+			return errE
+		}
+		ids := urlsToIDs[u]
+		if len(ids) > 0 {
+			wantedIDs[i] = ids[0]
+			wantedHooksSet.Add(ids[0])
+			urlsToIDs[u] = ids[1:]
+		}
+	}
+
+	extraHooks := existingHooksSet.Difference(wantedHooksSet).ToSlice()
+	if !configuration.prune("hooks", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraHooks = nil
+	}
+	slices.Sort(extraHooks)
+	for _, hookID := range extraHooks {
+		_, err := client.Projects.DeleteProjectHook(s.Project, hookID, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to delete project hook")
+			errors.Details(errE)["hook"] = hookID
+			return errE
+		}
+	}
+
+	for i, hook := range configuration.Hooks {
+		id := wantedIDs[i]
+		if id == 0 {
+			u := fmt.Sprintf("projects/%s/hooks", gitlab.PathEscape(s.Project))
+			req, err := client.NewRequest(http.MethodPost, u, hook, contextOptions(ctx))
+			if err != nil {
+				// We made sure above that all hooks in configuration have URL.
+				errE := errors.WithMessage(err, "failed to create project hook")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["hook"] = hook["url"]
+				return errE
+			}
+			_, err = client.Do(req, nil)
+			if err != nil {
+				errE := errors.WithMessage(err, "failed to create project hook")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["hook"] = hook["url"]
+				return errE
+			}
+		} else {
+			u := fmt.Sprintf("projects/%s/hooks/%d", gitlab.PathEscape(s.Project), id)
+			req, err := client.NewRequest(http.MethodPut, u, hook, contextOptions(ctx))
+			if err != nil {
+				errE := errors.WithMessage(err, "failed to update project hook")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["hook"] = id
+				return errE
+			}
+			_, err = client.Do(req, nil)
+			if err != nil {
+				errE := errors.WithMessage(err, "failed to update project hook")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["hook"] = id
+				return errE
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHooksDocumentation(t *testing.T) {
+	t.Parallel()
+
+	data, errE := parseHooksDocumentation(testProjects)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]string{
+		"confidential_issues_events": "Trigger hook on confidential issues events. Type: boolean",
+		"confidential_note_events":   "Trigger hook on confidential note events. Type: boolean",
+		"deployment_events":          "Trigger hook on deployment events. Type: boolean",
+		"enable_ssl_verification":    "Do SSL verification when triggering the hook. Type: boolean",
+		"issues_events":              "Trigger hook on issues events. Type: boolean",
+		"job_events":                 "Trigger hook on job events. Type: boolean",
+		"merge_requests_events":      "Trigger hook on merge requests events. Type: boolean",
+		"note_events":                "Trigger hook on note events. Type: boolean",
+		"pipeline_events":            "Trigger hook on pipeline events. Type: boolean",
+		"push_events":                "Trigger hook on push events. Type: boolean",
+		"push_events_branch_filter":  "Trigger hook on push events for matching branches only. Type: string",
+		"releases_events":            "Trigger hook on release events. Type: boolean",
+		"tag_push_events":            "Trigger hook on tag push events. Type: boolean",
+		"token":                      "Secret token to validate received payloads; the token isn't returned in the response. Type: string",
+		"url":                        "The hook URL. Type: string",
+		"wiki_page_events":           "Trigger hook on wiki events. Type: boolean",
+	}, data)
+}
+
+func TestFetchApplyHooks(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Fproject/hooks": `[` +
+			`{"id":2,"url":"https://example.com/old","project_id":3,"push_events":true,"created_at":"2012-10-12T17:04:47Z"},` +
+			`{"id":1,"url":"https://example.com/hook","project_id":3,"push_events":true,"enable_ssl_verification":true,"created_at":"2012-10-12T17:04:47Z"}` +
+			`]`,
+		"PUT /api/v4/projects/group%2Fproject/hooks/1":    `{}`,
+		"POST /api/v4/projects/group%2Fproject/hooks":     `{}`,
+		"DELETE /api/v4/projects/group%2Fproject/hooks/2": "",
+	})
+
+	requests := server.fetchApply(t, Options{Only: []string{"hooks"}}, func(configuration *Configuration) { //nolint:exhaustruct
+		assert.Equal(t, []map[string]interface{}{
+			{"url": "https://example.com/hook", "push_events": true, "enable_ssl_verification": true},
+			{"url": "https://example.com/old", "push_events": true},
+		}, configuration.Hooks)
+
+		configuration.Hooks = []map[string]interface{}{
+			{"url": "https://example.com/hook", "push_events": false, "token": "secret"},
+			{"url": "https://example.com/new", "tag_push_events": true},
+		}
+	})
+
+	assert.Equal(t, []string{
+		"GET /api/v4/projects/group%2Fproject/hooks?page=1&per_page=100",
+		"GET /api/v4/projects/group%2Fproject/hooks?page=1&per_page=100",
+		"DELETE /api/v4/projects/group%2Fproject/hooks/2",
+		`PUT /api/v4/projects/group%2Fproject/hooks/1 {"push_events":false,"token":"secret","url":"https://example.com/hook"}`,
+		`POST /api/v4/projects/group%2Fproject/hooks {"tag_push_events":true,"url":"https://example.com/new"}`,
+	}, requests)
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	"gitlab.com/tozd/go/errors"
+)
+
+// ignoreKey is the top-level key of the configuration file with rules
+// for objects which are not managed.
+const ignoreKey = "ignore"
+
+// ignoreRule matches an object when all its patterns match values of
+// corresponding fields of the object.
+type ignoreRule map[string]*regexp.Regexp
+
+// compilePattern compiles a pattern from an ignore rule.
+//
+// Patterns between slashes (e.g., /^DEPLOY_/) are regular expressions.
+// Other patterns are globs where * matches any sequence of characters
+// and ? matches any single character. Globs have to match the whole value.
+func compilePattern(pattern string) (*regexp.Regexp, errors.E) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			errE := errors.WithMessage(err, "invalid regular expression")
+			errors.Details(errE)["pattern"] = pattern
+			return nil, errE
+		}
+		return re, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()), nil
+}
+
+// ignoreRules compiles ignore rules for the configuration section with the name.
+func (c *Configuration) ignoreRules(name string) ([]ignoreRule, errors.E) {
+	rules := []ignoreRule{}
+	for i, r := range c.Ignore[name] {
+		rule := ignoreRule{}
+		for field, pattern := range r {
+			re, errE := compilePattern(pattern)
+			if errE != nil {
+				errors.Details(errE)["section"] = name
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["field"] = field
+				return nil, errE
+			}
+			rule[field] = re
+		}
+		if len(rule) > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// ignored returns true if the object (or just its identity fields) matches
+// any ignore rule for the configuration section with the name.
+//
+// Invalid ignore rules do not match anything. They are reported by
+// withoutIgnored which is called before updating the configuration section.
+func (c *Configuration) ignored(name string, object map[string]interface{}) bool {
+	rules, errE := c.ignoreRules(name)
+	if errE != nil {
+		return false
+	}
+	return matchesIgnoreRules(rules, object)
+}
+
+// matchesIgnoreRules returns true if the object matches any of the rules.
+func matchesIgnoreRules(rules []ignoreRule, object map[string]interface{}) bool {
+RULES:
+	for _, rule := range rules {
+		for field, re := range rule {
+			value, ok := object[field]
+			if !ok || value == nil {
+				continue RULES
+			}
+			if !re.MatchString(fmt.Sprint(value)) {
+				continue RULES
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// removeIgnored returns the configuration section (a list of objects) without
+// objects matching any of the rules. Other configuration sections are returned as-is.
+func removeIgnored(section interface{}, rules []ignoreRule) interface{} {
+	if len(rules) == 0 {
+		return section
+	}
+
+	switch s := section.(type) {
+	case []map[string]interface{}:
+		if s == nil {
+			return s
+		}
+		result := []map[string]interface{}{}
+		for _, object := range s {
+			if !matchesIgnoreRules(rules, object) {
+				result = append(result, object)
+			}
+		}
+		return result
+	case []interface{}:
+		if s == nil {
+			return s
+		}
+		result := []interface{}{}
+		for _, value := range s {
+			object, ok := value.(map[string]interface{})
+			if !ok || !matchesIgnoreRules(rules, object) {
+				result = append(result, value)
+			}
+		}
+		return result
+	default:
+		return section
+	}
+}
+
+// withoutIgnored returns a copy of the configuration without objects matching
+// ignore rules in the configuration section with the name.
+func (c *Configuration) withoutIgnored(name string) (*Configuration, errors.E) {
+	rules, errE := c.ignoreRules(name)
+	if errE != nil {
+		return nil, errE
+	}
+	if len(rules) == 0 {
+		return c, nil
+	}
+
+	result := *c
+	result.setSection(name, removeIgnored(c.Section(name), rules))
+	if dirs, ok := c.sourceDirs[name]; ok {
+		// Directories are kept aligned with objects which remain.
+		kept := []string{}
+		for i, object := range sectionObjects(c.Section(name)) {
+			if i < len(dirs) && (object == nil || !matchesIgnoreRules(rules, object)) {
+				kept = append(kept, dirs[i])
+			}
+		}
+		result.sourceDirs = maps.Clone(c.sourceDirs)
+		result.sourceDirs[name] = kept
+	}
+	return &result, nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompilePattern(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		Pattern string
+		Value   string
+		Match   bool
+	}{
+		{"DEPLOY_*", "DEPLOY_TOKEN", true},
+		{"DEPLOY_*", "MY_DEPLOY_TOKEN", false},
+		{"release/v?", "release/v1", true},
+		{"release/v?", "release/v10", false},
+		{"a.b", "axb", false},
+		{"*", "", true},
+		{"/^CI_/", "CI_TOKEN", true},
+		{"/TOKEN/", "CI_TOKEN_2", true},
+		{"/^CI_/", "MY_CI_TOKEN", false},
+	} {
+		t.Run(tt.Pattern+" "+tt.Value, func(t *testing.T) {
+			t.Parallel()
+
+			re, errE := compilePattern(tt.Pattern)
+			require.NoError(t, errE, "% -+#.1v", errE)
+			assert.Equal(t, tt.Match, re.MatchString(tt.Value))
+		})
+	}
+
+	_, errE := compilePattern("/[/")
+	assert.ErrorContains(t, errE, "invalid regular expression")
+}
+
+func TestWithoutIgnored(t *testing.T) {
+	t.Parallel()
+
+	configuration := &Configuration{
+		Variables: []map[string]interface{}{
+			{"key": "DEPLOY_TOKEN", "environment_scope": "production", "value": "foo"},
+			{"key": "DEPLOY_TOKEN", "environment_scope": "*", "value": "bar"},
+			{"key": "OTHER", "environment_scope": "production", "value": "baz"},
+		},
+		Extra: map[string]interface{}{
+			"custom": []interface{}{
+				map[string]interface{}{"name": "tmp-1"},
+				map[string]interface{}{"name": "keep"},
+			},
+		},
+		Ignore: map[string][]map[string]string{
+			"variables": {{"key": "DEPLOY_*", "environment_scope": "production"}},
+			"custom":    {{"name": "/^tmp-/"}},
+		},
+	}
+
+	result, errE := configuration.withoutIgnored("variables")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []map[string]interface{}{
+		{"key": "DEPLOY_TOKEN", "environment_scope": "*", "value": "bar"},
+		{"key": "OTHER", "environment_scope": "production", "value": "baz"},
+	}, result.Variables)
+	// The configuration itself is not modified.
+	assert.Len(t, configuration.Variables, 3)
+
+	result, errE = configuration.withoutIgnored("custom")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "keep"},
+	}, result.Extra["custom"])
+	assert.Len(t, configuration.Extra["custom"], 2)
+
+	result, errE = configuration.withoutIgnored("labels")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Same(t, configuration, result)
+
+	assert.True(t, configuration.ignored("variables", map[string]interface{}{"key": "DEPLOY_KEY", "environment_scope": "production"}))
+	assert.False(t, configuration.ignored("variables", map[string]interface{}{"key": "DEPLOY_KEY"}))
+
+	configuration.Ignore["variables"] = []map[string]string{{"key": "/(/"}}
+	_, errE = configuration.withoutIgnored("variables")
+	assert.ErrorContains(t, errE, "invalid regular expression")
+}
+
+func TestDiffConfigurationIgnore(t *testing.T) {
+	t.Parallel()
+
+	live := &Configuration{
+		ProtectedBranches: []map[string]interface{}{
+			{"name": "main", "allow_force_push": false},
+			{"name": "release/1.0", "allow_force_push": true},
+		},
+	}
+	wanted := &Configuration{
+		ProtectedBranches: []map[string]interface{}{
+			{"name": "main", "allow_force_push": false},
+			{"name": "release/2.0", "allow_force_push": false},
+		},
+		Ignore: map[string][]map[string]string{
+			"protected_branches": {{"name": "release/*"}},
+		},
+	}
+
+	changes, errE := diffConfiguration(Resources(), live, wanted, false)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Empty(t, changes)
+}
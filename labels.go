@@ -1,9 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"slices"
 	"sort"
 
@@ -12,24 +12,122 @@ import (
 	"gitlab.com/tozd/go/errors"
 )
 
+// labelsEndpoint describes GitLab API endpoint for labels of a project
+// or of a group, together with its documentation.
+type labelsEndpoint struct {
+	// Owner is "project" or "group".
+	Owner string
+	// Doc is the file with GitLab's documentation of the API endpoint.
+	Doc string
+	// Create and Edit are headings of documentation sections describing
+	// creating and editing a label.
+	Create string
+	Edit   string
+}
+
+//nolint:gochecknoglobals
+var (
+	projectLabels = labelsEndpoint{
+		Owner:  "project",
+		Doc:    "labels.md",
+		Create: "Create a new label",
+		Edit:   "Edit an existing label",
+	}
+	groupLabels = labelsEndpoint{
+		Owner:  "group",
+		Doc:    "group_labels.md",
+		Create: "Create a new group label",
+		Edit:   "Update a group label",
+	}
+)
+
+// labelsResource is a Resource for project labels.
+type labelsResource struct{}
+
+// Name implements Resource interface.
+func (labelsResource) Name() string {
+	return "labels"
+}
+
+// Descriptions implements Resource interface.
+func (labelsResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getLabelsDescriptions(ctx, docs, projectLabels)
+}
+
+// Sensitive implements Resource interface.
+func (labelsResource) Sensitive() []string {
+	return nil
+}
+
+// Keys implements KeyedResource interface.
+func (labelsResource) Keys() [][]string {
+	return [][]string{{"id"}, {"name"}}
+}
+
+// Describe implements KeyedResource interface.
+func (labelsResource) Describe() []string {
+	return []string{"name"}
+}
+
+// Required implements RequiredResource interface.
+func (labelsResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getLabelsRequired(ctx, docs, projectLabels)
+}
+
+// Get implements Resource interface.
+func (labelsResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getLabels(ctx, client, configuration, projectLabels)
+}
+
+// Update implements Resource interface.
+func (labelsResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateLabels(ctx, client, configuration, projectLabels)
+}
+
+// groupLabelsResource is a Resource for group labels. It differs from
+// labelsResource only in the API endpoint used.
+type groupLabelsResource struct {
+	labelsResource
+}
+
+// Descriptions implements Resource interface.
+func (groupLabelsResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getLabelsDescriptions(ctx, docs, groupLabels)
+}
+
+// Required implements RequiredResource interface.
+func (groupLabelsResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getLabelsRequired(ctx, docs, groupLabels)
+}
+
+// Get implements Resource interface.
+func (groupLabelsResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getLabels(ctx, client, configuration, groupLabels)
+}
+
+// Update implements Resource interface.
+func (groupLabelsResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateLabels(ctx, client, configuration, groupLabels)
+}
+
 // getLabels populates configuration struct with configuration available
-// from GitLab labels API endpoint.
-func (c *GetCommand) getLabels(client *gitlab.Client, configuration *Configuration) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting project labels...\n")
+// from GitLab project or group labels API endpoint.
+func (g *Getter) getLabels(ctx context.Context, client *gitlab.Client, configuration *Configuration, endpoint labelsEndpoint) errors.E {
+	g.Printf("Getting %s labels...\n", endpoint.Owner)
 
 	configuration.Labels = []map[string]interface{}{}
 
-	descriptions, errE := getLabelsDescriptions(c.DocsRef)
+	descriptions, errE := getLabelsDescriptions(ctx, g.Docs, endpoint)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 	// We need "id" later on.
 	if _, ok := descriptions["id"]; !ok {
-		return false, errors.New(`"id" field is missing in project labels descriptions`)
+		return errors.Errorf(`"id" field is missing in %s labels descriptions`, endpoint.Owner)
 	}
 	configuration.LabelsComment = formatDescriptions(descriptions)
 
-	u := fmt.Sprintf("projects/%s/labels", gitlab.PathEscape(c.Project))
+	u := g.OwnerPath(endpoint.Owner) + "/labels"
 	options := &gitlab.ListLabelsOptions{ //nolint:exhaustruct
 		ListOptions: gitlab.ListOptions{
 			PerPage: maxGitLabPageSize,
@@ -39,20 +137,20 @@ func (c *GetCommand) getLabels(client *gitlab.Client, configuration *Configurati
 	}
 
 	for {
-		req, err := client.NewRequest(http.MethodGet, u, options, nil)
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
 		if err != nil {
-			errE := errors.WithMessage(err, "failed to get project labels")
+			errE := errors.WithMessagef(err, "failed to get %s labels", endpoint.Owner)
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		labels := []map[string]interface{}{}
 
 		response, err := client.Do(req, &labels)
 		if err != nil {
-			errE := errors.WithMessage(err, "failed to get project labels")
+			errE := errors.WithMessagef(err, "failed to get %s labels", endpoint.Owner)
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		if len(labels) == 0 {
@@ -74,14 +172,14 @@ func (c *GetCommand) getLabels(client *gitlab.Client, configuration *Configurati
 
 			id, ok := label["id"]
 			if !ok {
-				return false, errors.New(`project label is missing field "id"`)
+				return errors.Errorf(`%s label is missing field "id"`, endpoint.Owner)
 			}
 			_, ok = id.(int)
 			if !ok {
-				errE := errors.New(`project label's field "id" is not an integer`)
+				errE := errors.Errorf(`%s label's field "id" is not an integer`, endpoint.Owner)
 				errors.Details(errE)["type"] = fmt.Sprintf("%T", id)
 				errors.Details(errE)["value"] = id
-				return false, errE
+				return errE
 			}
 
 			configuration.Labels = append(configuration.Labels, label)
@@ -100,18 +198,18 @@ func (c *GetCommand) getLabels(client *gitlab.Client, configuration *Configurati
 		return configuration.Labels[i]["id"].(int) < configuration.Labels[j]["id"].(int) //nolint:forcetypeassert,errcheck
 	})
 
-	return false, nil
+	return nil
 }
 
 // parseLabelsDocumentation parses GitLab's documentation in Markdown for
-// labels API endpoint and extracts description of fields used to describe
-// an individual label.
-func parseLabelsDocumentation(input []byte) (map[string]string, errors.E) {
-	newDescriptions, err := parseTable(input, "Create a new label", nil)
+// project or group labels API endpoint and extracts description of fields
+// used to describe an individual label.
+func parseLabelsDocumentation(input []byte, endpoint labelsEndpoint) (map[string]string, errors.E) {
+	newDescriptions, err := parseTable(input, endpoint.Create, nil)
 	if err != nil {
 		return nil, err
 	}
-	editDescriptions, err := parseTable(input, "Edit an existing label", nil)
+	editDescriptions, err := parseTable(input, endpoint.Edit, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -121,27 +219,38 @@ func parseLabelsDocumentation(input []byte) (map[string]string, errors.E) {
 }
 
 // getLabelsDescriptions obtains description of fields used to describe
-// an individual label from GitLab's documentation for labels API endpoint.
-func getLabelsDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/labels.md", gitRef))
+// an individual label from GitLab's documentation for project or group
+// labels API endpoint.
+func getLabelsDescriptions(ctx context.Context, docs Docs, endpoint labelsEndpoint) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, endpoint.Doc)
 	if err != nil {
-		return nil, errors.WithMessage(err, "failed to get project labels descriptions")
+		return nil, errors.WithMessagef(err, "failed to get %s labels descriptions", endpoint.Owner)
 	}
-	return parseLabelsDocumentation(data)
+	return parseLabelsDocumentation(data, endpoint)
 }
 
-// updateLabels updates GitLab project's labels using GitLab labels API endpoint
-// based on the configuration struct.
+// getLabelsRequired obtains fields required to create an individual label
+// from GitLab's documentation for project or group labels API endpoint.
+func getLabelsRequired(ctx context.Context, docs Docs, endpoint labelsEndpoint) ([]string, errors.E) {
+	data, err := docs.Get(ctx, endpoint.Doc)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get %s labels required fields", endpoint.Owner)
+	}
+	return parseRequired(data, endpoint.Create, nil)
+}
+
+// updateLabels updates GitLab project's or group's labels using GitLab
+// project or group labels API endpoint based on the configuration struct.
 //
 // Labels without the ID field are matched to existing labels based on the name.
 // Unmatched labels are created as new. Save configuration with label IDs to be able
 // to rename existing labels.
-func (c *SetCommand) updateLabels(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updateLabels(ctx context.Context, client *gitlab.Client, configuration *Configuration, endpoint labelsEndpoint) errors.E {
 	if configuration.Labels == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating project labels...\n")
+	s.Printf("Updating %s labels...\n", endpoint.Owner)
 
 	options := &gitlab.ListLabelsOptions{ //nolint:exhaustruct
 		ListOptions: gitlab.ListOptions{
@@ -154,9 +263,19 @@ func (c *SetCommand) updateLabels(client *gitlab.Client, configuration *Configur
 	labels := []*gitlab.Label{}
 
 	for {
-		ls, response, err := client.Labels.ListLabels(c.Project, options)
+		// We do not use go-gitlab's functions because they differ between projects and groups.
+		req, err := client.NewRequest(http.MethodGet, s.OwnerPath(endpoint.Owner)+"/labels", options, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessagef(err, "failed to get %s labels", endpoint.Owner)
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		ls := []*gitlab.Label{}
+
+		response, err := client.Do(req, &ls)
 		if err != nil {
-			errE := errors.WithMessage(err, "failed to get project labels")
+			errE := errors.WithMessagef(err, "failed to get %s labels", endpoint.Owner)
 			errors.Details(errE)["page"] = options.Page
 			return errE
 		}
@@ -173,6 +292,10 @@ func (c *SetCommand) updateLabels(client *gitlab.Client, configuration *Configur
 	existingLabelsSet := mapset.NewThreadUnsafeSet[int]()
 	namesToIDs := map[string]int{}
 	for _, label := range labels {
+		// Labels matching ignore rules are neither updated nor deleted.
+		if configuration.ignored("labels", map[string]interface{}{"id": label.ID, "name": label.Name}) {
+			continue
+		}
 		namesToIDs[label.Name] = label.ID
 		existingLabelsSet.Add(label.ID)
 	}
@@ -185,7 +308,7 @@ func (c *SetCommand) updateLabels(client *gitlab.Client, configuration *Configur
 			// If ID is provided, the label should exist.
 			iid, ok := id.(int) //nolint:govet
 			if !ok {
-				errE := errors.New(`project label's field "id" is not an integer`)
+				errE := errors.Errorf(`%s label's field "id" is not an integer`, endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["type"] = fmt.Sprintf("%T", id)
 				errors.Details(errE)["value"] = id
@@ -201,13 +324,13 @@ func (c *SetCommand) updateLabels(client *gitlab.Client, configuration *Configur
 
 		name, ok := label["name"]
 		if !ok {
-			errE := errors.Errorf(`project label is missing field "name"`)
+			errE := errors.Errorf(`%s label is missing field "name"`, endpoint.Owner)
 			errors.Details(errE)["index"] = i
 			return errE
 		}
 		n, ok := name.(string)
 		if !ok {
-			errE := errors.New(`project label's field "name" is not a string`)
+			errE := errors.Errorf(`%s label's field "name" is not a string`, endpoint.Owner)
 			errors.Details(errE)["index"] = i
 			errors.Details(errE)["type"] = fmt.Sprintf("%T", name)
 			errors.Details(errE)["value"] = name
@@ -229,20 +352,24 @@ func (c *SetCommand) updateLabels(client *gitlab.Client, configuration *Configur
 	}
 
 	extraLabels := existingLabelsSet.Difference(wantedLabelsSet).ToSlice()
+	if !configuration.prune("labels", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraLabels = nil
+	}
 	slices.Sort(extraLabels)
 	for _, labelID := range extraLabels {
 		// TODO: Use go-gitlab's function once it is updated to new API.
 		//       See: https://github.com/xanzy/go-gitlab/issues/1321
-		u := fmt.Sprintf("projects/%s/labels/%d", gitlab.PathEscape(c.Project), labelID)
-		req, err := client.NewRequest(http.MethodDelete, u, nil, nil)
+		u := fmt.Sprintf("%s/labels/%d", s.OwnerPath(endpoint.Owner), labelID)
+		req, err := client.NewRequest(http.MethodDelete, u, nil, contextOptions(ctx))
 		if err != nil {
-			errE := errors.WithMessage(err, "failed to delete project label")
+			errE := errors.WithMessagef(err, "failed to delete %s label", endpoint.Owner)
 			errors.Details(errE)["label"] = labelID
 			return errE
 		}
 		_, err = client.Do(req, nil)
 		if err != nil {
-			errE := errors.WithMessage(err, "failed to delete project label")
+			errE := errors.WithMessagef(err, "failed to delete %s label", endpoint.Owner)
 			errors.Details(errE)["label"] = labelID
 			return errE
 		}
@@ -251,18 +378,18 @@ func (c *SetCommand) updateLabels(client *gitlab.Client, configuration *Configur
 	for i, label := range configuration.Labels {
 		id, ok := label["id"]
 		if !ok { //nolint:dupl
-			u := fmt.Sprintf("projects/%s/labels", gitlab.PathEscape(c.Project))
-			req, err := client.NewRequest(http.MethodPost, u, label, nil)
+			u := s.OwnerPath(endpoint.Owner) + "/labels"
+			req, err := client.NewRequest(http.MethodPost, u, label, contextOptions(ctx))
 			if err != nil {
 				// We made sure above that all labels in configuration without label ID have name.
-				errE := errors.WithMessage(err, "failed to create project label")
+				errE := errors.WithMessagef(err, "failed to create %s label", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["label"] = label["name"]
 				return errE
 			}
 			_, err = client.Do(req, nil)
 			if err != nil { // We made sure above that all labels in configuration without label ID have name.
-				errE := errors.WithMessage(err, "failed to create project label")
+				errE := errors.WithMessagef(err, "failed to create %s label", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["label"] = label["name"]
 				return errE
@@ -271,17 +398,17 @@ func (c *SetCommand) updateLabels(client *gitlab.Client, configuration *Configur
 			// We made sure above that all labels in configuration with label ID exist
 			// and that they are ints.
 			iid := id.(int) //nolint:errcheck,forcetypeassert
-			u := fmt.Sprintf("projects/%s/labels/%d", gitlab.PathEscape(c.Project), iid)
-			req, err := client.NewRequest(http.MethodPut, u, label, nil)
+			u := fmt.Sprintf("%s/labels/%d", s.OwnerPath(endpoint.Owner), iid)
+			req, err := client.NewRequest(http.MethodPut, u, label, contextOptions(ctx))
 			if err != nil {
-				errE := errors.WithMessage(err, "failed to update project label")
+				errE := errors.WithMessagef(err, "failed to update %s label", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["label"] = iid
 				return errE
 			}
 			_, err = client.Do(req, nil)
 			if err != nil {
-				errE := errors.WithMessage(err, "failed to update project label")
+				errE := errors.WithMessagef(err, "failed to update %s label", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["label"] = iid
 				return errE
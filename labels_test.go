@@ -16,7 +16,7 @@ var testLabels []byte
 func TestParseLabelsDocumentation(t *testing.T) {
 	t.Parallel()
 
-	data, errE := parseLabelsDocumentation(testLabels)
+	data, errE := parseLabelsDocumentation(testLabels, projectLabels)
 	require.NoError(t, errE, "% -+#.1v", errE)
 	assert.Equal(t, map[string]string{
 		"color":       "The color of the label given in 6-digit hex notation with leading '#' sign (for example, #FFAABB) or one of the CSS color names. Type: string",
@@ -2,6 +2,7 @@ package config
 
 import (
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/yuin/goldmark/ast"
@@ -15,6 +16,9 @@ import (
 
 var tierRegexp = regexp.MustCompile(` *\((FREE|PREMIUM|ULTIMATE)?( (SELF|SAAS|ALL))?( (EXPERIMENT|BTEA))?\)$`)
 
+// Some tables mark required fields with an icon, e.g., "**{check-circle}** Yes".
+var iconRegexp = regexp.MustCompile(`\{[a-z-]+\}`)
+
 // walker is the expected number of columns to find in a table.
 const tableColumns = 4
 
@@ -156,6 +160,47 @@ func (v *extractTableVisitor) tableWalker(n ast.Node, entering bool) (ast.WalkSt
 //
 // keyMapper is used to optionally (when not nil) further transform found fields.
 func parseTable(input []byte, heading string, keyMapper func(string) string) (map[string]string, errors.E) {
+	return parseTableColumn(input, heading, keyMapper, func(row []string) string {
+		description := row[3]
+		if len(description) > 0 {
+			if !strings.HasSuffix(description, ".") && !strings.HasSuffix(description, ")") {
+				description += "."
+			}
+			description += " "
+		}
+		return description + "Type: " + row[1]
+	})
+}
+
+// parseRequired is a halper function which parses Markdown input and find the first table after
+// the heading, which then converts into a sorted list of fields (attributes) which are required.
+//
+// Fields which are required only under some condition are not returned.
+//
+// keyMapper is used to optionally (when not nil) further transform found fields.
+func parseRequired(input []byte, heading string, keyMapper func(string) string) ([]string, errors.E) {
+	values, errE := parseTableColumn(input, heading, keyMapper, func(row []string) string {
+		return strings.ToLower(strings.TrimSpace(iconRegexp.ReplaceAllString(row[2], "")))
+	})
+	if errE != nil {
+		return nil, errE
+	}
+	required := []string{}
+	for key, value := range values {
+		if value == "yes" {
+			required = append(required, key)
+		}
+	}
+	slices.Sort(required)
+	return required, nil
+}
+
+// parseTableColumn parses Markdown input and find the first table after the heading,
+// which then converts into a map between fields (attributes) and values returned by
+// the value function for their rows.
+//
+// keyMapper is used to optionally (when not nil) further transform found fields.
+func parseTableColumn(input []byte, heading string, keyMapper func(string) string, value func([]string) string) (map[string]string, errors.E) {
 	p := parser.NewParser(
 		parser.WithBlockParsers(parser.DefaultBlockParsers()...),
 		parser.WithInlineParsers(parser.DefaultInlineParsers()...),
@@ -178,7 +223,8 @@ func parseTable(input []byte, heading string, keyMapper func(string) string) (ma
 				return errE
 			}
 			for i, h := range expectedHeader {
-				if row[i] != h {
+				// Some tables do not capitalize all column names.
+				if !strings.EqualFold(row[i], h) {
 					errE := errors.New("invalid header")
 					errors.Details(errE)["row"] = row
 					return errE
@@ -215,14 +261,7 @@ func parseTable(input []byte, heading string, keyMapper func(string) string) (ma
 				errors.Details(errE)["row"] = row
 				return "", errE
 			}
-			description := row[3]
-			if len(description) > 0 {
-				if !strings.HasSuffix(description, ".") && !strings.HasSuffix(description, ")") {
-					description += "."
-				}
-				description += " "
-			}
-			return description + "Type: " + row[1], nil
+			return value(row), nil
 		},
 		Result:     map[string]string{},
 		currentRow: nil,
@@ -0,0 +1,466 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+// Bot users which GitLab creates for project and group access tokens are members
+// managed through tokens, so they are not part of the members configuration section.
+var botUsernameRegexp = regexp.MustCompile(`^(project|group)_\d+_bot`)
+
+// membersResource is a Resource for direct project members.
+//
+// Bot users of access tokens are skipped.
+type membersResource struct{}
+
+// Name implements Resource interface.
+func (membersResource) Name() string {
+	return "members"
+}
+
+// Descriptions implements Resource interface.
+func (membersResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getMembersDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (membersResource) Sensitive() []string {
+	return nil
+}
+
+// Keys implements KeyedResource interface.
+func (membersResource) Keys() [][]string {
+	return [][]string{{"user_id"}, {"username"}}
+}
+
+// Describe implements KeyedResource interface.
+func (membersResource) Describe() []string {
+	return []string{"username"}
+}
+
+// Required implements RequiredResource interface.
+func (membersResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getMembersRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (membersResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getMembers(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (membersResource) Resolve(ctx context.Context, t *Target, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	return t.resolveMembers(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (membersResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateMembers(ctx, client, configuration)
+}
+
+// getMembers populates configuration struct with configuration available
+// from GitLab project members API endpoint.
+//
+// Only direct members are returned. Inherited members (e.g., from ancestor groups)
+// are listed only in the comment of the configuration section.
+func (g *Getter) getMembers(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting project members...\n")
+
+	configuration.Members = []map[string]interface{}{}
+
+	descriptions, errE := getMembersDescriptions(ctx, g.Docs)
+	if errE != nil {
+		return errE
+	}
+	// We need "user_id" later on.
+	if _, ok := descriptions["user_id"]; !ok {
+		return errors.New(`"user_id" field is missing in project members descriptions`)
+	}
+
+	u := fmt.Sprintf("projects/%s/members", gitlab.PathEscape(g.Project))
+	options := &gitlab.ListProjectMembersOptions{ //nolint:exhaustruct
+		ListOptions: gitlab.ListOptions{
+			PerPage: maxGitLabPageSize,
+			Page:    1,
+		},
+	}
+
+	directMembersSet := mapset.NewThreadUnsafeSet[int]()
+
+	for {
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project members")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		members := []map[string]interface{}{}
+
+		response, err := client.Do(req, &members)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project members")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		if len(members) == 0 {
+			break
+		}
+
+		for _, member := range members {
+			// Making sure id and access level are an integer.
+			castFloatsToInts(member)
+
+			// Members API returns user's ID as "id".
+			id, ok := member["id"]
+			if !ok {
+				return errors.New(`project member is missing field "id"`)
+			}
+			iid, ok := id.(int)
+			if !ok {
+				errE := errors.New(`project member's field "id" is not an integer`)
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", id)
+				errors.Details(errE)["value"] = id
+				return errE
+			}
+			username, ok := member["username"]
+			if !ok {
+				return errors.New(`project member is missing field "username"`)
+			}
+			name, ok := username.(string)
+			if !ok {
+				errE := errors.New(`project member's field "username" is not a string`)
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", username)
+				errors.Details(errE)["value"] = username
+				return errE
+			}
+			directMembersSet.Add(iid)
+			if botUsernameRegexp.MatchString(name) {
+				continue
+			}
+			member["user_id"] = iid
+
+			// Only retain those keys which can be edited through the API
+			// (which are those available in descriptions).
+			for key := range member {
+				_, ok := descriptions[key]
+				if !ok {
+					delete(member, key)
+				}
+			}
+
+			if g.AccessLevelNames {
+				accessLevelToName(member, "access_level")
+			}
+
+			configuration.Members = append(configuration.Members, member)
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	// We sort by username so that we have deterministic order.
+	sort.SliceStable(configuration.Members, func(i, j int) bool {
+		// We checked that username is string above.
+		return configuration.Members[i]["username"].(string) < configuration.Members[j]["username"].(string) //nolint:forcetypeassert,errcheck
+	})
+
+	for _, member := range configuration.Members {
+		if g.IDs {
+			// Add comment for the sequence item itself.
+			member["comment:"] = member["username"]
+			delete(member, "username")
+		} else {
+			delete(member, "user_id")
+		}
+	}
+
+	inherited, errE := g.getInheritedMembers(ctx, client, directMembersSet)
+	if errE != nil {
+		return errE
+	}
+	configuration.MembersComment = formatDescriptions(descriptions) + inherited
+
+	return nil
+}
+
+// getInheritedMembers returns a comment listing project members which are not
+// direct members of the project, using GitLab project members API endpoint.
+func (g *Getter) getInheritedMembers(ctx context.Context, client *gitlab.Client, directMembersSet mapset.Set[int]) (string, errors.E) {
+	options := &gitlab.ListProjectMembersOptions{ //nolint:exhaustruct
+		ListOptions: gitlab.ListOptions{
+			PerPage: maxGitLabPageSize,
+			Page:    1,
+		},
+	}
+
+	members := []*gitlab.ProjectMember{}
+
+	for {
+		ms, response, err := client.ProjectMembers.ListAllProjectMembers(g.Project, options, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get all project members")
+			errors.Details(errE)["page"] = options.Page
+			return "", errE
+		}
+
+		for _, member := range ms {
+			if !directMembersSet.Contains(member.ID) && !botUsernameRegexp.MatchString(member.Username) {
+				members = append(members, member)
+			}
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	if len(members) == 0 {
+		return "", nil
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Username < members[j].Username
+	})
+
+	output := "Inherited members (not configured here):\n"
+	for _, member := range members {
+		level := map[string]interface{}{"access_level": int(member.AccessLevel)}
+		accessLevelToName(level, "access_level")
+		output += fmt.Sprintf("- %s: %v", member.Username, level["access_level"])
+		if member.ExpiresAt != nil {
+			output += fmt.Sprintf(", expires at %s", member.ExpiresAt.String())
+		}
+		output += "\n"
+	}
+	return output, nil
+}
+
+// parseMembersDocumentation parses GitLab's documentation in Markdown for
+// members API endpoint and extracts description of fields used to describe
+// an individual project member.
+func parseMembersDocumentation(input []byte) (map[string]string, errors.E) {
+	return parseTable(input, "Add a member to a group or project", func(key string) string {
+		switch key {
+		case "invite_source", "tasks_to_be_done", "tasks_project_id":
+			// These only influence the invitation and are not stored with the member.
+			return ""
+		default:
+			return key
+		}
+	})
+}
+
+// getMembersDescriptions obtains description of fields used to describe an individual
+// project member from GitLab's documentation for members API endpoint.
+func getMembersDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "members.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get project members descriptions")
+	}
+	descriptions, errE := parseMembersDocumentation(data)
+	if errE != nil {
+		return nil, errE
+	}
+	// Users can be referenced by their usernames instead of IDs.
+	descriptions["username"] = "The username of the member, instead of user_id. Type: string"
+	allowAccessLevelNames(descriptions, "access_level")
+	return descriptions, nil
+}
+
+// getMembersRequired obtains fields required to add an individual project member
+// from GitLab's documentation for members API endpoint.
+func getMembersRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "members.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get project members required fields")
+	}
+	required, errE := parseRequired(data, "Add a member to a group or project", nil)
+	if errE != nil {
+		return nil, errE
+	}
+	// The user can be provided with the "username" field instead.
+	return slices.DeleteFunc(required, func(field string) bool { return field == "user_id" }), nil
+}
+
+// resolveMembers returns a copy of the configuration struct in which usernames
+// in "username" fields are replaced with IDs in "user_id" fields, and names
+// of access levels in "access_level" fields with their values.
+//
+// Members with usernames of bot users of access tokens are removed.
+func (t *Target) resolveMembers(ctx context.Context, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	if configuration.Members == nil {
+		return configuration, nil
+	}
+
+	resolved := *configuration
+	members, _ := deepCopy(configuration.Members).([]map[string]interface{})
+	resolved.Members = []map[string]interface{}{}
+	for i, member := range members {
+		if username, ok := member["username"].(string); ok && botUsernameRegexp.MatchString(username) {
+			continue
+		}
+		resolved.Members = append(resolved.Members, member)
+		errE := resolveField(ctx, client, member, "username", "user_id", t.names.userID)
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+		errE = accessLevelFromName(member, "access_level")
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+	}
+
+	return &resolved, nil
+}
+
+// updateMembers updates GitLab project's direct members using GitLab project
+// members API endpoint based on the configuration struct.
+//
+// Members are matched to existing direct members based on the user ID.
+// Unmatched members are added as new. Bot users of access tokens are neither
+// updated nor removed.
+func (s *Setter) updateMembers(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	if configuration.Members == nil {
+		return nil
+	}
+
+	s.Printf("Updating project members...\n")
+
+	options := &gitlab.ListProjectMembersOptions{ //nolint:exhaustruct
+		ListOptions: gitlab.ListOptions{
+			PerPage: maxGitLabPageSize,
+			Page:    1,
+		},
+	}
+
+	members := []*gitlab.ProjectMember{}
+
+	for {
+		ms, response, err := client.ProjectMembers.ListProjectMembers(s.Project, options, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project members")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		members = append(members, ms...)
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	existingMembersSet := mapset.NewThreadUnsafeSet[int]()
+	botMembersSet := mapset.NewThreadUnsafeSet[int]()
+	for _, member := range members {
+		if botUsernameRegexp.MatchString(member.Username) {
+			botMembersSet.Add(member.ID)
+			continue
+		}
+		// Members matching ignore rules are neither updated nor removed.
+		if configuration.ignored("members", map[string]interface{}{"user_id": member.ID, "username": member.Username}) {
+			continue
+		}
+		existingMembersSet.Add(member.ID)
+	}
+
+	wantedMembersSet := mapset.NewThreadUnsafeSet[int]()
+	for i, member := range configuration.Members {
+		id, ok := member["user_id"]
+		if !ok {
+			errE := errors.New(`project member is missing field "user_id"`)
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+		iid, ok := id.(int)
+		if !ok {
+			errE := errors.New(`project member's field "user_id" is not an integer`)
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["type"] = fmt.Sprintf("%T", id)
+			errors.Details(errE)["value"] = id
+			return errE
+		}
+		wantedMembersSet.Add(iid)
+	}
+
+	extraMembers := existingMembersSet.Difference(wantedMembersSet).ToSlice()
+	if !configuration.prune("members", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraMembers = nil
+	}
+	slices.Sort(extraMembers)
+	for _, userID := range extraMembers {
+		_, err := client.ProjectMembers.DeleteProjectMember(s.Project, userID, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to remove project member")
+			errors.Details(errE)["user"] = userID
+			return errE
+		}
+	}
+
+	for i, member := range configuration.Members {
+		// We checked that user id is int above.
+		userID := member["user_id"].(int) //nolint:errcheck,forcetypeassert
+
+		if botMembersSet.Contains(userID) {
+			continue
+		}
+
+		if !existingMembersSet.Contains(userID) { //nolint:dupl
+			u := fmt.Sprintf("projects/%s/members", gitlab.PathEscape(s.Project))
+			req, err := client.NewRequest(http.MethodPost, u, member, contextOptions(ctx))
+			if err != nil {
+				errE := errors.WithMessage(err, "failed to add project member")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["user"] = userID
+				return errE
+			}
+			_, err = client.Do(req, nil)
+			if err != nil {
+				errE := errors.WithMessage(err, "failed to add project member")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["user"] = userID
+				return errE
+			}
+		} else {
+			u := fmt.Sprintf("projects/%s/members/%d", gitlab.PathEscape(s.Project), userID)
+			req, err := client.NewRequest(http.MethodPut, u, member, contextOptions(ctx))
+			if err != nil {
+				errE := errors.WithMessage(err, "failed to update project member")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["user"] = userID
+				return errE
+			}
+			_, err = client.Do(req, nil)
+			if err != nil {
+				errE := errors.WithMessage(err, "failed to update project member")
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["user"] = userID
+				return errE
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	_ "embed"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Members file is from: https://gitlab.com/gitlab-org/gitlab/-/raw/master/doc/api/members.md
+//
+//go:embed testdata/members.md
+var testMembers []byte
+
+func TestParseMembersDocumentation(t *testing.T) {
+	t.Parallel()
+
+	data, errE := parseMembersDocumentation(testMembers)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]string{
+		"access_level":   "A valid access level. Type: integer",
+		"expires_at":     "A date string in the format YEAR-MONTH-DAY. Type: string",
+		"member_role_id": "The ID of a member role. Type: integer",
+		"user_id":        "The user ID of the new member or multiple IDs separated by commas. Type: integer/string",
+	}, data)
+}
+
+func TestFetchApplyMembers(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Fproject/members": `[` +
+			`{"id":2,"username":"bob","name":"Bob","state":"active","access_level":30,"expires_at":null},` +
+			`{"id":1,"username":"alice","name":"Alice","state":"active","access_level":40,"expires_at":"2099-01-01"},` +
+			`{"id":4,"username":"dave","name":"Dave","state":"active","access_level":20,"expires_at":null}` +
+			`]`,
+		"GET /api/v4/projects/group%2Fproject/members/all": `[` +
+			`{"id":1,"username":"alice","name":"Alice","state":"active","access_level":40,"expires_at":"2099-01-01"},` +
+			`{"id":2,"username":"bob","name":"Bob","state":"active","access_level":30,"expires_at":null},` +
+			`{"id":4,"username":"dave","name":"Dave","state":"active","access_level":20,"expires_at":null},` +
+			`{"id":5,"username":"eve","name":"Eve","state":"active","access_level":50,"expires_at":null},` +
+			`{"id":6,"username":"frank","name":"Frank","state":"active","access_level":10,"expires_at":"2099-06-01"}` +
+			`]`,
+		"GET /api/v4/users":                                 `[{"id":1,"username":"alice"},{"id":2,"username":"bob"},{"id":3,"username":"carol"}]`,
+		"POST /api/v4/projects/group%2Fproject/members":     `{"id":3,"username":"carol"}`,
+		"PUT /api/v4/projects/group%2Fproject/members/1":    `{}`,
+		"PUT /api/v4/projects/group%2Fproject/members/2":    `{}`,
+		"DELETE /api/v4/projects/group%2Fproject/members/4": "",
+	})
+
+	opts := Options{Only: []string{"members"}, AccessLevelNames: true} //nolint:exhaustruct
+
+	requests := server.fetchApply(t, opts, func(configuration *Configuration) {
+		assert.Equal(t, []map[string]interface{}{
+			{"username": "alice", "access_level": "maintainer", "expires_at": "2099-01-01"},
+			{"username": "bob", "access_level": "developer", "expires_at": nil},
+			{"username": "dave", "access_level": "reporter", "expires_at": nil},
+		}, configuration.Members)
+
+		// Inherited members are listed only in the comment.
+		g := Getter{Target: Target{Project: "group/project", Docs: Docs{Ref: DefaultDocsRef}}, AccessLevelNames: true} //nolint:exhaustruct
+		fetched := &Configuration{}                                                                                    //nolint:exhaustruct
+		errE := g.getMembers(t.Context(), server.client, fetched)
+		require.NoError(t, errE, "% -+#.1v", errE)
+		data, errE := toConfigurationYAML(fetched, excludedSections([]Resource{membersResource{}}))
+		require.NoError(t, errE, "% -+#.1v", errE)
+		assert.Equal(t, ""+
+			"# access_level: A valid access level. Type: integer or string\n"+
+			"# expires_at: A date string in the format YEAR-MONTH-DAY. Type: string\n"+
+			"# member_role_id: The ID of a member role. Type: integer\n"+
+			"# user_id: The user ID of the new member or multiple IDs separated by commas.\n"+
+			"# Type: integer/string\n"+
+			"# username: The username of the member, instead of user_id. Type: string\n"+
+			"# Inherited members (not configured here):\n"+
+			"# - eve: owner\n"+
+			"# - frank: guest, expires at 2099-06-01\n"+
+			"members:\n"+
+			"  - access_level: maintainer\n"+
+			"    expires_at: \"2099-01-01\"\n"+
+			"    username: alice\n"+
+			"  - access_level: developer\n"+
+			"    expires_at: null\n"+
+			"    username: bob\n"+
+			"  - access_level: reporter\n"+
+			"    expires_at: null\n"+
+			"    username: dave\n",
+			string(data),
+		)
+
+		configuration.Members = []map[string]interface{}{
+			{"username": "alice", "access_level": "maintainer", "expires_at": nil},
+			{"username": "bob", "access_level": "developer"},
+			{"username": "carol", "access_level": "reporter", "expires_at": "2099-01-01"},
+		}
+	})
+
+	assert.Equal(t, []string{
+		"GET /api/v4/projects/group%2Fproject/members?page=1&per_page=100",
+		"GET /api/v4/projects/group%2Fproject/members/all?page=1&per_page=100",
+		"GET /api/v4/projects/group%2Fproject/members?page=1&per_page=100",
+		"GET /api/v4/projects/group%2Fproject/members/all?page=1&per_page=100",
+		"GET /api/v4/users?username=alice",
+		"GET /api/v4/users?username=bob",
+		"GET /api/v4/users?username=carol",
+		"GET /api/v4/projects/group%2Fproject/members?page=1&per_page=100",
+		"DELETE /api/v4/projects/group%2Fproject/members/4",
+		`PUT /api/v4/projects/group%2Fproject/members/1 {"access_level":40,"expires_at":null,"user_id":1}`,
+		`PUT /api/v4/projects/group%2Fproject/members/2 {"access_level":30,"user_id":2}`,
+		`POST /api/v4/projects/group%2Fproject/members {"access_level":20,"expires_at":"2099-01-01","user_id":3}`,
+	}, requests)
+}
+
+func TestApplyMembersKeepsTokenBots(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Fproject/access_tokens":  `[]`,
+		"POST /api/v4/projects/group%2Fproject/access_tokens": `{"id":1,"user_id":9,"token":"new-token"}`,
+		"GET /api/v4/projects/group%2Fproject/members": `[` +
+			`{"id":1,"username":"alice","name":"Alice","state":"active","access_level":40,"expires_at":null},` +
+			`{"id":9,"username":"project_7_bot_0123abcd","name":"ci","state":"active","access_level":30,"expires_at":"2099-01-01"}` +
+			`]`,
+		"GET /api/v4/users": `[{"id":1,"username":"alice"}]`,
+		"PUT /api/v4/projects/group%2Fproject/members/1": `{}`,
+	})
+
+	opts := Options{ //nolint:exhaustruct
+		Only:         []string{"project_access_tokens", "members"},
+		TokensOutput: filepath.Join(t.TempDir(), "tokens.yml"),
+	}
+
+	configuration := &Configuration{ //nolint:exhaustruct
+		ProjectAccessTokens: []map[string]interface{}{
+			{"name": "ci", "scopes": []interface{}{"api"}, "access_level": "developer", "expires_at": "2099-01-01"},
+		},
+		Members: []map[string]interface{}{
+			{"username": "alice", "access_level": "maintainer"},
+			// Bot users in the configuration are ignored as well.
+			{"username": "project_7_bot_0123abcd", "access_level": "guest"},
+		},
+	}
+
+	_, errE := Apply(t.Context(), server.client, "group/project", configuration, opts)
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	// The bot user of the new token is not removed.
+	assert.Equal(t, []string{
+		"GET /api/v4/projects/group%2Fproject/access_tokens?page=1&per_page=100",
+		`POST /api/v4/projects/group%2Fproject/access_tokens {"access_level":30,"expires_at":"2099-01-01","name":"ci","scopes":["api"]}`,
+		"GET /api/v4/users?username=alice",
+		"GET /api/v4/projects/group%2Fproject/members?page=1&per_page=100",
+		`PUT /api/v4/projects/group%2Fproject/members/1 {"access_level":40,"user_id":1}`,
+	}, server.requests)
+}
@@ -0,0 +1,394 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// mergeConfigurationFile merges configuration data generated for resources into
+// the existing configuration file at path and returns the merged data. If the
+// file does not exist, data is returned as-is.
+//
+// Only values which changed are updated, new fields and objects are added with
+// their comments, and fields and objects which are not in data anymore are removed.
+// Everything else in the existing file (comments, order of fields and objects,
+// configuration sections which were not fetched or are set to null, SOPS metadata) is kept.
+//
+// If the file is encrypted with SOPS, unchanged encrypted values are kept as they are.
+// Changed or new sensitive values cannot be merged into an encrypted file because they
+// would end up not encrypted, so an error is returned for them.
+func mergeConfigurationFile(path string, data []byte, resources []Resource, encSuffix string) ([]byte, errors.E) {
+	existingData, err := os.ReadFile(kong.ExpandPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return data, nil
+	} else if err != nil {
+		errE := errors.WithMessage(err, "cannot read configuration")
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+
+	existing, errE := parseDocument(path, existingData)
+	if errE != nil {
+		return nil, errE
+	}
+	if existing == nil {
+		return data, nil
+	}
+	generated, errE := parseDocument(path, data)
+	if errE != nil {
+		return nil, errE
+	}
+
+	// Decrypted existing configuration is used to compare values which are encrypted.
+	plain := existing
+	encrypted := mappingIndex(existing, "sops") >= 0
+	if encrypted {
+		plainData, errE := readConfigurationData(path, false) //nolint:govet
+		if errE != nil {
+			return nil, errE
+		}
+		plain, errE = parseDocument(path, plainData)
+		if errE != nil {
+			return nil, errE
+		}
+	}
+
+	m := merger{
+		encrypted: encrypted,
+		encSuffix: encSuffix,
+		dir:       filepath.Dir(kong.ExpandPath(path)),
+		sensitive: []string{},
+	}
+	for i := 0; i < len(generated.Content); i += 2 {
+		key, value := generated.Content[i], generated.Content[i+1]
+		spec := sectionSpec{Keys: nil, Describe: nil, Sensitive: nil, WriteOnly: nil, Identity: nil, KeyDefaults: nil}
+		index := slices.IndexFunc(resources, func(r Resource) bool { return r.Name() == key.Value })
+		if index >= 0 {
+			spec = getSectionSpec(resources[index])
+		}
+
+		j := mappingIndex(existing, key.Value)
+		if j < 0 {
+			m.checkSensitiveValue(key.Value, value, spec)
+			existing.Content = append(existing.Content, key, value)
+			continue
+		}
+		if isNull(existing.Content[j+1]) {
+			// The configuration section is not managed.
+			continue
+		}
+		existing.Content[j+1] = m.mergeSection(key.Value, existing.Content[j+1], mappingValue(plain, key.Value), value, spec)
+	}
+
+	if len(m.sensitive) > 0 {
+		errE := errors.New("cannot merge sensitive values into an encrypted configuration, decrypt it first")
+		errors.Details(errE)["path"] = path
+		errors.Details(errE)["fields"] = m.sensitive
+		return nil, errE
+	}
+
+	return toYAML(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{existing}}) //nolint:exhaustruct
+}
+
+// parseDocument parses YAML data and returns its top-level mapping node,
+// or nil if data is empty.
+func parseDocument(path string, data []byte) (*yaml.Node, errors.E) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot unmarshal configuration")
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		errE := errors.New("configuration is not an object")
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+	return root.Content[0], nil
+}
+
+// mappingIndex returns the index of the key node with the key in the mapping node,
+// or -1 if there is none.
+func mappingIndex(node *yaml.Node, key string) int {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingValue returns the value node for the key in the mapping node,
+// or nil if there is none.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	i := mappingIndex(node, key)
+	if i < 0 {
+		return nil
+	}
+	return node.Content[i+1]
+}
+
+// decodeNode decodes the node into a value. It returns nil if the node is nil
+// or cannot be decoded.
+func decodeNode(node *yaml.Node) interface{} {
+	if node == nil {
+		return nil
+	}
+	var value interface{}
+	err := node.Decode(&value)
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// merger merges generated YAML nodes into existing YAML nodes.
+type merger struct {
+	encrypted bool
+	encSuffix string
+	// dir is the directory of the existing configuration file.
+	dir string
+
+	// sensitive are paths of sensitive values which would end up not encrypted.
+	sensitive []string
+}
+
+// fieldName returns the name of the field with encSuffix removed.
+func (m *merger) fieldName(key string) string {
+	if m.encSuffix != "" {
+		return strings.TrimSuffix(key, m.encSuffix)
+	}
+	return key
+}
+
+// checkSensitiveField records the path if the field is sensitive and its value
+// is about to be written into an encrypted configuration.
+func (m *merger) checkSensitiveField(path, field string, spec sectionSpec) {
+	if m.encrypted && slices.Contains(spec.Sensitive, m.fieldName(field)) {
+		m.sensitive = append(m.sensitive, path)
+	}
+}
+
+// checkSensitiveValue records paths of all sensitive fields in the value (an object
+// or a list of objects) which is about to be written into an encrypted configuration.
+func (m *merger) checkSensitiveValue(path string, value *yaml.Node, spec sectionSpec) {
+	switch value.Kind { //nolint:exhaustive
+	case yaml.MappingNode:
+		for i := 0; i < len(value.Content); i += 2 {
+			m.checkSensitiveField(path+"."+value.Content[i].Value, value.Content[i].Value, spec)
+		}
+	case yaml.SequenceNode:
+		for i, item := range value.Content {
+			m.checkSensitiveValue(path+"["+strconv.Itoa(i)+"]", item, spec)
+		}
+	}
+}
+
+// mergeSection merges the generated configuration section into the existing one
+// and returns the resulting node. Plain is the existing configuration section
+// decrypted, if the configuration is encrypted.
+func (m *merger) mergeSection(path string, existing, plain, generated *yaml.Node, spec sectionSpec) *yaml.Node {
+	if existing.Kind == yaml.SequenceNode && generated.Kind == yaml.SequenceNode && len(spec.Keys) > 0 {
+		m.mergeList(path, existing, plain, generated, spec)
+		return existing
+	}
+	if existing.Kind == yaml.MappingNode && generated.Kind == yaml.MappingNode {
+		m.mergeObject(path, existing, plain, generated, spec)
+		return existing
+	}
+	return m.mergeValue(path, "", existing, plain, generated, spec)
+}
+
+// mergeList merges generated objects into existing objects matching them using spec.
+//
+// Matched objects are merged and kept in their existing order, new objects are
+// appended, and existing objects which do not match any generated object are removed.
+func (m *merger) mergeList(path string, existing, plain, generated *yaml.Node, spec sectionSpec) {
+	existingItems := make([]map[string]interface{}, len(existing.Content))
+	identities := make([]string, len(existing.Content))
+	for i := range existing.Content {
+		var p *yaml.Node
+		if plain != nil && plain.Kind == yaml.SequenceNode && i < len(plain.Content) {
+			p = plain.Content[i]
+		}
+		item, _ := decodeNode(p).(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+		}
+		for key, value := range item {
+			if name := m.fieldName(key); name != key {
+				item[name] = value
+				delete(item, key)
+			}
+		}
+		existingItems[i] = item
+		if spec.Identity != nil {
+			identities[i] = spec.Identity(item, m.dir)
+		}
+	}
+
+	matched := make([]bool, len(existing.Content))
+	merged := make([]*yaml.Node, len(existing.Content))
+	added := []*yaml.Node{}
+	for _, item := range generated.Content {
+		wanted, _ := decodeNode(item).(map[string]interface{})
+		for key, value := range wanted {
+			if name := m.fieldName(key); name != key {
+				wanted[name] = value
+				delete(wanted, key)
+			}
+		}
+		i := -1
+		if spec.Identity != nil {
+			i = matchIdentity(identities, matched, spec.Identity(wanted, m.dir))
+			if i >= 0 && existing.Content[i].Kind == yaml.MappingNode && item.Kind == yaml.MappingNode {
+				item = withoutMissingKeys(existing.Content[i], item, spec)
+			}
+		}
+		if i < 0 {
+			i = matchItem(spec, existingItems, matched, wanted)
+		}
+		if i < 0 {
+			m.checkSensitiveValue(path+"["+describeItem(spec, wanted)+"]", item, spec)
+			added = append(added, item)
+			continue
+		}
+		matched[i] = true
+		var p *yaml.Node
+		if plain != nil && plain.Kind == yaml.SequenceNode && i < len(plain.Content) {
+			p = plain.Content[i]
+		}
+		itemPath := path + "[" + describeItem(spec, wanted) + "]"
+		if existing.Content[i].Kind == yaml.MappingNode && item.Kind == yaml.MappingNode {
+			m.mergeObject(itemPath, existing.Content[i], p, item, spec)
+			merged[i] = existing.Content[i]
+		} else {
+			merged[i] = m.mergeValue(itemPath, "", existing.Content[i], p, item, spec)
+		}
+	}
+
+	content := []*yaml.Node{}
+	for i, node := range merged {
+		if matched[i] {
+			content = append(content, node)
+		}
+	}
+	existing.Content = append(content, added...)
+}
+
+// matchIdentity returns the index of the object with the identity which has
+// not been matched yet, or -1 if there is none.
+func matchIdentity(identities []string, matched []bool, identity string) int {
+	if identity == "" {
+		return -1
+	}
+	for i, id := range identities {
+		if !matched[i] && id == identity {
+			return i
+		}
+	}
+	return -1
+}
+
+// withoutMissingKeys returns a copy of the generated object without key fields
+// which are missing from the existing object, so that an existing object matched
+// by its identity keeps its keys (e.g., a path to a file instead of its contents).
+func withoutMissingKeys(existing, generated *yaml.Node, spec sectionSpec) *yaml.Node {
+	node := *generated
+	node.Content = []*yaml.Node{}
+	for i := 0; i < len(generated.Content); i += 2 {
+		key := generated.Content[i].Value
+		if mappingIndex(existing, key) < 0 && slices.ContainsFunc(spec.Keys, func(keys []string) bool { return slices.Contains(keys, key) }) {
+			continue
+		}
+		node.Content = append(node.Content, generated.Content[i], generated.Content[i+1])
+	}
+	return &node
+}
+
+// mergeObject merges fields of the generated object into the existing object.
+//
+// Changed fields are updated in place, new fields are appended, and existing
+// fields which are not in the generated object are removed, unless they are sensitive
+// or write-only.
+func (m *merger) mergeObject(path string, existing, plain, generated *yaml.Node, spec sectionSpec) {
+	content := []*yaml.Node{}
+	for i := 0; i < len(existing.Content); i += 2 {
+		key := existing.Content[i].Value
+		g := mappingValue(generated, key)
+		if g == nil {
+			// Sensitive values which GitLab does not return (e.g., secret
+			// tokens of hooks) and write-only values are kept.
+			if slices.Contains(spec.Sensitive, m.fieldName(key)) || slices.Contains(spec.WriteOnly, key) {
+				content = append(content, existing.Content[i], existing.Content[i+1])
+			}
+			continue
+		}
+		fieldPath := path + "." + key
+		var value *yaml.Node
+		if existing.Content[i+1].Kind == yaml.MappingNode && g.Kind == yaml.MappingNode {
+			m.mergeObject(fieldPath, existing.Content[i+1], mappingValue(plain, key), g, spec)
+			value = existing.Content[i+1]
+		} else {
+			value = m.mergeValue(fieldPath, key, existing.Content[i+1], mappingValue(plain, key), g, spec)
+		}
+		content = append(content, existing.Content[i], value)
+	}
+	for i := 0; i < len(generated.Content); i += 2 {
+		key := generated.Content[i].Value
+		if mappingIndex(existing, key) >= 0 {
+			continue
+		}
+		m.checkSensitiveField(path+"."+key, key, spec)
+		content = append(content, generated.Content[i], generated.Content[i+1])
+	}
+	existing.Content = content
+}
+
+// mergeValue returns the existing node if its value is equal to the value of
+// the generated node and the generated node otherwise. Comments of the existing
+// node are kept.
+//
+// Field is the name of the field the value is for, if any.
+func (m *merger) mergeValue(path, field string, existing, plain, generated *yaml.Node, spec sectionSpec) *yaml.Node {
+	if plain == nil {
+		plain = existing
+	}
+	if equalValues(decodeNode(plain), decodeNode(generated)) {
+		return existing
+	}
+
+	if m.encrypted {
+		if existing.Kind == yaml.ScalarNode && strings.HasPrefix(existing.Value, "ENC[") {
+			m.sensitive = append(m.sensitive, path)
+		} else {
+			m.checkSensitiveField(path, field, spec)
+		}
+	}
+
+	node := *generated
+	if node.HeadComment == "" {
+		node.HeadComment = existing.HeadComment
+	}
+	if node.LineComment == "" {
+		node.LineComment = existing.LineComment
+	}
+	if node.FootComment == "" {
+		node.FootComment = existing.FootComment
+	}
+	return &node
+}
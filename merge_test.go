@@ -0,0 +1,256 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeConfigurationFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(output, []byte(""+
+		"# Our project settings.\n"+
+		"project:\n"+
+		"  # We want this.\n"+
+		"  lfs_enabled: true\n"+
+		"  description: old # Update it.\n"+
+		"  wiki_enabled: true\n"+
+		"labels:\n"+
+		"  # Custom comment.\n"+
+		"  - name: bug\n"+
+		"    color: '#FF0000'\n"+
+		"    id: 1\n"+
+		"  - name: gone\n"+
+		"    color: '#000000'\n"+
+		"    id: 2\n"+
+		"variables: null\n",
+	), 0o600))
+
+	configuration := &Configuration{
+		Project: map[string]interface{}{
+			"lfs_enabled":            true,
+			"description":            "new",
+			"issues_enabled":         false,
+			"comment:issues_enabled": "Enable issues.",
+			"comment:lfs_enabled":    "Enable LFS.",
+			"comment:description":    "Short project description.",
+		},
+		Labels: []map[string]interface{}{
+			{"id": 1, "name": "bug", "color": "#00FF00"},
+			{"id": 3, "name": "feature", "color": "#0000FF"},
+		},
+		LabelsComment: "Labels.",
+		Variables: []map[string]interface{}{
+			{"key": "FOO", "environment_scope": "*", "value": "bar"},
+		},
+		ProtectedTags:        []map[string]interface{}{},
+		ProtectedTagsComment: "Protected tags.",
+	}
+	resources := []Resource{projectResource{}, labelsResource{}, variablesResource{}, protectedTagsResource{}}
+	data, errE := toConfigurationYAML(configuration, excludedSections(resources))
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	merged, errE := mergeConfigurationFile(output, data, resources, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, ""+
+		"# Our project settings.\n"+
+		"project:\n"+
+		"  # We want this.\n"+
+		"  lfs_enabled: true\n"+
+		"  description: new # Update it.\n"+
+		"  # Enable issues.\n"+
+		"  issues_enabled: false\n"+
+		"labels:\n"+
+		"  # Custom comment.\n"+
+		"  - name: bug\n"+
+		"    color: '#00FF00'\n"+
+		"    id: 1\n"+
+		"  - color: '#0000FF'\n"+
+		"    id: 3\n"+
+		"    name: feature\n"+
+		"variables: null\n"+
+		"# Protected tags.\n"+
+		"protected_tags: []\n",
+		string(merged),
+	)
+
+	// Merging into a file which does not exist returns data as-is.
+	data2, errE := mergeConfigurationFile(filepath.Join(dir, "missing.yml"), data, resources, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, data, data2)
+}
+
+func TestMergeKeepsUnreadableSensitive(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(output, []byte(""+
+		"hooks:\n"+
+		"  - url: https://example.com/hook\n"+
+		"    push_events: true\n"+
+		"    # sops:enc\n"+
+		"    token: secret\n",
+	), 0o600))
+
+	configuration := &Configuration{
+		Hooks: []map[string]interface{}{
+			{"url": "https://example.com/hook", "push_events": false},
+		},
+	}
+	resources := []Resource{hooksResource{}}
+	data, errE := toConfigurationYAML(configuration, excludedSections(resources))
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	merged, errE := mergeConfigurationFile(output, data, resources, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, ""+
+		"hooks:\n"+
+		"  - url: https://example.com/hook\n"+
+		"    push_events: false\n"+
+		"    # sops:enc\n"+
+		"    token: secret\n",
+		string(merged),
+	)
+}
+
+func TestMergeKeepsWriteOnly(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(output, []byte(""+
+		"project_access_tokens:\n"+
+		"  - name: bot\n"+
+		"    expires_in: 30\n"+
+		"    # sops:enc\n"+
+		"    token: secret\n",
+	), 0o600))
+
+	configuration := &Configuration{
+		ProjectAccessTokens: []map[string]interface{}{
+			{"name": "bot", "expires_at": "2030-01-01"},
+		},
+	}
+	resources := []Resource{projectAccessTokensResource{}}
+	data, errE := toConfigurationYAML(configuration, excludedSections(resources))
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	merged, errE := mergeConfigurationFile(output, data, resources, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, ""+
+		"project_access_tokens:\n"+
+		"  - name: bot\n"+
+		"    expires_in: 30\n"+
+		"    # sops:enc\n"+
+		"    token: secret\n"+
+		"    expires_at: \"2030-01-01\"\n",
+		string(merged),
+	)
+}
+
+func TestMergeDeployKeyFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "keys"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "keys", "ci.pub"), []byte(testDeployKeyCI+"\n"), 0o600))
+	output := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(output, []byte(""+
+		"deploy_keys:\n"+
+		"  - title: CI\n"+
+		"    key_file: keys/ci.pub\n"+
+		"  - title: Deploy\n"+
+		"    key: "+testDeployKey+"\n",
+	), 0o600))
+
+	configuration := &Configuration{
+		DeployKeys: []map[string]interface{}{
+			{"title": "CI", "key": testDeployKeyCI, "can_push": true},
+			{"title": "Deploy", "key": testDeployKey, "can_push": false},
+		},
+	}
+	resources := []Resource{deployKeysResource{}}
+	data, errE := toConfigurationYAML(configuration, excludedSections(resources))
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	merged, errE := mergeConfigurationFile(output, data, resources, "")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, ""+
+		"deploy_keys:\n"+
+		"  - title: CI\n"+
+		"    key_file: keys/ci.pub\n"+
+		"    can_push: true\n"+
+		"  - title: Deploy\n"+
+		"    key: "+testDeployKey+"\n"+
+		"    can_push: false\n",
+		string(merged),
+	)
+}
+
+func TestMergeEncrypted(t *testing.T) {
+	t.Parallel()
+
+	parse := func(data string) *yaml.Node {
+		t.Helper()
+		node, errE := parseDocument("", []byte(data))
+		require.NoError(t, errE, "% -+#.1v", errE)
+		return node
+	}
+
+	existing := "" +
+		"variables:\n" +
+		"  - key: FOO\n" +
+		"    environment_scope: '*'\n" +
+		"    # sops:enc\n" +
+		"    value: ENC[AES256_GCM,data:abc,type:str]\n"
+	plain := "" +
+		"variables:\n" +
+		"  - key: FOO\n" +
+		"    environment_scope: '*'\n" +
+		"    value: secret\n"
+	spec := getSectionSpec(variablesResource{})
+
+	for _, tt := range []struct {
+		name      string
+		generated string
+		sensitive []string
+	}{
+		{
+			"unchanged",
+			"variables:\n  - key: FOO\n    environment_scope: '*'\n    value: secret\n",
+			[]string{},
+		},
+		{
+			"changed",
+			"variables:\n  - key: FOO\n    environment_scope: '*'\n    value: changed\n",
+			[]string{`variables[key="FOO" environment_scope="*"].value`},
+		},
+		{
+			"added",
+			"variables:\n  - key: FOO\n    environment_scope: '*'\n    value: secret\n  - key: BAR\n    environment_scope: '*'\n    value: new\n",
+			[]string{`variables[key="BAR" environment_scope="*"].value`},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := parse(existing)
+			m := merger{encrypted: true, encSuffix: "", sensitive: []string{}}
+			m.mergeSection("variables", mappingValue(e, "variables"), mappingValue(parse(plain), "variables"), mappingValue(parse(tt.generated), "variables"), spec)
+			assert.Equal(t, tt.sensitive, m.sensitive)
+			if len(tt.sensitive) == 0 {
+				data, errE := toYAML(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{e}})
+				require.NoError(t, errE, "% -+#.1v", errE)
+				assert.Equal(t, existing, string(data))
+			}
+		})
+	}
+}
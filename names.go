@@ -0,0 +1,282 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+// resolveFunc resolves a username or a full path to an ID.
+type resolveFunc func(ctx context.Context, client *gitlab.Client, name string) (int, errors.E)
+
+// nameResolver resolves usernames and full paths of groups and projects to their IDs
+// and IDs of users and groups back to usernames and full paths, caching the results.
+//
+// A nil nameResolver does not cache anything.
+type nameResolver struct {
+	mu    sync.Mutex
+	cache map[string]interface{}
+}
+
+// newNameResolver returns a new nameResolver with an empty cache.
+func newNameResolver() *nameResolver {
+	return &nameResolver{
+		mu:    sync.Mutex{},
+		cache: map[string]interface{}{},
+	}
+}
+
+// cached returns the value cached under the key. If there is none,
+// it obtains the value using fetch and caches it.
+func cached[T any](r *nameResolver, key string, fetch func() (T, errors.E)) (T, errors.E) {
+	if r == nil {
+		return fetch()
+	}
+
+	r.mu.Lock()
+	value, ok := r.cache[key].(T)
+	r.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+
+	value, errE := fetch()
+	if errE != nil {
+		return value, errE
+	}
+
+	r.mu.Lock()
+	r.cache[key] = value
+	r.mu.Unlock()
+
+	return value, nil
+}
+
+// userID resolves the username to the user's ID using GitLab users API endpoint.
+func (r *nameResolver) userID(ctx context.Context, client *gitlab.Client, username string) (int, errors.E) {
+	return cached(r, "user-name:"+username, func() (int, errors.E) {
+		users, _, err := client.Users.ListUsers(&gitlab.ListUsersOptions{ //nolint:exhaustruct
+			Username: &username,
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get user")
+			errors.Details(errE)["user"] = username
+			return 0, errE
+		}
+		for _, user := range users {
+			if strings.EqualFold(user.Username, username) {
+				return user.ID, nil
+			}
+		}
+		errE := errors.New("user not found")
+		errors.Details(errE)["user"] = username
+		return 0, errE
+	})
+}
+
+// groupID resolves the full path of a group to its ID using GitLab groups API endpoint.
+func (r *nameResolver) groupID(ctx context.Context, client *gitlab.Client, path string) (int, errors.E) {
+	return cached(r, "group-path:"+path, func() (int, errors.E) {
+		group, _, err := client.Groups.GetGroup(path, &gitlab.GetGroupOptions{ //nolint:exhaustruct
+			WithProjects: gitlab.Bool(false),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get group")
+			errors.Details(errE)["group"] = path
+			return 0, errE
+		}
+		return group.ID, nil
+	})
+}
+
+// projectID resolves the full path of a project to its ID using GitLab projects API endpoint.
+func (r *nameResolver) projectID(ctx context.Context, client *gitlab.Client, path string) (int, errors.E) {
+	return cached(r, "project:"+path, func() (int, errors.E) {
+		project, _, err := client.Projects.GetProject(path, nil, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project")
+			errors.Details(errE)["project"] = path
+			return 0, errE
+		}
+		return project.ID, nil
+	})
+}
+
+// username resolves the user's ID to their username using GitLab users API endpoint.
+func (r *nameResolver) username(ctx context.Context, client *gitlab.Client, id int) (string, errors.E) {
+	return cached(r, "user-id:"+strconv.Itoa(id), func() (string, errors.E) {
+		user, _, err := client.Users.GetUser(id, gitlab.GetUsersOptions{}, gitlab.WithContext(ctx)) //nolint:exhaustruct
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get user")
+			errors.Details(errE)["user"] = id
+			return "", errE
+		}
+		return user.Username, nil
+	})
+}
+
+// groupPath resolves the group's ID to its full path using GitLab groups API endpoint.
+func (r *nameResolver) groupPath(ctx context.Context, client *gitlab.Client, id int) (string, errors.E) {
+	return cached(r, "group-id:"+strconv.Itoa(id), func() (string, errors.E) {
+		group, _, err := client.Groups.GetGroup(id, &gitlab.GetGroupOptions{ //nolint:exhaustruct
+			WithProjects: gitlab.Bool(false),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get group")
+			errors.Details(errE)["group"] = id
+			return "", errE
+		}
+		return group.FullPath, nil
+	})
+}
+
+// resolveField replaces usernames or full paths in the field "from" of the object
+// with IDs in the field "to", resolving them using resolve.
+//
+// The value of the field can be a string, or a list of strings in which case
+// IDs are added to those already listed in the field "to".
+func resolveField(ctx context.Context, client *gitlab.Client, object map[string]interface{}, from, to string, resolve resolveFunc) errors.E {
+	value, ok := object[from]
+	if !ok {
+		return nil
+	}
+	delete(object, from)
+
+	switch v := value.(type) {
+	case nil:
+	case string:
+		id, errE := resolve(ctx, client, v)
+		if errE != nil {
+			return errE
+		}
+		object[to] = id
+	case []interface{}:
+		ids, _ := object[to].([]interface{})
+		ids = append([]interface{}{}, ids...)
+		for i, name := range v {
+			n, ok := name.(string)
+			if !ok {
+				errE := errors.Errorf(`field "%s" has a value which is not a string`, from)
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", name)
+				errors.Details(errE)["value"] = name
+				return errE
+			}
+			id, errE := resolve(ctx, client, n)
+			if errE != nil {
+				return errE
+			}
+			if !slices.Contains(ids, interface{}(id)) {
+				ids = append(ids, id)
+			}
+		}
+		object[to] = ids
+	default:
+		errE := errors.Errorf(`field "%s" is not a string or a list of strings`, from)
+		errors.Details(errE)["type"] = fmt.Sprintf("%T", value)
+		errors.Details(errE)["value"] = value
+		return errE
+	}
+
+	return nil
+}
+
+// resolveAccessLevels replaces usernames in "user" fields and full paths of groups
+// in "group" fields of access levels listed in the field of the protected branch
+// or tag with IDs in "user_id" and "group_id" fields, and names of access levels
+// in "access_level" fields with their values.
+func (t *Target) resolveAccessLevels(ctx context.Context, client *gitlab.Client, protected map[string]interface{}, field string) errors.E {
+	levels, ok := protected[field].([]interface{})
+	if !ok {
+		// Invalid access levels are reported when updating.
+		return nil
+	}
+
+	for i, level := range levels {
+		l, ok := level.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		errE := accessLevelFromName(l, "access_level")
+		if errE != nil {
+			errors.Details(errE)["accessLevels"] = field
+			errors.Details(errE)["levelIndex"] = i
+			return errE
+		}
+		for _, ii := range []struct {
+			From    string
+			To      string
+			Resolve resolveFunc
+		}{
+			{"user", "user_id", t.names.userID},
+			{"group", "group_id", t.names.groupID},
+		} {
+			errE := resolveField(ctx, client, l, ii.From, ii.To, ii.Resolve)
+			if errE != nil {
+				errors.Details(errE)["accessLevels"] = field
+				errors.Details(errE)["levelIndex"] = i
+				return errE
+			}
+		}
+	}
+
+	return nil
+}
+
+// nameAccessLevels replaces "user_id" and "group_id" fields of access levels listed
+// in the field of the protected branch or tag with usernames in "user" fields and
+// full paths of groups in "group" fields. Fields without an ID are removed.
+func (g *Getter) nameAccessLevels(ctx context.Context, client *gitlab.Client, protected map[string]interface{}, field string) errors.E {
+	levels, ok := protected[field].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	for i, level := range levels {
+		l, ok := level.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, ii := range []struct {
+			From string
+			To   string
+			Name func(ctx context.Context, client *gitlab.Client, id int) (string, errors.E)
+		}{
+			{"user_id", "user", g.names.username},
+			{"group_id", "group", g.names.groupPath},
+		} {
+			id, ok := l[ii.From]
+			if !ok {
+				continue
+			}
+			delete(l, ii.From)
+			if id == nil {
+				continue
+			}
+			iid, ok := id.(int)
+			if !ok {
+				errE := errors.Errorf(`access level's field "%s" is not an integer`, ii.From)
+				errors.Details(errE)["accessLevels"] = field
+				errors.Details(errE)["levelIndex"] = i
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", id)
+				errors.Details(errE)["value"] = id
+				return errE
+			}
+			name, errE := ii.Name(ctx, client, iid)
+			if errE != nil {
+				errors.Details(errE)["accessLevels"] = field
+				errors.Details(errE)["levelIndex"] = i
+				return errE
+			}
+			l[ii.To] = name
+		}
+	}
+
+	return nil
+}
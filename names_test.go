@@ -0,0 +1,188 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+)
+
+func newNamesTestClient(t *testing.T) (*gitlab.Client, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := r.Method + " " + r.URL.EscapedPath()
+		if r.URL.RawQuery != "" {
+			request += "?" + r.URL.RawQuery
+		}
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch request {
+		case "GET /api/v4/users?username=alice":
+			_, _ = w.Write([]byte(`[{"id":5,"username":"alice"}]`))
+		case "GET /api/v4/users?username=nobody":
+			_, _ = w.Write([]byte(`[]`))
+		case "GET /api/v4/users?username=5":
+			_, _ = w.Write([]byte(`[{"id":9,"username":"5"}]`))
+		case "GET /api/v4/users/5":
+			_, _ = w.Write([]byte(`{"id":5,"username":"alice"}`))
+		case "GET /api/v4/groups/acme%2Fdevs?with_projects=false", "GET /api/v4/groups/7?with_projects=false":
+			_, _ = w.Write([]byte(`{"id":7,"full_path":"acme/devs"}`))
+		case "GET /api/v4/projects/acme%2Fupstream":
+			_, _ = w.Write([]byte(`{"id":42,"path_with_namespace":"acme/upstream"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := gitlab.NewClient("", gitlab.WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, requests...)
+	}
+}
+
+func TestResolveApprovalRules(t *testing.T) {
+	t.Parallel()
+
+	client, requests := newNamesTestClient(t)
+	target := &Target{names: newNameResolver()} //nolint:exhaustruct
+
+	configuration := &Configuration{
+		ApprovalRules: []map[string]interface{}{
+			{"name": "Reviewers", "users": []interface{}{"alice"}, "user_ids": []interface{}{3, 5}, "groups": []interface{}{"acme/devs"}},
+			{"name": "Others", "users": []interface{}{"alice"}},
+		},
+	}
+
+	resolved, errE := target.resolveApprovalRules(t.Context(), client, configuration)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "Reviewers", "user_ids": []interface{}{3, 5}, "group_ids": []interface{}{7}},
+		{"name": "Others", "user_ids": []interface{}{5}},
+	}, resolved.ApprovalRules)
+
+	// The configuration itself is not modified.
+	assert.Equal(t, []interface{}{"alice"}, configuration.ApprovalRules[0]["users"])
+
+	// Resolved names are cached.
+	assert.Equal(t, []string{
+		"GET /api/v4/users?username=alice",
+		"GET /api/v4/groups/acme%2Fdevs?with_projects=false",
+	}, requests())
+
+	configuration.ApprovalRules[1]["users"] = []interface{}{"nobody"}
+	_, errE = target.resolveApprovalRules(t.Context(), client, configuration)
+	assert.EqualError(t, errE, "user not found")
+
+	configuration.ApprovalRules[1]["users"] = []interface{}{5}
+	_, errE = target.resolveApprovalRules(t.Context(), client, configuration)
+	assert.EqualError(t, errE, `field "users" has a value which is not a string`)
+}
+
+func TestResolveProtectedBranches(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newNamesTestClient(t)
+	target := &Target{names: newNameResolver()} //nolint:exhaustruct
+
+	configuration := &Configuration{
+		ProtectedBranches: []map[string]interface{}{
+			{
+				"name": "main",
+				"allowed_to_push": []interface{}{
+					map[string]interface{}{"access_level": "maintainer"},
+					map[string]interface{}{"user": "alice"},
+					map[string]interface{}{"group": "acme/devs"},
+				},
+			},
+		},
+	}
+
+	resolved, errE := target.resolveProtectedBranches(t.Context(), client, configuration)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"access_level": 40},
+		map[string]interface{}{"user_id": 5},
+		map[string]interface{}{"group_id": 7},
+	}, resolved.ProtectedBranches[0]["allowed_to_push"])
+}
+
+func TestResolveForkedFromProject(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newNamesTestClient(t)
+	target := &Target{names: newNameResolver()} //nolint:exhaustruct
+
+	configuration := &Configuration{ForkedFromProject: "acme/upstream"}
+	resolved, errE := target.resolveForkedFromProject(t.Context(), client, configuration)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, 42, resolved.ForkedFromProject)
+	assert.Equal(t, "acme/upstream", configuration.ForkedFromProject)
+
+	configuration = &Configuration{ForkedFromProject: 0}
+	resolved, errE = target.resolveForkedFromProject(t.Context(), client, configuration)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Same(t, configuration, resolved)
+}
+
+func TestNameAccessLevels(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newNamesTestClient(t)
+	g := &Getter{} //nolint:exhaustruct
+
+	protectedBranch := map[string]interface{}{
+		"name": "main",
+		"allowed_to_merge": []interface{}{
+			map[string]interface{}{"access_level": 40, "user_id": nil, "group_id": nil},
+			map[string]interface{}{"access_level": nil, "user_id": 5, "group_id": nil},
+			map[string]interface{}{"access_level": nil, "user_id": nil, "group_id": 7},
+		},
+	}
+
+	errE := g.nameAccessLevels(t.Context(), client, protectedBranch, "allowed_to_merge")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"access_level": 40},
+		map[string]interface{}{"access_level": nil, "user": "alice"},
+		map[string]interface{}{"access_level": nil, "group": "acme/devs"},
+	}, protectedBranch["allowed_to_merge"])
+}
+
+func TestNameResolverCache(t *testing.T) {
+	t.Parallel()
+
+	client, requests := newNamesTestClient(t)
+	r := newNameResolver()
+
+	// A numeric username does not collide with a cached user ID.
+	for range 2 {
+		username, errE := r.username(t.Context(), client, 5)
+		require.NoError(t, errE, "% -+#.1v", errE)
+		assert.Equal(t, "alice", username)
+
+		id, errE := r.userID(t.Context(), client, "5")
+		require.NoError(t, errE, "% -+#.1v", errE)
+		assert.Equal(t, 9, id)
+	}
+
+	assert.Equal(t, []string{
+		"GET /api/v4/users/5",
+		"GET /api/v4/users?username=5",
+	}, requests())
+}
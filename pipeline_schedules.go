@@ -1,9 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"slices"
 	"sort"
 
@@ -12,35 +12,82 @@ import (
 	"gitlab.com/tozd/go/errors"
 )
 
+// pipelineSchedulesResource is a Resource for pipeline schedules.
+type pipelineSchedulesResource struct{}
+
+// Name implements Resource interface.
+func (pipelineSchedulesResource) Name() string {
+	return "pipeline_schedules"
+}
+
+// Descriptions implements Resource interface.
+func (pipelineSchedulesResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getPipelineSchedulesDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (pipelineSchedulesResource) Sensitive() []string {
+	// For now pipeline schedule variables cannot contain secrets as they cannot be masked.
+	// See: https://gitlab.com/gitlab-org/gitlab/-/issues/35439
+	return nil
+}
+
+// Keys implements KeyedResource interface.
+func (pipelineSchedulesResource) Keys() [][]string {
+	return [][]string{{"id"}}
+}
+
+// Describe implements KeyedResource interface.
+func (pipelineSchedulesResource) Describe() []string {
+	return []string{"id", "description"}
+}
+
+// Required implements RequiredResource interface.
+func (pipelineSchedulesResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getPipelineSchedulesRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (pipelineSchedulesResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getPipelineSchedules(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (pipelineSchedulesResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updatePipelineSchedules(ctx, client, configuration)
+}
+
 // getPipelineSchedules populates configuration struct with configuration available
 // from GitLab pipeline schedules API endpoint.
-func (c *GetCommand) getPipelineSchedules(client *gitlab.Client, configuration *Configuration) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting pipeline schedules...\n")
+func (g *Getter) getPipelineSchedules(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting pipeline schedules...\n")
 
 	configuration.PipelineSchedules = []map[string]interface{}{}
 
-	descriptions, errE := getPipelineSchedulesDescriptions(c.DocsRef)
+	descriptions, errE := getPipelineSchedulesDescriptions(ctx, g.Docs)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 	// We need "id" later on.
 	if _, ok := descriptions["id"]; !ok {
-		return false, errors.New(`"id" field is missing in pipeline schedules descriptions`)
+		return errors.New(`"id" field is missing in pipeline schedules descriptions`)
 	}
 	configuration.PipelineSchedulesComment = formatDescriptions(descriptions)
 
-	u := fmt.Sprintf("projects/%s/pipeline_schedules", gitlab.PathEscape(c.Project))
+	u := fmt.Sprintf("projects/%s/pipeline_schedules", gitlab.PathEscape(g.Project))
 	options := &gitlab.ListPipelineSchedulesOptions{
 		PerPage: maxGitLabPageSize,
 		Page:    1,
 	}
 
+	ids := []int{}
+
 	for {
-		req, err := client.NewRequest(http.MethodGet, u, options, nil)
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get pipeline schedules")
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		pipelineSchedules := []map[string]interface{}{}
@@ -49,7 +96,7 @@ func (c *GetCommand) getPipelineSchedules(client *gitlab.Client, configuration *
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get pipeline schedules")
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		if len(pipelineSchedules) == 0 {
@@ -62,72 +109,83 @@ func (c *GetCommand) getPipelineSchedules(client *gitlab.Client, configuration *
 
 			id, ok := pipelineSchedule["id"]
 			if !ok {
-				return false, errors.New(`pipeline schedule is missing field "id"`)
+				return errors.New(`pipeline schedule is missing field "id"`)
 			}
 			iid, ok := id.(int)
 			if !ok {
 				errE := errors.New(`pipeline schedule's field "id" is not an integer`)
 				errors.Details(errE)["type"] = fmt.Sprintf("%T", id)
 				errors.Details(errE)["value"] = id
-				return false, errE
-			}
-
-			// We have to fetch each pipeline schedule individually to get variables.
-			req, err := client.NewRequest(http.MethodGet, fmt.Sprintf("projects/%s/pipeline_schedules/%d", gitlab.PathEscape(c.Project), iid), nil, nil)
-			if err != nil {
-				errE := errors.WithMessage(err, "failed to get pipeline schedule")
-				errors.Details(errE)["id"] = iid
-				return false, errE
+				return errE
 			}
 
-			ps := map[string]interface{}{}
+			ids = append(ids, iid)
+		}
 
-			_, err = client.Do(req, &ps)
-			if err != nil {
-				errE := errors.WithMessage(err, "failed to get pipeline schedule")
-				errors.Details(errE)["id"] = iid
-				return false, errE
-			}
+		if response.NextPage == 0 {
+			break
+		}
 
-			// Making sure ids are an integer.
-			castFloatsToInts(ps)
+		options.Page = response.NextPage
+	}
 
-			// We already extracted ID, so we just set it to not have to validate it again.
-			ps["id"] = iid
+	// We have to fetch each pipeline schedule individually to get variables.
+	// We do so concurrently, storing each pipeline schedule at its index.
+	pipelineSchedules := make([]map[string]interface{}, len(ids))
+	errE = parallel(g.Workers, len(ids), func(i int) errors.E {
+		iid := ids[i]
 
-			// Only retain those keys which can be edited through the API
-			// (which are those available in descriptions).
-			for key := range ps {
-				_, ok := descriptions[key]
-				if !ok {
-					delete(ps, key)
-				}
-			}
+		req, err := client.NewRequest(http.MethodGet, fmt.Sprintf("projects/%s/pipeline_schedules/%d", gitlab.PathEscape(g.Project), iid), nil, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get pipeline schedule")
+			errors.Details(errE)["id"] = iid
+			return errE
+		}
 
-			// TODO: This field is returned, but it cannot be changed. It is not documented.
-			//       See: https://gitlab.com/gitlab-org/gitlab/-/issues/427328
-			removeField(ps, "raw")
+		ps := map[string]interface{}{}
 
-			configuration.PipelineSchedules = append(configuration.PipelineSchedules, ps)
+		_, err = client.Do(req, &ps)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get pipeline schedule")
+			errors.Details(errE)["id"] = iid
+			return errE
 		}
 
-		if response.NextPage == 0 {
-			break
+		// Making sure ids are an integer.
+		castFloatsToInts(ps)
+
+		// We already extracted ID, so we just set it to not have to validate it again.
+		ps["id"] = iid
+
+		// Only retain those keys which can be edited through the API
+		// (which are those available in descriptions).
+		for key := range ps {
+			_, ok := descriptions[key]
+			if !ok {
+				delete(ps, key)
+			}
 		}
 
-		options.Page = response.NextPage
+		// TODO: This field is returned, but it cannot be changed. It is not documented.
+		//       See: https://gitlab.com/gitlab-org/gitlab/-/issues/427328
+		removeField(ps, "raw")
+
+		pipelineSchedules[i] = ps
+		return nil
+	})
+	if errE != nil {
+		return errE
 	}
 
+	configuration.PipelineSchedules = pipelineSchedules
+
 	// We sort by pipeline schedule's id so that we have deterministic order.
 	sort.Slice(configuration.PipelineSchedules, func(i, j int) bool {
 		// We checked that id is int above.
 		return configuration.PipelineSchedules[i]["id"].(int) < configuration.PipelineSchedules[j]["id"].(int) //nolint:forcetypeassert,errcheck
 	})
 
-	// For now pipeline schedule variables cannot contain secrets as they cannot be masked,
-	// so we return false here.
-	// See: https://gitlab.com/gitlab-org/gitlab/-/issues/35439
-	return false, nil
+	return nil
 }
 
 // parsePipelineSchedulesDocumentation parses GitLab's documentation in Markdown for
@@ -146,22 +204,32 @@ func parsePipelineSchedulesDocumentation(input []byte) (map[string]string, error
 
 // getPipelineSchedulesDescriptions obtains description of fields used to describe
 // an individual pipeline schedules from GitLab's documentation for pipeline schedules API endpoint.
-func getPipelineSchedulesDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/pipeline_schedules.md", gitRef))
+func getPipelineSchedulesDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "pipeline_schedules.md")
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get pipeline schedules descriptions")
 	}
 	return parsePipelineSchedulesDocumentation(data)
 }
 
+// getPipelineSchedulesRequired obtains fields required to create an individual pipeline schedule
+// from GitLab's documentation for pipeline schedules API endpoint.
+func getPipelineSchedulesRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "pipeline_schedules.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get pipeline schedules required fields")
+	}
+	return parseRequired(data, "Create a new pipeline schedule", nil)
+}
+
 // updatePipelineSchedules updates GitLab project's pipeline schedules using GitLab
 // pipeline schedules API endpoint based on the configuration struct.
-func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuration *Configuration) errors.E { //nolint:maintidx
+func (s *Setter) updatePipelineSchedules(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E { //nolint:maintidx
 	if configuration.PipelineSchedules == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating pipeline schedules...\n")
+	s.Printf("Updating pipeline schedules...\n")
 
 	options := &gitlab.ListPipelineSchedulesOptions{
 		PerPage: maxGitLabPageSize,
@@ -171,7 +239,7 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 	pipelineSchedules := []*gitlab.PipelineSchedule{}
 
 	for {
-		ps, response, err := client.PipelineSchedules.ListPipelineSchedules(c.Project, options)
+		ps, response, err := client.PipelineSchedules.ListPipelineSchedules(s.Project, options, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get pipeline schedules")
 			errors.Details(errE)["page"] = options.Page
@@ -189,6 +257,10 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 
 	existingPipelineSchedulesSet := mapset.NewThreadUnsafeSet[int]()
 	for _, pipelineSchedule := range pipelineSchedules {
+		// Pipeline schedules matching ignore rules are neither updated nor deleted.
+		if configuration.ignored("pipeline_schedules", map[string]interface{}{"id": pipelineSchedule.ID, "description": pipelineSchedule.Description}) {
+			continue
+		}
 		existingPipelineSchedulesSet.Add(pipelineSchedule.ID)
 	}
 
@@ -216,9 +288,13 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 	}
 
 	extraPipelineSchedules := existingPipelineSchedulesSet.Difference(wantedPipelineSchedulesSet).ToSlice()
+	if !configuration.prune("pipeline_schedules", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraPipelineSchedules = nil
+	}
 	slices.Sort(extraPipelineSchedules)
 	for _, pipelineScheduleID := range extraPipelineSchedules {
-		_, err := client.PipelineSchedules.DeletePipelineSchedule(c.Project, pipelineScheduleID)
+		_, err := client.PipelineSchedules.DeletePipelineSchedule(s.Project, pipelineScheduleID, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to delete pipeline schedule")
 			errors.Details(errE)["pipelineSchedule"] = pipelineScheduleID
@@ -231,8 +307,8 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 
 		id, ok := pipelineSchedule["id"]
 		if !ok {
-			u := fmt.Sprintf("projects/%s/pipeline_schedules", gitlab.PathEscape(c.Project))
-			req, err := client.NewRequest(http.MethodPost, u, pipelineSchedule, nil)
+			u := fmt.Sprintf("projects/%s/pipeline_schedules", gitlab.PathEscape(s.Project))
+			req, err := client.NewRequest(http.MethodPost, u, pipelineSchedule, contextOptions(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to create pipeline schedule")
 				errors.Details(errE)["index"] = i
@@ -250,7 +326,7 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 			// ID exist and that they are ints.
 			iid := id.(int) //nolint:errcheck,forcetypeassert
 
-			_, _, err := client.PipelineSchedules.TakeOwnershipOfPipelineSchedule(c.Project, iid)
+			_, _, err := client.PipelineSchedules.TakeOwnershipOfPipelineSchedule(s.Project, iid, gitlab.WithContext(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to take ownership of pipeline schedule")
 				errors.Details(errE)["index"] = i
@@ -258,8 +334,8 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 				return errE
 			}
 
-			u := fmt.Sprintf("projects/%s/pipeline_schedules/%d", gitlab.PathEscape(c.Project), iid)
-			req, err := client.NewRequest(http.MethodPut, u, pipelineSchedule, nil)
+			u := fmt.Sprintf("projects/%s/pipeline_schedules/%d", gitlab.PathEscape(s.Project), iid)
+			req, err := client.NewRequest(http.MethodPut, u, pipelineSchedule, contextOptions(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to update pipeline schedule")
 				errors.Details(errE)["index"] = i
@@ -274,7 +350,7 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 				return errE
 			}
 
-			ps, _, err = client.PipelineSchedules.GetPipelineSchedule(c.Project, iid)
+			ps, _, err = client.PipelineSchedules.GetPipelineSchedule(s.Project, iid, gitlab.WithContext(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to get pipeline schedule")
 				errors.Details(errE)["index"] = i
@@ -336,9 +412,10 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 		slices.Sort(extraVariables)
 		for _, variable := range extraVariables {
 			_, _, err := client.PipelineSchedules.DeletePipelineScheduleVariable(
-				c.Project,
+				s.Project,
 				ps.ID,
 				variable,
+				gitlab.WithContext(ctx),
 			)
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to remove variable for pipeline schedule")
@@ -356,8 +433,8 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 
 			if existingVariablesSet.Contains(key) {
 				// Update existing variable.
-				u := fmt.Sprintf("projects/%s/pipeline_schedules/%d/variables/%s", gitlab.PathEscape(c.Project), ps.ID, gitlab.PathEscape(key))
-				req, err := client.NewRequest(http.MethodPut, u, variable, nil)
+				u := fmt.Sprintf("projects/%s/pipeline_schedules/%d/variables/%s", gitlab.PathEscape(s.Project), ps.ID, gitlab.PathEscape(key))
+				req, err := client.NewRequest(http.MethodPut, u, variable, contextOptions(ctx))
 				if err != nil {
 					errE := errors.WithMessage(err, "failed to update variable for pipeline schedule")
 					errors.Details(errE)["index"] = i
@@ -377,8 +454,8 @@ func (c *SetCommand) updatePipelineSchedules(client *gitlab.Client, configuratio
 				}
 			} else {
 				// Create new variable.
-				u := fmt.Sprintf("projects/%s/pipeline_schedules/%d/variables", gitlab.PathEscape(c.Project), ps.ID)
-				req, err := client.NewRequest(http.MethodPost, u, variable, nil)
+				u := fmt.Sprintf("projects/%s/pipeline_schedules/%d/variables", gitlab.PathEscape(s.Project), ps.ID)
+				req, err := client.NewRequest(http.MethodPost, u, variable, contextOptions(ctx))
 				if err != nil {
 					errE := errors.WithMessage(err, "failed to create variable for pipeline schedule")
 					errors.Details(errE)["index"] = i
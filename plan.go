@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/tozd/go/errors"
+	"gitlab.com/tozd/go/x"
+)
+
+// We do not use type=path for Input because we want a relative path.
+
+// PlanCommand describes parameters for the plan command.
+//
+//nolint:lll
+type PlanCommand struct {
+	GitLab
+	Sections
+
+	Input     string `default:".gitlab-conf.yml" help:"Where to load the configuration from. Can be \"-\" for stdin. Default is \"${default}\"."            placeholder:"PATH" short:"i"`
+	EncSuffix string `                           help:"Remove the suffix from field names before comparing. Disabled by default."                                              short:"S"`
+	NoDecrypt bool   `                           help:"Do not attempt to decrypt the configuration."`
+	NoPrune   bool   `                           help:"Compare as if objects which exist in GitLab but are missing from the configuration are not deleted."`
+	Workers   int    `default:"4"                help:"Maximum number of concurrent requests to GitLab API. Default is ${default}."                         placeholder:"INT"`
+}
+
+// Run runs the plan command.
+func (c *PlanCommand) Run(_ *Globals) errors.E {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	changes, errE := c.plan(ctx)
+	if errE != nil {
+		return errE
+	}
+
+	fmt.Fprintf(os.Stderr, "Compared everything.\n")
+
+	return writeChanges(os.Stdout, changes)
+}
+
+// plan returns changes set command would make.
+func (c *PlanCommand) plan(ctx context.Context) ([]resourceChange, errors.E) {
+	if c.Workers < 1 {
+		errE := errors.New("invalid number of workers")
+		errors.Details(errE)["workers"] = c.Workers
+		return nil, errE
+	}
+
+	if c.Project == "" && c.Group == "" {
+		projectID, errE := x.InferGitLabProjectID(".")
+		if errE != nil {
+			return nil, errE
+		}
+		c.Project = projectID
+	}
+
+	resources, errE := c.resources(c.registeredResources())
+	if errE != nil {
+		return nil, errE
+	}
+
+	configuration, errE := readConfiguration(c.Input, c.NoDecrypt, c.EncSuffix)
+	if errE != nil {
+		return nil, errE
+	}
+
+	errE = c.setup(c.Workers, nil)
+	if errE != nil {
+		return nil, errE
+	}
+
+	// Current avatar is stored into a temporary directory
+	// so that we can compare it with the configured one.
+	tempDir, err := os.MkdirTemp("", "gitlab-config-")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+
+	opts := c.options(filepath.Join(tempDir, "avatar.img"), configuration.Ignore)
+
+	live, errE := Fetch(ctx, c.client, c.owner(), opts)
+	if errE != nil {
+		return nil, errE
+	}
+
+	target := opts.target(c.owner())
+	for _, resource := range resources {
+		configuration, errE = resolve(target.withResource(ctx, resource.Name()), c.client, &target, resource, configuration)
+		if errE != nil {
+			return nil, errE
+		}
+	}
+
+	return diffConfiguration(resources, live, configuration, c.NoPrune)
+}
+
+// options returns Options for Fetch with the avatar path and ignore rules.
+func (c *PlanCommand) options(avatar string, ignore map[string][]map[string]string) Options {
+	return Options{
+		Group: c.Group != "",
+		Only:  c.Only,
+		Skip:  c.Skip,
+		Docs:  c.docs(),
+		// Wanted configuration is compared after resolving names to IDs.
+		IDs:              true,
+		AccessLevelNames: false,
+		Avatar:           avatar,
+		Workers:          c.Workers,
+		Snapshot:         "",
+		Rollback:         false,
+		NoPrune:          c.NoPrune,
+		RotateBefore:     0,
+		TokensOutput:     "",
+		Input:            "",
+		EncSuffix:        "",
+		Ignore:           ignore,
+		Comments:         false,
+		Logger:           c.logger,
+	}
+}
@@ -1,60 +1,74 @@
 package config
 
 import (
-	"fmt"
+	"context"
 	"net/http"
-	"os"
 
 	"github.com/xanzy/go-gitlab"
 	"gitlab.com/tozd/go/errors"
 )
 
-// getProject populates configuration struct with configuration available
-// from GitLab projects API endpoint.
-func (c *GetCommand) getProject(client *gitlab.Client, configuration *Configuration) (bool, errors.E) {
-	fmt.Fprintf(os.Stderr, "Getting project...\n")
+// projectResource is a Resource for project settings.
+type projectResource struct{}
 
-	descriptions, errE := getProjectDescriptions(c.DocsRef)
-	if errE != nil {
-		return false, errE
-	}
+// Name implements Resource interface.
+func (projectResource) Name() string {
+	return "project"
+}
+
+// Descriptions implements Resource interface.
+func (projectResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getProjectDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (projectResource) Sensitive() []string {
+	return nil
+}
 
-	u := "projects/" + gitlab.PathEscape(c.Project)
+// Get implements Resource interface.
+func (projectResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getProject(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (projectResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateProject(ctx, client, configuration)
+}
 
-	req, err := client.NewRequest(http.MethodGet, u, nil, nil)
+// getProject fetches the project from GitLab projects API endpoint.
+func getProject(ctx context.Context, client *gitlab.Client, project string) (map[string]interface{}, errors.E) {
+	u := "projects/" + gitlab.PathEscape(project)
+
+	req, err := client.NewRequest(http.MethodGet, u, nil, contextOptions(ctx))
 	if err != nil {
-		return false, errors.WithMessage(err, `failed to get project`)
+		return nil, errors.WithMessage(err, `failed to get project`)
 	}
 
-	project := map[string]interface{}{}
+	p := map[string]interface{}{}
 
-	_, err = client.Do(req, &project)
+	_, err = client.Do(req, &p)
 	if err != nil {
-		return false, errors.WithMessage(err, `failed to get project`)
+		return nil, errors.WithMessage(err, `failed to get project`)
 	}
 
-	hasSensitive := false
+	return p, nil
+}
 
-	// We use a separate top-level configuration for avatar instead.
-	s, errE := c.getAvatar(client, project, configuration)
-	if errE != nil {
-		return false, errE
-	}
-	hasSensitive = hasSensitive || s
+// getProject populates configuration struct with configuration available
+// from GitLab projects API endpoint.
+func (g *Getter) getProject(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting project...\n")
 
-	// We use a separate top-level configuration for shared with groups instead.
-	s, errE = c.getSharedWithGroups(client, project, configuration)
+	descriptions, errE := getProjectDescriptions(ctx, g.Docs)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
-	hasSensitive = hasSensitive || s
 
-	// We use a separate top-level configuration for fork relationship.
-	s, errE = c.getForkedFromProject(client, project, configuration)
+	project, errE := getProject(ctx, client, g.Project)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
-	hasSensitive = hasSensitive || s
 
 	// Only retain those keys which can be edited through the API
 	// (which are those available in descriptions). We cannot add comments
@@ -75,7 +89,7 @@ func (c *GetCommand) getProject(client *gitlab.Client, configuration *Configurat
 	if project["container_expiration_policy"] != nil {
 		policy, ok := project["container_expiration_policy"].(map[string]interface{})
 		if !ok {
-			return false, errors.New(`invalid "container_expiration_policy"`)
+			return errors.New(`invalid "container_expiration_policy"`)
 		}
 		if policy["name_regex"] != nil && policy["name_regex_delete"] == nil {
 			policy["name_regex_delete"] = policy["name_regex"]
@@ -93,7 +107,7 @@ func (c *GetCommand) getProject(client *gitlab.Client, configuration *Configurat
 
 	configuration.Project = project
 
-	return hasSensitive, nil
+	return nil
 }
 
 // parseProjectDocumentation parses GitLab's documentation in Markdown for
@@ -134,8 +148,8 @@ func parseProjectDocumentation(input []byte) (map[string]string, errors.E) {
 
 // getProjectDescriptions obtains description of fields used to describe
 // an individual project from GitLab's documentation for projects API endpoint.
-func getProjectDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/projects.md", gitRef))
+func getProjectDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "projects.md")
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get project configuration descriptions")
 	}
@@ -144,14 +158,14 @@ func getProjectDescriptions(gitRef string) (map[string]string, errors.E) {
 
 // updateProject updates GitLab project's configuration using GitLab projects API endpoint
 // based on the configuration struct.
-func (c *SetCommand) updateProject(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updateProject(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
 	if configuration.Project == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating project...\n")
+	s.Printf("Updating project...\n")
 
-	u := "projects/" + gitlab.PathEscape(c.Project)
+	u := "projects/" + gitlab.PathEscape(s.Project)
 
 	// For now we provide both keys, the new and the deprecated.
 	containerExpirationPolicy, ok := configuration.Project["container_expiration_policy"]
@@ -177,7 +191,7 @@ func (c *SetCommand) updateProject(client *gitlab.Client, configuration *Configu
 		delete(configuration.Project, "public_jobs")
 	}
 
-	req, err := client.NewRequest(http.MethodPut, u, configuration.Project, nil)
+	req, err := client.NewRequest(http.MethodPut, u, configuration.Project, contextOptions(ctx))
 	if err != nil {
 		return errors.WithMessage(err, "failed to update GitLab project")
 	}
@@ -0,0 +1,380 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+// projectAccessTokensResource is a Resource for project access tokens.
+type projectAccessTokensResource struct{}
+
+// Name implements Resource interface.
+func (projectAccessTokensResource) Name() string {
+	return "project_access_tokens"
+}
+
+// Descriptions implements Resource interface.
+func (projectAccessTokensResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getProjectAccessTokensDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (projectAccessTokensResource) Sensitive() []string {
+	return []string{tokenField}
+}
+
+// WriteOnly implements WriteOnlyResource interface.
+func (projectAccessTokensResource) WriteOnly() []string {
+	return []string{expiresInField}
+}
+
+// Keys implements KeyedResource interface.
+func (projectAccessTokensResource) Keys() [][]string {
+	return [][]string{{"name"}}
+}
+
+// Describe implements KeyedResource interface.
+func (projectAccessTokensResource) Describe() []string {
+	return nil
+}
+
+// Required implements RequiredResource interface.
+func (projectAccessTokensResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getProjectAccessTokensRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (projectAccessTokensResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getProjectAccessTokens(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (projectAccessTokensResource) Resolve(_ context.Context, _ *Target, _ *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	if configuration.ProjectAccessTokens == nil {
+		return configuration, nil
+	}
+
+	projectAccessTokens, errE := resolveTokens(configuration.ProjectAccessTokens, "access_level")
+	if errE != nil {
+		return nil, errE
+	}
+	resolved := *configuration
+	resolved.ProjectAccessTokens = projectAccessTokens
+	return &resolved, nil
+}
+
+// Update implements Resource interface.
+func (projectAccessTokensResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateProjectAccessTokens(ctx, client, configuration)
+}
+
+// getProjectAccessTokens populates configuration struct with configuration available
+// from GitLab project access tokens API endpoint.
+//
+// Only active project access tokens are returned. GitLab does not return values
+// of project access tokens, so they are not populated.
+func (g *Getter) getProjectAccessTokens(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting project access tokens...\n")
+
+	configuration.ProjectAccessTokens = []map[string]interface{}{}
+
+	descriptions, errE := getProjectAccessTokensDescriptions(ctx, g.Docs)
+	if errE != nil {
+		return errE
+	}
+	// We need "name" later on.
+	if _, ok := descriptions["name"]; !ok {
+		return errors.New(`"name" field is missing in project access tokens descriptions`)
+	}
+	configuration.ProjectAccessTokensComment = formatDescriptions(descriptions)
+
+	u := fmt.Sprintf("projects/%s/access_tokens", gitlab.PathEscape(g.Project))
+	options := &gitlab.ListProjectAccessTokensOptions{
+		PerPage: maxGitLabPageSize,
+		Page:    1,
+	}
+
+	for {
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project access tokens")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		projectAccessTokens := []map[string]interface{}{}
+
+		response, err := client.Do(req, &projectAccessTokens)
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project access tokens")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		if len(projectAccessTokens) == 0 {
+			break
+		}
+
+		for _, projectAccessToken := range projectAccessTokens {
+			// Revoked and expired tokens are not active.
+			if projectAccessToken["active"] != true {
+				continue
+			}
+
+			// Making sure access level is an integer.
+			castFloatsToInts(projectAccessToken)
+
+			// Only retain those keys which can be edited through the API
+			// (which are those available in descriptions).
+			for key := range projectAccessToken {
+				_, ok := descriptions[key]
+				if !ok {
+					delete(projectAccessToken, key)
+				}
+			}
+
+			name, ok := projectAccessToken["name"]
+			if !ok {
+				return errors.New(`project access token is missing field "name"`)
+			}
+			_, ok = name.(string)
+			if !ok {
+				errE := errors.New(`project access token's field "name" is not a string`)
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", name)
+				errors.Details(errE)["value"] = name
+				return errE
+			}
+
+			if g.AccessLevelNames {
+				accessLevelToName(projectAccessToken, "access_level")
+			}
+
+			configuration.ProjectAccessTokens = append(configuration.ProjectAccessTokens, projectAccessToken)
+		}
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	// We sort by project access token name so that we have deterministic order.
+	sort.SliceStable(configuration.ProjectAccessTokens, func(i, j int) bool {
+		// We checked that name is string above.
+		return configuration.ProjectAccessTokens[i]["name"].(string) < configuration.ProjectAccessTokens[j]["name"].(string) //nolint:forcetypeassert,errcheck
+	})
+
+	return nil
+}
+
+// parseProjectAccessTokensDocumentation parses GitLab's documentation in Markdown for
+// project access tokens API endpoint and extracts description of fields used to describe
+// an individual project access token.
+func parseProjectAccessTokensDocumentation(input []byte) (map[string]string, errors.E) {
+	return parseTable(input, "Create a project access token", nil)
+}
+
+// getProjectAccessTokensDescriptions obtains description of fields used to describe an individual
+// project access token from GitLab's documentation for project access tokens API endpoint.
+func getProjectAccessTokensDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "project_access_tokens.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get project access tokens descriptions")
+	}
+	descriptions, errE := parseProjectAccessTokensDocumentation(data)
+	if errE != nil {
+		return nil, errE
+	}
+	addTokenDescriptions(descriptions)
+	allowAccessLevelNames(descriptions, "access_level")
+	return descriptions, nil
+}
+
+// getProjectAccessTokensRequired obtains fields required to create an individual project access token
+// from GitLab's documentation for project access tokens API endpoint.
+func getProjectAccessTokensRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "project_access_tokens.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get project access tokens required fields")
+	}
+	required, errE := parseRequired(data, "Create a project access token", nil)
+	if errE != nil {
+		return nil, errE
+	}
+	// The expiration date can be provided with the "expires_in" field instead.
+	return slices.DeleteFunc(required, func(field string) bool { return field == "expires_at" }), nil
+}
+
+// updateProjectAccessTokens updates GitLab project's access tokens using GitLab project
+// access tokens API endpoint based on the configuration struct.
+//
+// Project access tokens are matched to existing active project access tokens based
+// on the name. Project access tokens cannot be changed, so when their fields do not
+// match, new project access tokens are created and existing ones revoked. When they
+// are about to expire, they are rotated. Values of new project access tokens are saved.
+func (s *Setter) updateProjectAccessTokens(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	if configuration.ProjectAccessTokens == nil {
+		return nil
+	}
+
+	s.Printf("Updating project access tokens...\n")
+
+	options := &gitlab.ListProjectAccessTokensOptions{
+		PerPage: maxGitLabPageSize,
+		Page:    1,
+	}
+
+	projectAccessTokens := []*gitlab.ProjectAccessToken{}
+
+	for {
+		ts, response, err := client.ProjectAccessTokens.ListProjectAccessTokens(s.Project, options, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to get project access tokens")
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		projectAccessTokens = append(projectAccessTokens, ts...)
+
+		if response.NextPage == 0 {
+			break
+		}
+
+		options.Page = response.NextPage
+	}
+
+	existingProjectAccessTokensSet := mapset.NewThreadUnsafeSet[int]()
+	namesToProjectAccessTokens := map[string]*existingToken{}
+	for _, projectAccessToken := range projectAccessTokens {
+		if !projectAccessToken.Active {
+			continue
+		}
+		// Project access tokens matching ignore rules are neither updated nor revoked.
+		if configuration.ignored("project_access_tokens", map[string]interface{}{"name": projectAccessToken.Name}) {
+			continue
+		}
+		existingProjectAccessTokensSet.Add(projectAccessToken.ID)
+		// If there are multiple project access tokens with the same name, the first one is matched.
+		if _, ok := namesToProjectAccessTokens[projectAccessToken.Name]; !ok {
+			var expiresAt *time.Time
+			if projectAccessToken.ExpiresAt != nil {
+				t := time.Time(*projectAccessToken.ExpiresAt)
+				expiresAt = &t
+			}
+			namesToProjectAccessTokens[projectAccessToken.Name] = &existingToken{
+				ID:        projectAccessToken.ID,
+				Scopes:    projectAccessToken.Scopes,
+				ExpiresAt: expiresAt,
+				Fields:    map[string]interface{}{"access_level": int(projectAccessToken.AccessLevel)},
+			}
+		}
+	}
+
+	now := time.Now()
+
+	changes := make([]tokenChange, len(configuration.ProjectAccessTokens))
+	names := make([]string, len(configuration.ProjectAccessTokens))
+	wantedProjectAccessTokensSet := mapset.NewThreadUnsafeSet[int]()
+	newProjectAccessTokens := []string{}
+	for i, projectAccessToken := range configuration.ProjectAccessTokens {
+		name, errE := tokenName(projectAccessToken, i)
+		if errE != nil {
+			return errE
+		}
+		names[i] = name
+		existing := namesToProjectAccessTokens[name]
+		if existing != nil {
+			wantedProjectAccessTokensSet.Add(existing.ID)
+		}
+		changes[i], errE = s.tokenChange(existing, projectAccessToken, now)
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+		if changes[i] != tokenKeep {
+			newProjectAccessTokens = append(newProjectAccessTokens, name)
+		}
+	}
+
+	// We check this before making any changes because values are revealed only once.
+	errE := s.checkTokensOutput("project_access_tokens", newProjectAccessTokens)
+	if errE != nil {
+		return errE
+	}
+
+	extraProjectAccessTokens := existingProjectAccessTokensSet.Difference(wantedProjectAccessTokensSet).ToSlice()
+	if !configuration.prune("project_access_tokens", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraProjectAccessTokens = nil
+	}
+	slices.Sort(extraProjectAccessTokens)
+	for _, projectAccessTokenID := range extraProjectAccessTokens {
+		_, err := client.ProjectAccessTokens.RevokeProjectAccessToken(s.Project, projectAccessTokenID, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to revoke project access token")
+			errors.Details(errE)["projectAccessToken"] = projectAccessTokenID
+			return errE
+		}
+	}
+
+	for i, projectAccessToken := range configuration.ProjectAccessTokens {
+		if changes[i] == tokenKeep {
+			continue
+		}
+
+		message := "failed to create project access token"
+		u := fmt.Sprintf("projects/%s/access_tokens", gitlab.PathEscape(s.Project))
+		fields := newTokenFields(projectAccessToken, now)
+		if changes[i] == tokenRotate {
+			// Rotating revokes the existing project access token.
+			message = "failed to rotate project access token"
+			u = fmt.Sprintf("projects/%s/access_tokens/%d/rotate", gitlab.PathEscape(s.Project), namesToProjectAccessTokens[names[i]].ID)
+			fields = map[string]interface{}{"expires_at": tokenExpiresAt(projectAccessToken, now)}
+		}
+		req, err := client.NewRequest(http.MethodPost, u, fields, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, message)
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["projectAccessToken"] = names[i]
+			return errE
+		}
+		created := new(gitlab.ProjectAccessToken)
+		_, err = client.Do(req, created)
+		if err != nil {
+			errE := errors.WithMessage(err, message)
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["projectAccessToken"] = names[i]
+			return errE
+		}
+		errE := s.saveToken("project_access_tokens", names[i], created.Token)
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+
+		if changes[i] != tokenReplace {
+			continue
+		}
+
+		// The existing project access token is revoked only after the new one has been saved.
+		id := namesToProjectAccessTokens[names[i]].ID
+		_, err = client.ProjectAccessTokens.RevokeProjectAccessToken(s.Project, id, gitlab.WithContext(ctx))
+		if err != nil {
+			errE := errors.WithMessage(err, "failed to revoke project access token")
+			errors.Details(errE)["index"] = i
+			errors.Details(errE)["projectAccessToken"] = id
+			return errE
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Project access tokens file is from: https://gitlab.com/gitlab-org/gitlab/-/raw/master/doc/api/project_access_tokens.md
+//
+//go:embed testdata/project_access_tokens.md
+var testProjectAccessTokens []byte
+
+func TestParseProjectAccessTokensDocumentation(t *testing.T) {
+	t.Parallel()
+
+	data, errE := parseProjectAccessTokensDocumentation(testProjectAccessTokens)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, map[string]string{
+		"access_level": "Access level. Valid values are 10 (Guest), 20 (Reporter), 30 (Developer), 40 (Maintainer), and 50 (Owner). Defaults to 40. Type: Integer",
+		"expires_at":   "Expiration date of the access token in ISO format (YYYY-MM-DD). The date cannot be set later than the maximum allowable lifetime of an access token. Type: Date",
+		"name":         "Name of the project access token. Type: String",
+		"scopes":       "List of scopes. Type: Array[String]",
+	}, data)
+}
+
+func TestFetchApplyProjectAccessTokens(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	soon := now.AddDate(0, 0, 2).Format(tokenDateFormat)
+
+	server := newTestServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Fproject/access_tokens": `[` +
+			`{"id":1,"name":"bot","scopes":["api"],"access_level":40,"expires_at":"` + soon + `","active":true,"revoked":false,"user_id":10},` +
+			`{"id":2,"name":"reader","scopes":["read_api"],"access_level":20,"expires_at":"2099-01-01","active":true,"revoked":false,"user_id":11},` +
+			`{"id":3,"name":"stale","scopes":["read_api"],"access_level":20,"expires_at":"2000-01-01","active":false,"revoked":false,"user_id":12},` +
+			`{"id":4,"name":"unused","scopes":["read_api"],"access_level":20,"expires_at":"2099-01-01","active":true,"revoked":false,"user_id":13}` +
+			`]`,
+		"POST /api/v4/projects/group%2Fproject/access_tokens/1/rotate": `{"id":5,"token":"rotated-token"}`,
+		"POST /api/v4/projects/group%2Fproject/access_tokens":          `{"id":6,"token":"new-token"}`,
+		"DELETE /api/v4/projects/group%2Fproject/access_tokens/2":      "",
+		"DELETE /api/v4/projects/group%2Fproject/access_tokens/4":      "",
+	})
+
+	tokensOutput := filepath.Join(t.TempDir(), "tokens.yml")
+	opts := Options{ //nolint:exhaustruct
+		Only:             []string{"project_access_tokens"},
+		AccessLevelNames: true,
+		RotateBefore:     7 * 24 * time.Hour,
+		TokensOutput:     tokensOutput,
+	}
+
+	requests := server.fetchApply(t, opts, func(configuration *Configuration) {
+		assert.Equal(t, []map[string]interface{}{
+			{"name": "bot", "scopes": []interface{}{"api"}, "access_level": "maintainer", "expires_at": soon},
+			{"name": "reader", "scopes": []interface{}{"read_api"}, "access_level": "reporter", "expires_at": "2099-01-01"},
+			{"name": "unused", "scopes": []interface{}{"read_api"}, "access_level": "reporter", "expires_at": "2099-01-01"},
+		}, configuration.ProjectAccessTokens)
+
+		configuration.ProjectAccessTokens = []map[string]interface{}{
+			{"name": "bot", "scopes": []interface{}{"api"}, "access_level": "maintainer", "expires_at": soon, "expires_in": 30},
+			{"name": "reader", "scopes": []interface{}{"read_api"}, "access_level": "developer", "expires_at": "2099-01-01"},
+		}
+	})
+
+	expiresAt := now.AddDate(0, 0, 30).Format(tokenDateFormat)
+	assert.Equal(t, []string{
+		"GET /api/v4/projects/group%2Fproject/access_tokens?page=1&per_page=100",
+		"GET /api/v4/projects/group%2Fproject/access_tokens?page=1&per_page=100",
+		"DELETE /api/v4/projects/group%2Fproject/access_tokens/4",
+		`POST /api/v4/projects/group%2Fproject/access_tokens/1/rotate {"expires_at":"` + expiresAt + `"}`,
+		`POST /api/v4/projects/group%2Fproject/access_tokens {"access_level":30,"expires_at":"2099-01-01","name":"reader","scopes":["read_api"]}`,
+		"DELETE /api/v4/projects/group%2Fproject/access_tokens/2",
+	}, requests)
+
+	data, err := os.ReadFile(tokensOutput)
+	require.NoError(t, err)
+	assert.Equal(t, "project_access_tokens:\n  bot: rotated-token\n  reader: new-token\n", string(data))
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// projectFile describes where a configuration of a GitLab project is stored.
+type projectFile struct {
+	// Project is project ID or <namespace/project_path>.
+	Project string `json:"project" yaml:"project"`
+
+	// File is the path to the configuration file.
+	File string `json:"file" yaml:"file"`
+
+	// Avatar is the path where get command saves the avatar to.
+	// File extension is set automatically.
+	Avatar string `json:"avatar,omitempty" yaml:"avatar,omitempty"`
+}
+
+// projectsManifest lists GitLab projects and their configuration files.
+type projectsManifest struct {
+	Projects []projectFile `json:"projects" yaml:"projects"`
+}
+
+// readProjects reads the list of projects and their configuration files from
+// path, which can be a manifest file or a directory.
+//
+// In a directory, each <namespace>/<project_path>.yml file (or with .yaml
+// file extension) is a configuration file for the GitLab project <namespace>/<project_path>,
+// unless another configuration file in the directory extends it.
+//
+// Relative paths in the manifest are relative to the manifest file.
+func readProjects(path string) ([]projectFile, errors.E) {
+	path = kong.ExpandPath(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot read projects")
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+
+	var projects []projectFile
+	var errE errors.E
+	if info.IsDir() {
+		projects, errE = readProjectsDirectory(path)
+	} else {
+		projects, errE = readProjectsManifest(path)
+	}
+	if errE != nil {
+		return nil, errE
+	}
+
+	seen := map[string]bool{}
+	for i, project := range projects {
+		if project.Project == "" || project.File == "" {
+			errE := errors.New("project and file are required")
+			errors.Details(errE)["path"] = path
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+		if seen[project.Project] {
+			errE := errors.New("duplicate project")
+			errors.Details(errE)["path"] = path
+			errors.Details(errE)["project"] = project.Project
+			return nil, errE
+		}
+		seen[project.Project] = true
+		if project.Avatar == "" {
+			projects[i].Avatar = strings.TrimSuffix(project.File, filepath.Ext(project.File)) + ".avatar.img"
+		}
+	}
+
+	return projects, nil
+}
+
+// savedBySet returns true if the file at path is a file which set command
+// saves next to a configuration file (a snapshot or values of new tokens).
+func savedBySet(path string) bool {
+	ext := filepath.Ext(path)
+	for _, saved := range []func(file string) string{snapshotPath, tokensPath} {
+		// For a file with only the extension, we get the suffix added to the name.
+		if strings.HasSuffix(path, saved(ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readProjectsDirectory returns a project for each configuration file in dir.
+//
+// Snapshots and values of new tokens saved by set command are skipped, as are
+// base files which other configuration files in dir extend.
+func readProjectsDirectory(dir string) ([]projectFile, errors.E) {
+	projects := []projectFile{}
+	bases := map[string]bool{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := filepath.Ext(path)
+		if d.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			return nil
+		}
+		if savedBySet(path) {
+			return nil
+		}
+		extends, errE := readExtends(path)
+		if errE != nil {
+			return errE
+		}
+		for _, base := range extends {
+			bases[base] = true
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		projects = append(projects, projectFile{
+			Project: filepath.ToSlash(strings.TrimSuffix(rel, ext)),
+			File:    path,
+			Avatar:  "",
+		})
+		return nil
+	})
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot read projects directory")
+		errors.Details(errE)["path"] = dir
+		return nil, errE
+	}
+	return slices.DeleteFunc(projects, func(project projectFile) bool {
+		return bases[filepath.Clean(project.File)]
+	}), nil
+}
+
+// readExtends returns cleaned paths of base files which the configuration
+// file at path extends.
+//
+// The file is not decrypted, so paths encrypted with SOPS are not returned.
+func readExtends(path string) ([]string, errors.E) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot read configuration")
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+	var config map[string]interface{}
+	err = yaml.Unmarshal(data, &config)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot unmarshal configuration")
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+	extends, errE := getExtends(config)
+	if errE != nil {
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+	for i, base := range extends {
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(filepath.Dir(path), base)
+		}
+		extends[i] = filepath.Clean(base)
+	}
+	return extends, nil
+}
+
+// readProjectsManifest returns projects listed in the manifest file at path,
+// with relative paths resolved against the directory of the manifest file.
+func readProjectsManifest(path string) ([]projectFile, errors.E) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot read projects manifest")
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+
+	var manifest projectsManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&manifest)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot decode projects manifest")
+		errors.Details(errE)["path"] = path
+		return nil, errE
+	}
+
+	base := filepath.Dir(path)
+	for i, project := range manifest.Projects {
+		if project.File != "" && !filepath.IsAbs(project.File) {
+			manifest.Projects[i].File = filepath.Join(base, project.File)
+		}
+		if project.Avatar != "" && !filepath.IsAbs(project.Avatar) {
+			manifest.Projects[i].Avatar = filepath.Join(base, project.Avatar)
+		}
+	}
+
+	return manifest.Projects, nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProjectsDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "group", "subgroup"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "group", "one.yml"), []byte("{}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "group", "one.avatar.png"), []byte{}, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "group", "one.snapshot.yml"), []byte("{}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "group", "subgroup", "two.yaml"), []byte("{}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "group", "subgroup", "two.tokens.yaml"), []byte("{}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "group", "base.yml"), []byte("{}"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "group", "three.yml"), []byte("extends: base.yml\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "group", "subgroup", "four.yml"), []byte("extends:\n  - ../base.yml\n"), 0o600))
+
+	projects, errE := readProjects(dir)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []projectFile{
+		{
+			Project: "group/one",
+			File:    filepath.Join(dir, "group", "one.yml"),
+			Avatar:  filepath.Join(dir, "group", "one.avatar.img"),
+		},
+		{
+			Project: "group/subgroup/four",
+			File:    filepath.Join(dir, "group", "subgroup", "four.yml"),
+			Avatar:  filepath.Join(dir, "group", "subgroup", "four.avatar.img"),
+		},
+		{
+			Project: "group/subgroup/two",
+			File:    filepath.Join(dir, "group", "subgroup", "two.yaml"),
+			Avatar:  filepath.Join(dir, "group", "subgroup", "two.avatar.img"),
+		},
+		{
+			Project: "group/three",
+			File:    filepath.Join(dir, "group", "three.yml"),
+			Avatar:  filepath.Join(dir, "group", "three.avatar.img"),
+		},
+	}, projects)
+}
+
+func TestReadProjectsManifest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.yml")
+	require.NoError(t, os.WriteFile(manifest, []byte(""+
+		"projects:\n"+
+		"  - project: group/one\n"+
+		"    file: one.yml\n"+
+		"  - project: 42\n"+
+		"    file: /configs/two.yml\n"+
+		"    avatar: avatars/two.img\n",
+	), 0o600))
+
+	projects, errE := readProjects(manifest)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []projectFile{
+		{
+			Project: "group/one",
+			File:    filepath.Join(dir, "one.yml"),
+			Avatar:  filepath.Join(dir, "one.avatar.img"),
+		},
+		{
+			Project: "42",
+			File:    "/configs/two.yml",
+			Avatar:  filepath.Join(dir, "avatars", "two.img"),
+		},
+	}, projects)
+
+	require.NoError(t, os.WriteFile(manifest, []byte(""+
+		"projects:\n"+
+		"  - project: group/one\n"+
+		"    file: one.yml\n"+
+		"  - project: group/one\n"+
+		"    file: two.yml\n",
+	), 0o600))
+	_, errE = readProjects(manifest)
+	assert.EqualError(t, errE, "duplicate project")
+
+	require.NoError(t, os.WriteFile(manifest, []byte("projects:\n  - project: group/one\n    path: one.yml\n"), 0o600))
+	_, errE = readProjects(manifest)
+	assert.Error(t, errE)
+}
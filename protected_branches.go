@@ -1,9 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"slices"
 	"sort"
 
@@ -12,24 +12,72 @@ import (
 	"gitlab.com/tozd/go/errors"
 )
 
+// protectedBranchesResource is a Resource for protected branches.
+type protectedBranchesResource struct{}
+
+// Name implements Resource interface.
+func (protectedBranchesResource) Name() string {
+	return "protected_branches"
+}
+
+// Descriptions implements Resource interface.
+func (protectedBranchesResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getProtectedBranchesDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (protectedBranchesResource) Sensitive() []string {
+	return nil
+}
+
+// Keys implements KeyedResource interface.
+func (protectedBranchesResource) Keys() [][]string {
+	return [][]string{{"name"}}
+}
+
+// Describe implements KeyedResource interface.
+func (protectedBranchesResource) Describe() []string {
+	return nil
+}
+
+// Required implements RequiredResource interface.
+func (protectedBranchesResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getProtectedBranchesRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (protectedBranchesResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getProtectedBranches(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (protectedBranchesResource) Resolve(ctx context.Context, t *Target, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	return t.resolveProtectedBranches(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (protectedBranchesResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateProtectedBranches(ctx, client, configuration)
+}
+
 // getProtectedBranches populates configuration struct with configuration available
 // from GitLab protected branches API endpoint.
-func (c *GetCommand) getProtectedBranches(client *gitlab.Client, configuration *Configuration) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting protected branches...\n")
+func (g *Getter) getProtectedBranches(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting protected branches...\n")
 
 	configuration.ProtectedBranches = []map[string]interface{}{}
 
-	descriptions, errE := getProtectedBranchesDescriptions(c.DocsRef)
+	descriptions, errE := getProtectedBranchesDescriptions(ctx, g.Docs)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 	// We need "name" later on.
 	if _, ok := descriptions["name"]; !ok {
-		return false, errors.New(`"name" field is missing in protected branches descriptions`)
+		return errors.New(`"name" field is missing in protected branches descriptions`)
 	}
 	configuration.ProtectedBranchesComment = formatDescriptions(descriptions)
 
-	u := fmt.Sprintf("projects/%s/protected_branches", gitlab.PathEscape(c.Project))
+	u := fmt.Sprintf("projects/%s/protected_branches", gitlab.PathEscape(g.Project))
 	options := &gitlab.ListProtectedBranchesOptions{ //nolint:exhaustruct
 		ListOptions: gitlab.ListOptions{
 			PerPage: maxGitLabPageSize,
@@ -38,11 +86,11 @@ func (c *GetCommand) getProtectedBranches(client *gitlab.Client, configuration *
 	}
 
 	for {
-		req, err := client.NewRequest(http.MethodGet, u, options, nil)
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get protected branches")
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		protectedBranches := []map[string]interface{}{}
@@ -51,7 +99,7 @@ func (c *GetCommand) getProtectedBranches(client *gitlab.Client, configuration *
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get protected branches")
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		if len(protectedBranches) == 0 {
@@ -79,16 +127,32 @@ func (c *GetCommand) getProtectedBranches(client *gitlab.Client, configuration *
 			// Make the description be a comment for the sequence item.
 			renameMapField(protectedBranch, "access_level_description", "comment:")
 
+			if g.AccessLevelNames {
+				for _, field := range []string{"allowed_to_push", "allowed_to_merge", "allowed_to_unprotect"} {
+					accessLevelsToNames(protectedBranch, field)
+				}
+			}
+
+			if !g.IDs {
+				for _, field := range []string{"allowed_to_push", "allowed_to_merge", "allowed_to_unprotect"} {
+					errE := g.nameAccessLevels(ctx, client, protectedBranch, field)
+					if errE != nil {
+						errors.Details(errE)["branch"] = protectedBranch["name"]
+						return errE
+					}
+				}
+			}
+
 			name, ok := protectedBranch["name"]
 			if !ok {
-				return false, errors.New(`protected branch is missing field "name"`)
+				return errors.New(`protected branch is missing field "name"`)
 			}
 			_, ok = name.(string)
 			if !ok {
 				errE := errors.New(`protected branch's field "name" is not a string`)
 				errors.Details(errE)["type"] = fmt.Sprintf("%T", name)
 				errors.Details(errE)["value"] = name
-				return false, errE
+				return errE
 			}
 
 			configuration.ProtectedBranches = append(configuration.ProtectedBranches, protectedBranch)
@@ -107,7 +171,7 @@ func (c *GetCommand) getProtectedBranches(client *gitlab.Client, configuration *
 		return configuration.ProtectedBranches[i]["name"].(string) < configuration.ProtectedBranches[j]["name"].(string) //nolint:forcetypeassert,errcheck
 	})
 
-	return false, nil
+	return nil
 }
 
 // parseProtectedBranchesDocumentation parses GitLab's documentation in Markdown for
@@ -119,25 +183,57 @@ func parseProtectedBranchesDocumentation(input []byte) (map[string]string, error
 
 // getProtectedBranchesDescriptions obtains description of fields used to describe
 // an individual protected branch from GitLab's documentation for protected branches API endpoint.
-func getProtectedBranchesDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/protected_branches.md", gitRef))
+func getProtectedBranchesDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "protected_branches.md")
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get protected branches descriptions")
 	}
 	return parseProtectedBranchesDocumentation(data)
 }
 
+// getProtectedBranchesRequired obtains fields required to create an individual protected branch
+// from GitLab's documentation for protected branches API endpoint.
+func getProtectedBranchesRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "protected_branches.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get protected branches required fields")
+	}
+	return parseRequired(data, "Protect repository branches", nil)
+}
+
+// resolveProtectedBranches returns a copy of the configuration struct in which usernames
+// and full paths of groups in access levels of protected branches are replaced with IDs.
+func (t *Target) resolveProtectedBranches(ctx context.Context, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	if configuration.ProtectedBranches == nil {
+		return configuration, nil
+	}
+
+	resolved := *configuration
+	resolved.ProtectedBranches, _ = deepCopy(configuration.ProtectedBranches).([]map[string]interface{})
+	for i, protectedBranch := range resolved.ProtectedBranches {
+		for _, field := range []string{"allowed_to_push", "allowed_to_merge", "allowed_to_unprotect"} {
+			errE := t.resolveAccessLevels(ctx, client, protectedBranch, field)
+			if errE != nil {
+				errors.Details(errE)["index"] = i
+				return nil, errE
+			}
+		}
+	}
+
+	return &resolved, nil
+}
+
 // updateProtectedBranches updates GitLab project's protected branches using GitLab
 // protected branches API endpoint based on the configuration struct.
 //
 // Access levels without the ID field are matched to existing access labels based on
 // their fields. Unmatched access levels are created as new.
-func (c *SetCommand) updateProtectedBranches(client *gitlab.Client, configuration *Configuration) errors.E { //nolint:maintidx
+func (s *Setter) updateProtectedBranches(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E { //nolint:maintidx
 	if configuration.ProtectedBranches == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating protected branches...\n")
+	s.Printf("Updating protected branches...\n")
 
 	options := &gitlab.ListProtectedBranchesOptions{ //nolint:exhaustruct
 		ListOptions: gitlab.ListOptions{
@@ -149,7 +245,7 @@ func (c *SetCommand) updateProtectedBranches(client *gitlab.Client, configuratio
 	protectedBranches := []*gitlab.ProtectedBranch{}
 
 	for {
-		pb, response, err := client.ProtectedBranches.ListProtectedBranches(c.Project, options)
+		pb, response, err := client.ProtectedBranches.ListProtectedBranches(s.Project, options, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get protected branches")
 			errors.Details(errE)["page"] = options.Page
@@ -168,6 +264,10 @@ func (c *SetCommand) updateProtectedBranches(client *gitlab.Client, configuratio
 	existingProtectedBranches := map[string]*gitlab.ProtectedBranch{}
 	existingProtectedBranchesSet := mapset.NewThreadUnsafeSet[string]()
 	for _, protectedBranch := range protectedBranches {
+		// Protected branches matching ignore rules are neither updated nor deleted.
+		if configuration.ignored("protected_branches", map[string]interface{}{"name": protectedBranch.Name}) {
+			continue
+		}
 		existingProtectedBranchesSet.Add(protectedBranch.Name)
 		existingProtectedBranches[protectedBranch.Name] = protectedBranch
 	}
@@ -192,9 +292,13 @@ func (c *SetCommand) updateProtectedBranches(client *gitlab.Client, configuratio
 	}
 
 	extraProtectedBranchesSlice := existingProtectedBranchesSet.Difference(wantedProtectedBranchesSet).ToSlice()
+	if !configuration.prune("protected_branches", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraProtectedBranchesSlice = nil
+	}
 	slices.Sort(extraProtectedBranchesSlice)
 	for _, protectedBranchName := range extraProtectedBranchesSlice {
-		_, err := client.ProtectedBranches.UnprotectRepositoryBranches(c.Project, protectedBranchName)
+		_, err := client.ProtectedBranches.UnprotectRepositoryBranches(s.Project, protectedBranchName, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to unprotect branch")
 			errors.Details(errE)["branch"] = protectedBranchName
@@ -341,7 +445,8 @@ func (c *SetCommand) updateProtectedBranches(client *gitlab.Client, configuratio
 				}
 			}
 
-			req, err := client.NewRequest(http.MethodPatch, fmt.Sprintf("projects/%s/protected_branches/%s", gitlab.PathEscape(c.Project), name), protectedBranch, nil)
+			u := fmt.Sprintf("projects/%s/protected_branches/%s", gitlab.PathEscape(s.Project), name)
+			req, err := client.NewRequest(http.MethodPatch, u, protectedBranch, contextOptions(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to update protected branch")
 				errors.Details(errE)["index"] = i
@@ -357,7 +462,7 @@ func (c *SetCommand) updateProtectedBranches(client *gitlab.Client, configuratio
 			}
 		} else {
 			// We create a new protected branch.
-			req, err := client.NewRequest(http.MethodPost, fmt.Sprintf("projects/%s/protected_branches", gitlab.PathEscape(c.Project)), protectedBranch, nil)
+			req, err := client.NewRequest(http.MethodPost, fmt.Sprintf("projects/%s/protected_branches", gitlab.PathEscape(s.Project)), protectedBranch, contextOptions(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to protect branch")
 				errors.Details(errE)["index"] = i
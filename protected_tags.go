@@ -1,9 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"slices"
 	"sort"
 
@@ -12,35 +12,83 @@ import (
 	"gitlab.com/tozd/go/errors"
 )
 
+// protectedTagsResource is a Resource for protected tags.
+type protectedTagsResource struct{}
+
+// Name implements Resource interface.
+func (protectedTagsResource) Name() string {
+	return "protected_tags"
+}
+
+// Descriptions implements Resource interface.
+func (protectedTagsResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getProtectedTagsDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (protectedTagsResource) Sensitive() []string {
+	return nil
+}
+
+// Keys implements KeyedResource interface.
+func (protectedTagsResource) Keys() [][]string {
+	return [][]string{{"name"}}
+}
+
+// Describe implements KeyedResource interface.
+func (protectedTagsResource) Describe() []string {
+	return nil
+}
+
+// Required implements RequiredResource interface.
+func (protectedTagsResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getProtectedTagsRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (protectedTagsResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getProtectedTags(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (protectedTagsResource) Resolve(ctx context.Context, t *Target, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	return t.resolveProtectedTags(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (protectedTagsResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateProtectedTags(ctx, client, configuration)
+}
+
 // getProtectedTags populates configuration struct with configuration available
 // from GitLab protected tags API endpoint.
-func (c *GetCommand) getProtectedTags(client *gitlab.Client, configuration *Configuration) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting protected tags...\n")
+func (g *Getter) getProtectedTags(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting protected tags...\n")
 
 	configuration.ProtectedTags = []map[string]interface{}{}
 
-	descriptions, errE := getProtectedTagsDescriptions(c.DocsRef)
+	descriptions, errE := getProtectedTagsDescriptions(ctx, g.Docs)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 	// We need "name" later on.
 	if _, ok := descriptions["name"]; !ok {
-		return false, errors.New(`"name" field is missing in protected tags descriptions`)
+		return errors.New(`"name" field is missing in protected tags descriptions`)
 	}
 	configuration.ProtectedTagsComment = formatDescriptions(descriptions)
 
-	u := fmt.Sprintf("projects/%s/protected_tags", gitlab.PathEscape(c.Project))
+	u := fmt.Sprintf("projects/%s/protected_tags", gitlab.PathEscape(g.Project))
 	options := &gitlab.ListProtectedTagsOptions{
 		PerPage: maxGitLabPageSize,
 		Page:    1,
 	}
 
 	for {
-		req, err := client.NewRequest(http.MethodGet, u, options, nil)
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get protected tags")
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		protectedTags := []map[string]interface{}{}
@@ -49,7 +97,7 @@ func (c *GetCommand) getProtectedTags(client *gitlab.Client, configuration *Conf
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get protected tags")
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		if len(protectedTags) == 0 {
@@ -80,16 +128,32 @@ func (c *GetCommand) getProtectedTags(client *gitlab.Client, configuration *Conf
 			// Make the description be a comment for the sequence item.
 			renameMapField(protectedTag, "access_level_description", "comment:")
 
+			if g.AccessLevelNames {
+				for _, field := range []string{"allowed_to_create"} {
+					accessLevelsToNames(protectedTag, field)
+				}
+			}
+
+			if !g.IDs {
+				for _, field := range []string{"allowed_to_create"} {
+					errE := g.nameAccessLevels(ctx, client, protectedTag, field)
+					if errE != nil {
+						errors.Details(errE)["tag"] = protectedTag["name"]
+						return errE
+					}
+				}
+			}
+
 			name, ok := protectedTag["name"]
 			if !ok {
-				return false, errors.New(`protected tag is missing field "name"`)
+				return errors.New(`protected tag is missing field "name"`)
 			}
 			_, ok = name.(string)
 			if !ok {
 				errE := errors.New(`protected tag's field "name" is not a string`)
 				errors.Details(errE)["type"] = fmt.Sprintf("%T", name)
 				errors.Details(errE)["value"] = name
-				return false, errE
+				return errE
 			}
 
 			configuration.ProtectedTags = append(configuration.ProtectedTags, protectedTag)
@@ -108,34 +172,69 @@ func (c *GetCommand) getProtectedTags(client *gitlab.Client, configuration *Conf
 		return configuration.ProtectedTags[i]["name"].(string) < configuration.ProtectedTags[j]["name"].(string) //nolint:forcetypeassert,errcheck
 	})
 
-	return false, nil
+	return nil
+}
+
+// protectedTagsKeyMapper maps fields in GitLab's documentation for protected tags.
+func protectedTagsKeyMapper(key string) string {
+	switch key {
+	case "create_access_level":
+		// We prefer that everything is done through "allowed_to_create".
+		return ""
+	default:
+		return key
+	}
 }
 
 // parseProtectedTagsDocumentation parses GitLab's documentation in Markdown for
 // protected tags API endpoint and extracts description of fields used to describe
 // protected tags.
 func parseProtectedTagsDocumentation(input []byte) (map[string]string, errors.E) {
-	return parseTable(input, "Protect repository tags", func(key string) string {
-		switch key {
-		case "create_access_level":
-			// We prefer that everything is done through "allowed_to_create".
-			return ""
-		default:
-			return key
-		}
-	})
+	return parseTable(input, "Protect repository tags", protectedTagsKeyMapper)
 }
 
 // getProtectedTagsDescriptions obtains description of fields used to describe
 // an individual protected tags from GitLab's documentation for protected tags API endpoint.
-func getProtectedTagsDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/protected_tags.md", gitRef))
+func getProtectedTagsDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "protected_tags.md")
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get protected tags descriptions")
 	}
 	return parseProtectedTagsDocumentation(data)
 }
 
+// getProtectedTagsRequired obtains fields required to create an individual protected tag
+// from GitLab's documentation for protected tags API endpoint.
+func getProtectedTagsRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "protected_tags.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get protected tags required fields")
+	}
+	return parseRequired(data, "Protect repository tags", protectedTagsKeyMapper)
+}
+
+// resolveProtectedTags returns a copy of the configuration struct in which usernames
+// and full paths of groups in access levels of protected tags are replaced with IDs.
+func (t *Target) resolveProtectedTags(ctx context.Context, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	if configuration.ProtectedTags == nil {
+		return configuration, nil
+	}
+
+	resolved := *configuration
+	resolved.ProtectedTags, _ = deepCopy(configuration.ProtectedTags).([]map[string]interface{})
+	for i, protectedTag := range resolved.ProtectedTags {
+		for _, field := range []string{"allowed_to_create"} {
+			errE := t.resolveAccessLevels(ctx, client, protectedTag, field)
+			if errE != nil {
+				errors.Details(errE)["index"] = i
+				return nil, errE
+			}
+		}
+	}
+
+	return &resolved, nil
+}
+
 // updateProtectedTags updates GitLab project's protected tags using GitLab
 // protected tags API endpoint based on the configuration struct.
 //
@@ -143,12 +242,12 @@ func getProtectedTagsDescriptions(gitRef string) (map[string]string, errors.E) {
 // configured as protected, and then updates or adds protection for configured
 // protected tags. When updating an existing protected tag it briefly umprotects
 // the tag and reprotects it with new configuration.
-func (c *SetCommand) updateProtectedTags(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updateProtectedTags(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
 	if configuration.ProtectedTags == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating protected tags...\n")
+	s.Printf("Updating protected tags...\n")
 
 	options := &gitlab.ListProtectedTagsOptions{
 		PerPage: maxGitLabPageSize,
@@ -158,7 +257,7 @@ func (c *SetCommand) updateProtectedTags(client *gitlab.Client, configuration *C
 	protectedTags := []*gitlab.ProtectedTag{}
 
 	for {
-		pt, response, err := client.ProtectedTags.ListProtectedTags(c.Project, options)
+		pt, response, err := client.ProtectedTags.ListProtectedTags(s.Project, options, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to get protected tags")
 			errors.Details(errE)["page"] = options.Page
@@ -176,6 +275,10 @@ func (c *SetCommand) updateProtectedTags(client *gitlab.Client, configuration *C
 
 	existingProtectedTagsSet := mapset.NewThreadUnsafeSet[string]()
 	for _, protectedTag := range protectedTags {
+		// Protected tags matching ignore rules are neither updated nor deleted.
+		if configuration.ignored("protected_tags", map[string]interface{}{"name": protectedTag.Name}) {
+			continue
+		}
 		existingProtectedTagsSet.Add(protectedTag.Name)
 	}
 
@@ -199,9 +302,13 @@ func (c *SetCommand) updateProtectedTags(client *gitlab.Client, configuration *C
 	}
 
 	extraProtectedTags := existingProtectedTagsSet.Difference(wantedProtectedTagsSet).ToSlice()
+	if !configuration.prune("protected_tags", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraProtectedTags = nil
+	}
 	slices.Sort(extraProtectedTags)
 	for _, protectedTagName := range extraProtectedTags {
-		_, err := client.ProtectedTags.UnprotectRepositoryTags(c.Project, protectedTagName)
+		_, err := client.ProtectedTags.UnprotectRepositoryTags(s.Project, protectedTagName, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to unprotect tag")
 			errors.Details(errE)["tag"] = protectedTagName
@@ -209,7 +316,7 @@ func (c *SetCommand) updateProtectedTags(client *gitlab.Client, configuration *C
 		}
 	}
 
-	u := fmt.Sprintf("projects/%s/protected_tags", gitlab.PathEscape(c.Project))
+	u := fmt.Sprintf("projects/%s/protected_tags", gitlab.PathEscape(s.Project))
 
 	for i, protectedTag := range configuration.ProtectedTags { //nolint:dupl
 		// We made sure above that all protected tags in configuration have a string name.
@@ -218,7 +325,7 @@ func (c *SetCommand) updateProtectedTags(client *gitlab.Client, configuration *C
 		// If project already have this protected tag, we have to
 		// first unprotect it to be able to update the protected tag.
 		if existingProtectedTagsSet.Contains(name) {
-			_, err := client.ProtectedTags.UnprotectRepositoryTags(c.Project, name)
+			_, err := client.ProtectedTags.UnprotectRepositoryTags(s.Project, name, gitlab.WithContext(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to unprotect tag before reprotecting")
 				errors.Details(errE)["index"] = i
@@ -227,7 +334,7 @@ func (c *SetCommand) updateProtectedTags(client *gitlab.Client, configuration *C
 			}
 		}
 
-		req, err := client.NewRequest(http.MethodPost, u, protectedTag, nil)
+		req, err := client.NewRequest(http.MethodPost, u, protectedTag, contextOptions(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to protect tag")
 			errors.Details(errE)["index"] = i
@@ -1,17 +1,45 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/xanzy/go-gitlab"
 	"gitlab.com/tozd/go/errors"
 )
 
-func getPushRules(client *gitlab.Client, project string) (map[string]interface{}, errors.E) {
+// pushRulesResource is a Resource for push rules.
+type pushRulesResource struct{}
+
+// Name implements Resource interface.
+func (pushRulesResource) Name() string {
+	return "push_rules"
+}
+
+// Descriptions implements Resource interface.
+func (pushRulesResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getPushRulesDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (pushRulesResource) Sensitive() []string {
+	return nil
+}
+
+// Get implements Resource interface.
+func (pushRulesResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getPushRules(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (pushRulesResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updatePushRules(ctx, client, configuration)
+}
+
+func getPushRules(ctx context.Context, client *gitlab.Client, project string) (map[string]interface{}, errors.E) {
 	u := fmt.Sprintf("projects/%s/push_rule", gitlab.PathEscape(project))
-	req, err := client.NewRequest(http.MethodGet, u, nil, nil)
+	req, err := client.NewRequest(http.MethodGet, u, nil, contextOptions(ctx))
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get push rules")
 	}
@@ -28,19 +56,19 @@ func getPushRules(client *gitlab.Client, project string) (map[string]interface{}
 
 // getPushRules populates configuration struct with GitLab's project's
 // push rules available from GitLab push rules API endpoint.
-func (c *GetCommand) getPushRules(client *gitlab.Client, configuration *Configuration) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting push rules...\n")
+func (g *Getter) getPushRules(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting push rules...\n")
 
 	configuration.PushRules = map[string]interface{}{}
 
-	descriptions, errE := getPushRulesDescriptions(c.DocsRef)
+	descriptions, errE := getPushRulesDescriptions(ctx, g.Docs)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 
-	pushRules, errE := getPushRules(client, c.Project)
+	pushRules, errE := getPushRules(ctx, client, g.Project)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 
 	// Only retain those keys which can be edited through the API
@@ -57,7 +85,7 @@ func (c *GetCommand) getPushRules(client *gitlab.Client, configuration *Configur
 
 	configuration.PushRules = pushRules
 
-	return false, nil
+	return nil
 }
 
 // parsePushRulesDocumentation parses GitLab's documentation in Markdown for
@@ -69,8 +97,8 @@ func parsePushRulesDocumentation(input []byte) (map[string]string, errors.E) {
 
 // getPushRulesDescriptions obtains description of fields used to describe payload for
 // project's push rules from GitLab's documentation for push rules API endpoint.
-func getPushRulesDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/projects.md", gitRef))
+func getPushRulesDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "projects.md")
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get push rules descriptions")
 	}
@@ -79,14 +107,14 @@ func getPushRulesDescriptions(gitRef string) (map[string]string, errors.E) {
 
 // updatePushRules updates GitLab project's push rules
 // using GitLab push rules API endpoint based on the configuration struct.
-func (c *SetCommand) updatePushRules(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updatePushRules(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
 	if configuration.PushRules == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating push rules...\n")
+	s.Printf("Updating push rules...\n")
 
-	pushRules, errE := getPushRules(client, c.Project)
+	pushRules, errE := getPushRules(ctx, client, s.Project)
 	if errE != nil {
 		return errE
 	}
@@ -95,7 +123,7 @@ func (c *SetCommand) updatePushRules(client *gitlab.Client, configuration *Confi
 		// The call is not really idempotent, so we delete rules only if they exist.
 		// See: https://gitlab.com/gitlab-org/gitlab/-/issues/427352
 		if len(pushRules) > 0 {
-			_, err := client.Projects.DeleteProjectPushRule(c.Project)
+			_, err := client.Projects.DeleteProjectPushRule(s.Project, gitlab.WithContext(ctx))
 			if err != nil {
 				return errors.WithMessage(err, "failed to delete push rules")
 			}
@@ -113,8 +141,8 @@ func (c *SetCommand) updatePushRules(client *gitlab.Client, configuration *Confi
 		description = "update"
 	}
 
-	u := fmt.Sprintf("projects/%s/push_rule", gitlab.PathEscape(c.Project))
-	req, err := client.NewRequest(method, u, configuration.PushRules, nil)
+	u := fmt.Sprintf("projects/%s/push_rule", gitlab.PathEscape(s.Project))
+	req, err := client.NewRequest(method, u, configuration.PushRules, contextOptions(ctx))
 	if err != nil {
 		return errors.WithMessagef(err, "failed to %s push rules", description)
 	}
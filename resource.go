@@ -0,0 +1,442 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+// Target is the GitLab project or group resources operate on.
+type Target struct {
+	// Project is project ID or <namespace/project_path>. It is empty for a group.
+	Project string
+
+	// Group is group ID or <namespace/group_path>. It is empty for a project.
+	Group string
+
+	// Docs is where GitLab's API documentation is obtained from.
+	Docs Docs
+
+	// Logger is used to report progress. If nil, progress is not reported.
+	Logger Logger
+
+	// names caches resolved usernames and full paths of groups and projects.
+	names *nameResolver
+}
+
+// Printf reports progress using the logger, if set.
+func (t *Target) Printf(format string, v ...interface{}) {
+	if t.Logger != nil {
+		t.Logger.Printf(format, v...)
+	}
+}
+
+// OwnerPath returns the API path of the group if owner is "group"
+// and of the project otherwise.
+func (t *Target) OwnerPath(owner string) string {
+	if owner == "group" {
+		return "groups/" + gitlab.PathEscape(t.Group)
+	}
+	return "projects/" + gitlab.PathEscape(t.Project)
+}
+
+// withResource returns a context which tags requests made with it with the project
+// (or group) and the configuration section of the resource, for events.
+func (t *Target) withResource(ctx context.Context, resource string) context.Context {
+	return context.WithValue(ctx, eventTagsKey{}, eventTags{
+		Project:  t.Project,
+		Group:    t.Group,
+		Resource: resource,
+	})
+}
+
+// Getter describes how resources obtain configuration from GitLab.
+type Getter struct {
+	Target
+
+	// IDs makes resources reference users, groups, and projects by their IDs
+	// instead of usernames and full paths.
+	IDs bool
+
+	// AccessLevelNames makes resources reference access levels by their names
+	// instead of integers.
+	AccessLevelNames bool
+
+	// Avatar is the path where the avatar is saved to. File extension is set automatically.
+	Avatar string
+
+	// Workers is the maximum number of concurrent requests a resource should make.
+	Workers int
+}
+
+// Setter describes how resources update GitLab.
+type Setter struct {
+	Target
+
+	// NoPrune makes resources not delete objects which exist in GitLab
+	// but are missing from the configuration.
+	NoPrune bool
+
+	// RotateBefore makes resources rotate tokens with "expires_in" field which
+	// expire sooner than this.
+	RotateBefore time.Duration
+
+	// TokensOutput is the path where values of new tokens are saved.
+	TokensOutput string
+
+	// Input is the path of the configuration file the configuration was read from.
+	// If TokensOutput is empty, values of new tokens are saved into it, under field
+	// names with EncSuffix.
+	Input     string
+	EncSuffix string
+}
+
+// Resource is a configuration section which can be obtained from GitLab
+// and updated in GitLab.
+//
+// Resources are registered either for projects or for groups. Group resources
+// use Group field of Target instead of Project field.
+//
+// Configuration sections of resources which are not built into this package
+// are stored in Extra field of Configuration under the resource's name.
+// A comment for the section can be stored under the name with "comment:" prefix.
+type Resource interface {
+	// Name returns the name of the configuration section.
+	Name() string
+
+	// Descriptions returns descriptions of fields of the configuration section
+	// (or of objects in the configuration section, if it is a list) as extracted
+	// from GitLab's documentation obtained from docs. It returns nil if the configuration
+	// section does not have fields.
+	Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E)
+
+	// Sensitive returns names of fields of the configuration section
+	// (or of objects in the configuration section, if it is a list) which hold
+	// sensitive values.
+	Sensitive() []string
+
+	// Get populates the configuration with the configuration section
+	// as available from GitLab. Requests should be made using ctx.
+	Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E
+
+	// Update updates GitLab based on the configuration section. If the configuration
+	// section is nil, it should not do anything. Requests should be made using ctx.
+	Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E
+}
+
+// KeyedResource is a Resource which configuration section is a list of objects
+// where objects can be matched between configurations based on their fields.
+type KeyedResource interface {
+	Resource
+
+	// Keys returns sets of fields which identify an object. Sets are tried in order
+	// and the first set for which all fields are present in the wanted object
+	// and which matches an existing object is used. If no set matches, the
+	// object is seen as new.
+	Keys() [][]string
+
+	// Describe returns fields used to describe an object to the user.
+	// If empty, fields from Keys are used.
+	Describe() []string
+}
+
+// DefaultedResource is a KeyedResource where GitLab uses a default value for some
+// fields from Keys when they are not set (e.g., "*" for environment scope of variables).
+// Objects are matched as if missing fields were set to their default values.
+type DefaultedResource interface {
+	KeyedResource
+
+	// KeyDefaults returns default values of fields from Keys.
+	KeyDefaults() map[string]interface{}
+}
+
+// RequiredResource is a Resource which configuration section is a list of objects
+// where some fields are required for an object to be created.
+type RequiredResource interface {
+	Resource
+
+	// Required returns names of fields which are required for an object to be created
+	// as extracted from GitLab's documentation obtained from docs.
+	Required(ctx context.Context, docs Docs) ([]string, errors.E)
+}
+
+// ReferencingResource is a Resource which configuration section can reference
+// users, groups, or projects by their usernames and full paths instead of IDs,
+// access levels by their names instead of values, or files by their paths
+// instead of their contents.
+type ReferencingResource interface {
+	Resource
+
+	// Resolve returns a copy of the configuration in which usernames and full paths
+	// in the configuration section are replaced with IDs, resolved using t,
+	// names of access levels with their values, and paths with contents of files.
+	// The configuration itself is not modified. Requests should be made using ctx.
+	Resolve(ctx context.Context, t *Target, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E)
+}
+
+// WriteOnlyResource is a Resource which configuration section has fields which
+// are used when updating GitLab but which GitLab does not return (e.g., lifetimes
+// of tokens). They are not compared with the configuration as available from
+// GitLab and merging with get command keeps them.
+type WriteOnlyResource interface {
+	Resource
+
+	// WriteOnly returns names of fields of the configuration section
+	// (or of objects in the configuration section, if it is a list) which
+	// GitLab does not return.
+	WriteOnly() []string
+}
+
+// IdentifiedResource is a KeyedResource which objects can be identified by a value
+// computed from them (e.g., a fingerprint of a public key) even when their keys differ
+// (e.g., a path to a file with the public key instead of the key itself).
+// Merging with get command matches objects by their identity first and matched
+// objects keep their keys.
+type IdentifiedResource interface {
+	KeyedResource
+
+	// Identity returns the identity of the object, or an empty string if it cannot
+	// be determined. Relative paths in the object are relative to dir.
+	Identity(object map[string]interface{}, dir string) string
+}
+
+var (
+	resourcesMu sync.RWMutex  //nolint:gochecknoglobals
+	resources   = []Resource{ //nolint:gochecknoglobals
+		projectResource{},
+		avatarResource{},
+		sharedWithGroupsResource{},
+		forkedFromProjectResource{},
+		approvalsResource{},
+		approvalRulesResource{},
+		pushRulesResource{},
+		labelsResource{},
+		protectedBranchesResource{},
+		protectedTagsResource{},
+		variablesResource{},
+		pipelineSchedulesResource{},
+		hooksResource{},
+		deployKeysResource{},
+		deployTokensResource{},
+		projectAccessTokensResource{},
+		membersResource{},
+	}
+	groupResources = []Resource{ //nolint:gochecknoglobals
+		groupResource{},
+		groupVariablesResource{},
+		groupLabelsResource{},
+	}
+)
+
+// RegisterResource registers a project resource, making get and set commands
+// process it after all already registered project resources.
+func RegisterResource(resource Resource) errors.E {
+	return registerResource(&resources, resource)
+}
+
+// RegisterGroupResource registers a group resource, making get and set commands
+// process it after all already registered group resources when used with a group.
+func RegisterGroupResource(resource Resource) errors.E {
+	return registerResource(&groupResources, resource)
+}
+
+func registerResource(list *[]Resource, resource Resource) errors.E {
+	resourcesMu.Lock()
+	defer resourcesMu.Unlock()
+
+	name := resource.Name()
+	if name == "" || strings.HasPrefix(name, "comment:") {
+		errE := errors.New("invalid resource name")
+		errors.Details(errE)["name"] = name
+		return errE
+	}
+	for _, r := range *list {
+		if r.Name() == name {
+			errE := errors.New("resource already registered")
+			errors.Details(errE)["name"] = name
+			return errE
+		}
+	}
+
+	*list = append(*list, resource)
+	return nil
+}
+
+// Resources returns all registered project resources in the order they are processed.
+func Resources() []Resource {
+	resourcesMu.RLock()
+	defer resourcesMu.RUnlock()
+
+	return append([]Resource{}, resources...)
+}
+
+// GroupResources returns all registered group resources in the order they are processed.
+func GroupResources() []Resource {
+	resourcesMu.RLock()
+	defer resourcesMu.RUnlock()
+
+	return append([]Resource{}, groupResources...)
+}
+
+// Section returns the configuration section with the name. It returns nil
+// if the configuration section is not set.
+func (c *Configuration) Section(name string) interface{} {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == name && tag != "" {
+			field := v.Field(i)
+			if field.Kind() != reflect.String && field.IsNil() {
+				return nil
+			}
+			return field.Interface()
+		}
+	}
+	return c.Extra[name]
+}
+
+// setSection sets the configuration section with the name to the value.
+// The value has to be of the type of the configuration section.
+func (c *Configuration) setSection(name string, value interface{}) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == name && tag != "" {
+			if value == nil {
+				v.Field(i).SetZero()
+			} else {
+				v.Field(i).Set(reflect.ValueOf(value))
+			}
+			return
+		}
+	}
+	extra := make(map[string]interface{}, len(c.Extra)+1)
+	for key, val := range c.Extra {
+		extra[key] = val
+	}
+	extra[name] = value
+	c.Extra = extra
+}
+
+// sectionObjects returns objects of the configuration section if it is a list,
+// with nil for items which are not objects. It returns nil otherwise.
+func sectionObjects(section interface{}) []map[string]interface{} {
+	switch s := section.(type) {
+	case []map[string]interface{}:
+		return s
+	case []interface{}:
+		objects := make([]map[string]interface{}, len(s))
+		for i, item := range s {
+			objects[i], _ = item.(map[string]interface{})
+		}
+		return objects
+	default:
+		return nil
+	}
+}
+
+// sourceDir returns the directory of the configuration file the object at index
+// in the configuration section with the name was read from, or "." if it is not known.
+func (c *Configuration) sourceDir(name string, index int) string {
+	dirs := c.sourceDirs[name]
+	if index < len(dirs) && dirs[index] != "" {
+		return dirs[index]
+	}
+	return "."
+}
+
+// pruneKey is the top-level key of the configuration file which
+// disables pruning for configuration sections.
+const pruneKey = "prune"
+
+// prune returns true if objects which exist in GitLab but are missing from
+// the configuration section with the name should be deleted. They are not
+// deleted if noPrune is true or if pruning is disabled for the configuration section.
+func (c *Configuration) prune(name string, noPrune bool) bool {
+	if noPrune {
+		return false
+	}
+	prune, ok := c.Prune[name]
+	return !ok || prune
+}
+
+// copySection copies the configuration section with the name, together
+// with its comment, from another configuration.
+func (c *Configuration) copySection(from *Configuration, name string) {
+	names := []string{name, "comment:" + name}
+	v := reflect.ValueOf(c).Elem()
+	f := reflect.ValueOf(from).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag != "" && slices.Contains(names, tag) {
+			v.Field(i).Set(f.Field(i))
+			names = slices.DeleteFunc(names, func(n string) bool { return n == tag })
+		}
+	}
+	for _, n := range names {
+		value, ok := from.Extra[n]
+		if !ok {
+			continue
+		}
+		if c.Extra == nil {
+			c.Extra = map[string]interface{}{}
+		}
+		c.Extra[n] = value
+	}
+}
+
+// annotateSensitive marks values of sensitive fields in the configuration section
+// for encryption with SOPS using the encComment comment and/or the encSuffix
+// field name suffix.
+//
+// It returns true if the configuration section includes any sensitive value.
+func annotateSensitive(section interface{}, fields []string, encComment, encSuffix string) bool {
+	if len(fields) == 0 {
+		return false
+	}
+
+	hasSensitive := false
+	annotate := func(obj map[string]interface{}) {
+		for _, field := range fields {
+			value, ok := obj[field]
+			if !ok {
+				continue
+			}
+			hasSensitive = true
+			if encComment != "" {
+				obj["comment:"+field+encSuffix] = encComment
+			}
+			if encSuffix != "" {
+				obj[field+encSuffix] = value
+				delete(obj, field)
+			}
+		}
+	}
+
+	switch s := section.(type) {
+	case map[string]interface{}:
+		annotate(s)
+	case []map[string]interface{}:
+		for _, obj := range s {
+			annotate(obj)
+		}
+	case []interface{}:
+		for _, obj := range s {
+			o, ok := obj.(map[string]interface{})
+			if ok {
+				annotate(o)
+			}
+		}
+	}
+
+	return hasSensitive
+}
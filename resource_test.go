@@ -0,0 +1,125 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRegisterResource(t *testing.T) {
+	t.Parallel()
+
+	errE := RegisterResource(labelsResource{})
+	assert.EqualError(t, errE, "resource already registered")
+
+	names := []string{}
+	for _, resource := range Resources() {
+		names = append(names, resource.Name())
+	}
+	assert.Equal(t, []string{
+		"project",
+		"avatar",
+		"shared_with_groups",
+		"forked_from_project",
+		"approvals",
+		"approval_rules",
+		"push_rules",
+		"labels",
+		"protected_branches",
+		"protected_tags",
+		"variables",
+		"pipeline_schedules",
+	}, names[:12])
+}
+
+func TestConfigurationSection(t *testing.T) {
+	t.Parallel()
+
+	var configuration Configuration
+	err := yaml.Unmarshal([]byte("labels:\n  - name: bug\nvariables: null\ncustom:\n  - url: https://example.com\n"), &configuration)
+	require.NoError(t, err)
+
+	assert.Equal(t, []map[string]interface{}{{"name": "bug"}}, configuration.Section("labels"))
+	assert.Nil(t, configuration.Section("variables"))
+	assert.Nil(t, configuration.Section("project"))
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "https://example.com"}}, configuration.Section("custom"))
+	assert.Nil(t, configuration.Section("unknown"))
+}
+
+func TestConfigurationCopySection(t *testing.T) {
+	t.Parallel()
+
+	from := Configuration{
+		Labels:        []map[string]interface{}{{"name": "bug"}},
+		LabelsComment: "labels",
+		Variables:     []map[string]interface{}{{"key": "FOO"}},
+		Extra: map[string]interface{}{
+			"custom":         []interface{}{},
+			"comment:custom": "custom",
+		},
+	}
+
+	var configuration Configuration
+	configuration.copySection(&from, "labels")
+	configuration.copySection(&from, "custom")
+
+	assert.Equal(t, from.Labels, configuration.Labels)
+	assert.Equal(t, "labels", configuration.LabelsComment)
+	assert.Nil(t, configuration.Variables)
+	assert.Equal(t, from.Extra, configuration.Extra)
+}
+
+func TestAnnotateSensitive(t *testing.T) {
+	t.Parallel()
+
+	section := []map[string]interface{}{
+		{"key": "FOO", "value": "bar"},
+		{"key": "EMPTY"},
+	}
+	assert.True(t, annotateSensitive(section, []string{"value"}, "sops:enc", "_enc"))
+	assert.Equal(t, []map[string]interface{}{
+		{"key": "FOO", "value_enc": "bar", "comment:value_enc": "sops:enc"},
+		{"key": "EMPTY"},
+	}, section)
+
+	assert.False(t, annotateSensitive([]map[string]interface{}{{"key": "EMPTY"}}, []string{"value"}, "sops:enc", ""))
+	assert.False(t, annotateSensitive(map[string]interface{}{"value": "x"}, nil, "sops:enc", ""))
+}
+
+func TestSectionsResources(t *testing.T) {
+	t.Parallel()
+
+	names := func(resources []Resource) []string {
+		n := []string{}
+		for _, resource := range resources {
+			n = append(n, resource.Name())
+		}
+		return n
+	}
+
+	resources, errE := (&Sections{Only: []string{"variables", "labels"}, Skip: nil}).resources(Resources())
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []string{"labels", "variables"}, names(resources))
+
+	resources, errE = (&Sections{Only: []string{"variables", "labels"}, Skip: []string{"labels"}}).resources(Resources())
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []string{"variables"}, names(resources))
+
+	resources, errE = (&Sections{Only: nil, Skip: []string{"avatar"}}).resources(Resources())
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.NotContains(t, names(resources), "avatar")
+	assert.Contains(t, names(resources), "project")
+	assert.Equal(t, []string{"avatar", "group"}, excludedSections(resources))
+
+	resources, errE = (&Sections{Only: []string{"labels"}, Skip: nil}).resources(GroupResources())
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []string{"labels"}, names(resources))
+
+	_, errE = (&Sections{Only: []string{"project"}, Skip: nil}).resources(GroupResources())
+	assert.EqualError(t, errE, "unknown configuration section")
+
+	_, errE = (&Sections{Only: []string{"unknown"}, Skip: nil}).resources(Resources())
+	assert.EqualError(t, errE, "unknown configuration section")
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"gitlab.com/tozd/go/errors"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaTypes maps types used in GitLab's documentation to JSON Schema types.
+var schemaTypes = map[string]string{ //nolint:gochecknoglobals
+	"string":   "string",
+	"integer":  "integer",
+	"boolean":  "boolean",
+	"array":    "array",
+	"hash":     "object",
+	"object":   "object",
+	"float":    "number",
+	"number":   "number",
+	"date":     "string",
+	"datetime": "string",
+}
+
+// We do not use type=path for Output because we want a relative path.
+
+// SchemaCommand describes parameters for the schema command.
+type SchemaCommand struct {
+	Documentation
+
+	Group  bool   `            help:"Generate the schema for group configuration instead of project configuration."`
+	Output string `default:"-" help:"Where to save the schema to. Can be \"-\" for stdout. Default is \"${default}\"." placeholder:"PATH" short:"o"`
+}
+
+// Run runs the schema command.
+func (c *SchemaCommand) Run(_ *Globals) errors.E {
+	resources := Resources()
+	if c.Group {
+		resources = GroupResources()
+	}
+
+	ctx, stop := c.newContext()
+	defer stop()
+
+	schema, errE := configurationSchema(ctx, resources, c.docs())
+	if errE != nil {
+		return errE
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, "cannot marshal schema")
+	}
+	data = append(data, '\n')
+
+	if c.Output != "-" {
+		err = os.WriteFile(kong.ExpandPath(c.Output), data, fileMode)
+	} else {
+		_, err = os.Stdout.Write(data)
+	}
+	if err != nil {
+		return errors.WithMessage(err, "cannot write schema")
+	}
+
+	return nil
+}
+
+// configurationSchema returns JSON Schema for the configuration file with
+// configuration sections of resources, using descriptions of their fields
+// as extracted from GitLab's documentation obtained from docs.
+//
+// The schema does not disallow additional properties because configuration files
+// contain comments, fields renamed for encryption with SOPS, and SOPS metadata.
+func configurationSchema(ctx context.Context, resources []Resource, docs Docs) (map[string]interface{}, errors.E) {
+	properties := map[string]interface{}{}
+	names := []string{}
+	for _, resource := range resources {
+		names = append(names, resource.Name())
+		schema, errE := sectionSchema(ctx, resource, docs)
+		if errE != nil {
+			errors.Details(errE)["section"] = resource.Name()
+			return nil, errE
+		}
+		properties[resource.Name()] = schema
+	}
+
+	properties[pruneKey] = map[string]interface{}{
+		"description": "Set a configuration section to false to not delete objects which exist in GitLab but are missing from it.",
+		"type":        []string{"object", "null"},
+		"propertyNames": map[string]interface{}{
+			"enum": names,
+		},
+		"additionalProperties": map[string]interface{}{
+			"type": "boolean",
+		},
+	}
+
+	properties[ignoreKey] = map[string]interface{}{
+		"description": "Rules matching objects which are not managed. Each rule maps field names to glob patterns or to regular expressions between slashes.",
+		"type":        []string{"object", "null"},
+		"propertyNames": map[string]interface{}{
+			"enum": names,
+		},
+		"additionalProperties": map[string]interface{}{
+			"type": []string{"array", "null"},
+			"items": map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type": "string",
+				},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"$schema":    schemaDialect,
+		"title":      "GitLab configuration",
+		"type":       "object",
+		"properties": properties,
+	}, nil
+}
+
+// sectionSchema returns JSON Schema for the configuration section of the resource.
+//
+// The shape of the configuration section is determined from the type of the
+// corresponding Configuration field. Configuration sections stored in Extra
+// can be an object or a list of objects.
+func sectionSchema(ctx context.Context, resource Resource, docs Docs) (map[string]interface{}, errors.E) {
+	descriptions, errE := resource.Descriptions(ctx, docs)
+	if errE != nil {
+		return nil, errE
+	}
+
+	var required []string
+	if r, ok := resource.(RequiredResource); ok {
+		required, errE = r.Required(ctx, docs)
+		if errE != nil {
+			return nil, errE
+		}
+	}
+
+	object := objectSchema(descriptions, required)
+
+	switch sectionType(resource.Name()) {
+	case reflect.TypeFor[map[string]interface{}]():
+		object["type"] = []string{"object", "null"}
+		return object, nil
+	case reflect.TypeFor[[]map[string]interface{}]():
+		return map[string]interface{}{
+			"type":  []string{"array", "null"},
+			"items": object,
+		}, nil
+	case reflect.TypeFor[*string]():
+		return map[string]interface{}{
+			"type": []string{"string", "null"},
+		}, nil
+	case reflect.TypeFor[interface{}]():
+		// An ID or a full path.
+		return map[string]interface{}{
+			"type": []string{"integer", "string", "null"},
+		}, nil
+	case nil:
+		if descriptions == nil {
+			return map[string]interface{}{}, nil
+		}
+		object["type"] = "object"
+		return map[string]interface{}{
+			"anyOf": []interface{}{
+				object,
+				map[string]interface{}{
+					"type":  "array",
+					"items": object,
+				},
+				map[string]interface{}{
+					"type": "null",
+				},
+			},
+		}, nil
+	}
+
+	errE = errors.New("unsupported configuration section type")
+	errors.Details(errE)["type"] = sectionType(resource.Name()).String()
+	return nil, errE
+}
+
+// sectionType returns the type of the Configuration field for the configuration
+// section with the name, or nil if the configuration section is stored in Extra.
+func sectionType(name string) reflect.Type {
+	t := reflect.TypeFor[Configuration]()
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == name && tag != "" {
+			return t.Field(i).Type
+		}
+	}
+	return nil
+}
+
+// objectSchema returns JSON Schema for an object with fields described by descriptions.
+// Required fields which are not among descriptions are ignored.
+func objectSchema(descriptions map[string]string, required []string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for field, description := range descriptions {
+		properties[field] = fieldSchema(description, slices.Contains(required, field))
+	}
+
+	object := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	r := []string{}
+	for _, field := range required {
+		if _, ok := descriptions[field]; ok {
+			r = append(r, field)
+		}
+	}
+	if len(r) > 0 {
+		object["required"] = r
+	}
+
+	return object
+}
+
+// fieldSchema returns JSON Schema for a field with the description as
+// extracted from GitLab's documentation, ending with "Type: <type>".
+//
+// Types which cannot be mapped to JSON Schema types are not constrained.
+// Fields which are not required can also be null.
+func fieldSchema(description string, required bool) map[string]interface{} {
+	schema := map[string]interface{}{}
+
+	text, types := descriptionTypes(description)
+	if text != "" {
+		schema["description"] = text
+	}
+	if len(types) == 0 {
+		return schema
+	}
+	if !required {
+		types = append(types, "null")
+	}
+	if len(types) == 1 {
+		schema["type"] = types[0]
+	} else {
+		schema["type"] = types
+	}
+
+	return schema
+}
+
+// descriptionTypes splits the description as extracted from GitLab's documentation,
+// ending with "Type: <type>", into the text and JSON Schema types.
+//
+// It returns nil types if any of the types cannot be mapped to a JSON Schema type.
+func descriptionTypes(description string) (string, []string) {
+	i := strings.LastIndex(description, "Type: ")
+	if i < 0 {
+		return strings.TrimSpace(description), nil
+	}
+
+	types := []string{}
+	docsTypes := strings.ReplaceAll(strings.ToLower(description[i+len("Type: "):]), " or ", ",")
+	for _, t := range strings.FieldsFunc(docsTypes, func(r rune) bool { return r == ',' || r == '/' }) {
+		t = strings.TrimSpace(t)
+		if strings.HasSuffix(t, " array") || strings.HasPrefix(t, "array of") {
+			t = "array"
+		}
+		schemaType, ok := schemaTypes[t]
+		if !ok {
+			// We do not constrain the type if any of the types is unknown.
+			return strings.TrimSpace(description[:i]), nil
+		}
+		if !slices.Contains(types, schemaType) {
+			types = append(types, schemaType)
+		}
+	}
+	if len(types) == 0 {
+		types = nil
+	}
+
+	return strings.TrimSpace(description[:i]), types
+}
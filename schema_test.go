@@ -0,0 +1,93 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequired(t *testing.T) {
+	t.Parallel()
+
+	required, errE := parseRequired(testLabels, "Create a new label", nil)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []string{"color", "name"}, required)
+
+	// Fields required only under some condition are not required.
+	required, errE = parseRequired(testLabels, "Edit an existing label", nil)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []string{"label_id"}, required)
+}
+
+func TestFieldSchema(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		description string
+		required    bool
+		schema      map[string]interface{}
+	}{
+		{"The name of the label. Type: string", true, map[string]interface{}{"description": "The name of the label.", "type": "string"}},
+		{"The name of the label. Type: string", false, map[string]interface{}{"description": "The name of the label.", "type": []string{"string", "null"}}},
+		{"The ID. Type: integer or string", true, map[string]interface{}{"description": "The ID.", "type": []string{"integer", "string"}}},
+		{"Type: integer/string", true, map[string]interface{}{"type": []string{"integer", "string"}}},
+		{"User IDs. Type: integer array", true, map[string]interface{}{"description": "User IDs.", "type": "array"}},
+		{"Settings. Type: hash", true, map[string]interface{}{"description": "Settings.", "type": "object"}},
+		{"Anything. Type: mixed", true, map[string]interface{}{"description": "Anything."}},
+		{"Without a type.", true, map[string]interface{}{"description": "Without a type."}},
+	} {
+		t.Run(tt.description, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.schema, fieldSchema(tt.description, tt.required))
+		})
+	}
+}
+
+func TestConfigurationSchema(t *testing.T) {
+	t.Parallel()
+
+	resources := []Resource{
+		labelsResource{},
+		avatarResource{},
+		testResource{name: "test", descriptions: map[string]string{"foo": "Foo. Type: boolean"}},
+		testResource{name: "test2", descriptions: nil},
+	}
+
+	schema, errE := configurationSchema(t.Context(), resources, Docs{Ref: DefaultDocsRef, Dir: "", CacheDir: ""})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, schemaDialect, schema["$schema"])
+	assert.Equal(t, "object", schema["type"])
+
+	properties, ok := schema["properties"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Len(t, properties, 6)
+	assert.Contains(t, properties, "prune")
+	assert.Contains(t, properties, "ignore")
+
+	labels, ok := properties["labels"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, []string{"array", "null"}, labels["type"])
+	items, ok := labels["items"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, []string{"color", "name"}, items["required"])
+	assert.Contains(t, items["properties"], "priority")
+
+	assert.Equal(t, map[string]interface{}{"type": []string{"string", "null"}}, properties["avatar"])
+
+	object := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"foo": map[string]interface{}{"description": "Foo.", "type": []string{"boolean", "null"}},
+		},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"anyOf": []interface{}{
+			object,
+			map[string]interface{}{"type": "array", "items": object},
+			map[string]interface{}{"type": "null"},
+		},
+	}, properties["test"])
+	assert.Equal(t, map[string]interface{}{}, properties["test2"])
+}
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/tozd/sops/v3"
@@ -22,15 +26,57 @@ import (
 //nolint:lll
 type SetCommand struct {
 	GitLab
+	Sections
 
-	Input     string `default:".gitlab-conf.yml" help:"Where to load the configuration from. Can be \"-\" for stdin. Default is \"${default}\"." placeholder:"PATH" short:"i"`
-	EncSuffix string `                           help:"Remove the suffix from field names before calling APIs. Disabled by default."                                short:"S"`
-	NoDecrypt bool   `                           help:"Do not attempt to decrypt the configuration."`
+	Input        string        `default:".gitlab-conf.yml"                  help:"Where to load the configuration from. Can be \"-\" for stdin. Default is \"${default}\"."                                                                                                         placeholder:"PATH"     short:"i"`
+	EncSuffix    string        `                                            help:"Remove the suffix from field names before calling APIs. Disabled by default."                                                                                                                                            short:"S"`
+	NoDecrypt    bool          `                                            help:"Do not attempt to decrypt the configuration."`
+	Projects     string        `                                            help:"Update configurations of multiple projects instead. PATH is a manifest file or a directory with <namespace>/<project_path>.yml files."                                                            placeholder:"PATH"     short:"P"`
+	Snapshot     string        `                                            help:"Where to save configuration as it is before any changes, for recovery. With projects, it is saved next to each configuration file instead. It can contain sensitive values. Disabled by default." placeholder:"PATH"`
+	Rollback     bool          `                                            help:"If updating fails, reapply the snapshot to configuration sections which have already been updated."`
+	NoPrune      bool          `                                            help:"Do not delete objects which exist in GitLab but are missing from the configuration."`
+	RotateBefore time.Duration `default:"168h"                              help:"Rotate tokens with expires_in which expire sooner than this. Default is ${default}."                                                                                                              placeholder:"DURATION"`
+	TokensOutput string        `                                            help:"Where to save values of new tokens instead of into the configuration file. With projects, it is saved next to each configuration file."                                                           placeholder:"PATH"`
+	Workers      int           `default:"4"                                 help:"Maximum number of concurrent requests to GitLab API when taking the snapshot. Default is ${default}."                                                                                             placeholder:"INT"`
+	OutputFormat string        `default:"text"             enum:"text,json" help:"Format of progress and results. With json, events are written to stdout. Possible: ${enum}. Default is \"${default}\"."                                                                           placeholder:"FORMAT"`
 }
 
 // Run runs the set command.
 func (c *SetCommand) Run(_ *Globals) errors.E {
-	if c.Project == "" {
+	if c.Group != "" && c.Projects != "" {
+		return errors.New("group cannot be used with projects")
+	}
+	if c.Rollback && c.Snapshot == "" {
+		return errors.New("rollback requires a snapshot")
+	}
+	if c.Workers < 1 {
+		errE := errors.New("invalid number of workers")
+		errors.Details(errE)["workers"] = c.Workers
+		return errE
+	}
+
+	var events *eventWriter
+	if c.OutputFormat == "json" {
+		events = &eventWriter{w: os.Stdout} //nolint:exhaustruct
+	}
+	errE := c.setup(c.Workers, events)
+	if errE != nil {
+		return errE
+	}
+
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	errE = c.run(ctx)
+	if errE != nil {
+		c.events.error(c.Project, c.Group, errE)
+	}
+	return errE
+}
+
+// run runs the set command after c has been set up.
+func (c *SetCommand) run(ctx context.Context) errors.E {
+	if c.Project == "" && c.Group == "" && c.Projects == "" {
 		projectID, errE := x.InferGitLabProjectID(".")
 		if errE != nil {
 			return errE
@@ -38,115 +84,352 @@ func (c *SetCommand) Run(_ *Globals) errors.E {
 		c.Project = projectID
 	}
 
-	var input []byte
-	var err error
-	if c.Input != "-" {
-		input, err = os.ReadFile(kong.ExpandPath(c.Input))
-	} else {
-		input, err = io.ReadAll(os.Stdin)
+	// We check selected configuration sections before updating any project.
+	_, errE := c.resources(c.registeredResources())
+	if errE != nil {
+		return errE
 	}
-	if err != nil {
-		errE := errors.WithMessage(err, "cannot read configuration")
-		errors.Details(errE)["path"] = c.Input
+
+	if c.Projects != "" {
+		return c.runProjects(ctx)
+	}
+
+	errE = c.load(ctx)
+	if errE != nil {
 		return errE
 	}
 
-	if !c.NoDecrypt {
-		decryptedInput, err := decrypt.Data(input, "yaml") //nolint:govet
-		if err == nil {
-			input = decryptedInput
-		} else if !errors.Is(err, sops.MetadataNotFound) {
-			var userErr sops.UserError
-			if errors.As(err, &userErr) {
-				err = errors.Errorf("%w\n\n%s", err, userErr.UserError())
+	fmt.Fprintf(os.Stderr, "Updated everything.\n")
+
+	return nil
+}
+
+// runProjects updates configurations of all projects listed in c.Projects,
+// continuing with other projects if updating configuration of a project fails.
+func (c *SetCommand) runProjects(ctx context.Context) errors.E {
+	projects, errE := readProjects(c.Projects)
+	if errE != nil {
+		return errE
+	}
+
+	failed := []string{}
+	skipped := []string{}
+	for i, project := range projects {
+		if ctx.Err() != nil {
+			for _, p := range projects[i:] {
+				skipped = append(skipped, p.Project)
 			}
-			errE := errors.WithMessage(err, "cannot decrypt configuration")
-			errors.Details(errE)["path"] = c.Input
-			return errE
+			break
+		}
+
+		command := *c
+		command.Project = project.Project
+		command.Input = project.File
+		if c.Snapshot != "" {
+			command.Snapshot = snapshotPath(project.File)
+		}
+		if c.TokensOutput != "" {
+			command.TokensOutput = tokensPath(project.File)
+		}
+
+		fmt.Fprintf(os.Stderr, "Updating project %s...\n", project.Project)
+		errE := command.load(ctx)
+		if errE != nil {
+			fmt.Fprintf(os.Stderr, "Project %s failed: %s\n", project.Project, errE.Error())
+			c.events.error(project.Project, "", errE)
+			failed = append(failed, project.Project)
+			continue
 		}
+		fmt.Fprintf(os.Stderr, "Project %s done.\n", project.Project)
 	}
 
-	var configuration Configuration
-	err = yaml.Unmarshal(input, &configuration)
-	if err != nil {
-		errE := errors.WithMessage(err, "cannot unmarshal configuration")
-		errors.Details(errE)["path"] = c.Input
+	fmt.Fprintf(os.Stderr, "Updated %d projects, %d failed, %d skipped.\n", len(projects)-len(failed)-len(skipped), len(failed), len(skipped))
+
+	if len(failed) > 0 || len(skipped) > 0 {
+		errE := errors.New("failed to update some projects")
+		errors.Details(errE)["failed"] = failed
+		if len(skipped) > 0 {
+			errors.Details(errE)["skipped"] = skipped
+		}
 		return errE
 	}
+	return nil
+}
 
-	// We use reflect to go over all struct's fields so we do not have to
-	// change this code as Configuration struct evolves.
-	v := reflect.ValueOf(configuration)
-	for i := range v.NumField() {
-		removeFieldSuffix(v.Field(i), c.EncSuffix)
-	}
+// snapshotPath returns the path of the snapshot for the configuration file.
+func snapshotPath(file string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + ".snapshot" + ext
+}
 
-	client, err := gitlab.NewClient(c.Token, gitlab.WithBaseURL(c.BaseURL))
-	if err != nil {
-		return errors.WithMessage(err, "failed to create GitLab API client instance")
+// tokensPath returns the path of the file with values of new tokens for the configuration file.
+func tokensPath(file string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + ".tokens" + ext
+}
+
+// options returns Options for Apply.
+func (c *SetCommand) options() Options {
+	return Options{
+		Group:            c.Group != "",
+		Only:             c.Only,
+		Skip:             c.Skip,
+		Docs:             c.docs(),
+		IDs:              false,
+		AccessLevelNames: false,
+		Avatar:           "",
+		Workers:          c.Workers,
+		Snapshot:         c.Snapshot,
+		Rollback:         c.Rollback,
+		NoPrune:          c.NoPrune,
+		RotateBefore:     c.RotateBefore,
+		TokensOutput:     c.TokensOutput,
+		Input:            c.Input,
+		EncSuffix:        c.EncSuffix,
+		Ignore:           nil,
+		Comments:         false,
+		Logger:           c.logger,
 	}
+}
 
-	errE := c.updateProject(client, &configuration)
+// load reads the configuration from c.Input and updates GitLab project's
+// configuration based on it.
+func (c *SetCommand) load(ctx context.Context) errors.E {
+	configuration, errE := readConfiguration(c.Input, c.NoDecrypt, c.EncSuffix)
 	if errE != nil {
 		return errE
 	}
 
-	errE = c.updateAvatar(client, &configuration)
-	if errE != nil {
-		return errE
+	_, errE = Apply(ctx, c.client, c.owner(), configuration, c.options())
+	return errE
+}
+
+// applyConfiguration updates configuration of the target for resources based on
+// the configuration struct.
+//
+// Before making any changes, it takes a snapshot of the configuration (if
+// opts.Snapshot is set). If updating a resource fails and opts.Rollback is true,
+// the snapshot is reapplied to all resources updated so far, including the failed one.
+//
+// If ctx is canceled (e.g., on interrupt), the current request is aborted and no
+// further resources are updated nor rolled back. Names of configuration sections
+// which were already applied are added to error details.
+func applyConfiguration(ctx context.Context, client *gitlab.Client, target Target, resources []Resource, configuration *Configuration, opts Options) (Result, errors.E) {
+	result := Result{
+		Applied:    []string{},
+		RolledBack: []string{},
 	}
 
-	errE = c.updateSharedWithGroups(client, &configuration)
+	snapshot, errE := takeSnapshot(ctx, client, target, resources, configuration, opts)
 	if errE != nil {
-		return errE
+		return result, errE
 	}
 
-	errE = c.updateForkedFromProject(client, &configuration)
-	if errE != nil {
-		return errE
+	setter := opts.setter(target)
+	for i, resource := range resources {
+		errE = update(ctx, client, setter, resource, configuration)
+		if errE != nil {
+			errors.Details(errE)["applied"] = append([]string{}, result.Applied...)
+			if ctx.Err() != nil {
+				target.Printf("Stopped before updating everything. Applied: %s.\n", strings.Join(result.Applied, ", "))
+				return result, errE
+			}
+			if opts.Rollback {
+				result.RolledBack, errE = rollback(ctx, client, setter, resources[:i+1], snapshot, errE)
+			}
+			return result, errE
+		}
+		result.Applied = append(result.Applied, resource.Name())
 	}
 
-	errE = c.updateApprovals(client, &configuration)
+	return result, nil
+}
+
+// resolve returns a copy of the configuration in which usernames and full paths
+// in the configuration section of the resource are replaced with IDs.
+// It returns the configuration as-is if the resource is not a ReferencingResource.
+func resolve(ctx context.Context, client *gitlab.Client, target *Target, resource Resource, configuration *Configuration) (*Configuration, errors.E) {
+	r, ok := resource.(ReferencingResource)
+	if !ok {
+		return configuration, nil
+	}
+	configuration, errE := r.Resolve(ctx, target, client, configuration)
 	if errE != nil {
-		return errE
+		errors.Details(errE)["section"] = resource.Name()
+		return nil, errE
 	}
+	return configuration, nil
+}
 
-	errE = c.updateApprovalRules(client, &configuration)
+// update updates GitLab project's configuration for the resource
+// based on the configuration struct.
+//
+// Objects matching ignore rules are first removed from the configuration section
+// and then usernames and full paths in it are resolved to IDs.
+func update(ctx context.Context, client *gitlab.Client, s *Setter, resource Resource, configuration *Configuration) errors.E {
+	ctx = s.withResource(ctx, resource.Name())
+	configuration, errE := configuration.withoutIgnored(resource.Name())
 	if errE != nil {
+		errors.Details(errE)["section"] = resource.Name()
 		return errE
 	}
-
-	errE = c.updatePushRules(client, &configuration)
+	configuration, errE = resolve(ctx, client, &s.Target, resource, configuration)
 	if errE != nil {
 		return errE
 	}
+	errE = resource.Update(ctx, s, client, configuration)
+	if errE != nil {
+		errors.Details(errE)["section"] = resource.Name()
+	}
+	return errE
+}
+
+// takeSnapshot fetches GitLab project's configuration for resources and saves it to opts.Snapshot.
+// It returns the configuration as it would be read back from the saved file.
+//
+// Objects matching ignore rules of the wanted configuration are not included in the snapshot,
+// so rolling back does not change them either.
+//
+// It returns nil if opts.Snapshot is empty.
+func takeSnapshot(ctx context.Context, client *gitlab.Client, target Target, resources []Resource, wanted *Configuration, opts Options) (*Configuration, errors.E) {
+	path := opts.Snapshot
+	if path == "" {
+		return nil, nil //nolint:nilnil
+	}
 
-	errE = c.updateLabels(client, &configuration)
+	target.Printf("Taking snapshot...\n")
+
+	getter := &Getter{
+		Target: target,
+		// IDs are stored so that the snapshot can be reapplied without resolving names.
+		IDs:              true,
+		AccessLevelNames: false,
+		Avatar:           strings.TrimSuffix(path, filepath.Ext(path)) + ".avatar.img",
+		Workers:          opts.workers(),
+	}
+
+	live, errE := getConfiguration(ctx, client, getter, resources, wanted.Ignore)
 	if errE != nil {
-		return errE
+		return nil, errors.WithMessage(errE, "failed to take snapshot")
 	}
+	live.Ignore = wanted.Ignore
+	// Sensitive values are marked so that the snapshot can be encrypted with SOPS.
+	hasSensitive := annotateSections(live, resources, "sops:enc", "")
 
-	errE = c.updateProtectedBranches(client, &configuration)
+	data, errE := toConfigurationYAML(live, excludedSections(resources))
+	if errE == nil {
+		errE = writeConfiguration(path, data)
+	}
 	if errE != nil {
-		return errE
+		return nil, errors.WithMessage(errE, "failed to take snapshot")
 	}
 
-	errE = c.updateProtectedTags(client, &configuration)
+	// We read the snapshot back so that it is exactly what would be applied from the file.
+	snapshot, errE := readConfiguration(path, true, "")
 	if errE != nil {
-		return errE
+		return nil, errors.WithMessage(errE, "failed to take snapshot")
+	}
+
+	target.Printf("Saved snapshot to %s.\n", path)
+	if hasSensitive {
+		target.Printf("WARNING: Snapshot includes sensitive values. Consider removing it when not needed anymore.\n")
 	}
 
-	errE = c.updateVariables(client, &configuration)
+	return snapshot, nil
+}
+
+// rollback reapplies the snapshot to resources after updating failed with errE.
+//
+// It returns names of configuration sections which were rolled back and errE
+// with those names added to its details, joined with the rollback error
+// if rolling back failed, too.
+func rollback(ctx context.Context, client *gitlab.Client, s *Setter, resources []Resource, snapshot *Configuration, errE errors.E) ([]string, errors.E) {
+	s.Printf("Update failed, rolling back...\n")
+
+	rolledBack := []string{}
+	for _, resource := range resources {
+		rollbackErrE := update(ctx, client, s, resource, snapshot)
+		if rollbackErrE != nil {
+			errors.Details(errE)["rolledBack"] = rolledBack
+			rollbackErrE = errors.WithMessage(rollbackErrE, "rollback failed")
+			errors.Details(rollbackErrE)["section"] = resource.Name()
+			return rolledBack, errors.Join(errE, rollbackErrE)
+		}
+		rolledBack = append(rolledBack, resource.Name())
+	}
+
+	s.Printf("Rolled back: %s.\n", strings.Join(rolledBack, ", "))
+
+	errors.Details(errE)["rolledBack"] = rolledBack
+	return rolledBack, errE
+}
+
+// readConfiguration reads configuration from the input file (or stdin if input is "-"),
+// decrypts it (unless noDecrypt is true), merges base files it extends,
+// and removes encSuffix from field names.
+func readConfiguration(input string, noDecrypt bool, encSuffix string) (*Configuration, errors.E) {
+	data, errE := readConfigurationData(input, noDecrypt)
 	if errE != nil {
-		return errE
+		return nil, errE
 	}
 
-	errE = c.updatePipelineSchedules(client, &configuration)
+	data, errE = resolveExtends(input, data, noDecrypt)
 	if errE != nil {
-		return errE
+		return nil, errE
 	}
 
-	fmt.Fprintf(os.Stderr, "Updated everything.\n")
+	var configuration Configuration
+	err := yaml.Unmarshal(data, &configuration)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot unmarshal configuration")
+		errors.Details(errE)["path"] = input
+		return nil, errE
+	}
 
-	return nil
+	configuration.takeSourceDirs()
+
+	// We use reflect to go over all struct's fields so we do not have to
+	// change this code as Configuration struct evolves.
+	v := reflect.ValueOf(configuration)
+	for i := range v.NumField() {
+		if v.Type().Field(i).IsExported() {
+			removeFieldSuffix(v.Field(i).Interface(), encSuffix)
+		}
+	}
+
+	return &configuration, nil
+}
+
+// readConfigurationData reads configuration data from the input file (or stdin
+// if input is "-") and decrypts it (unless noDecrypt is true).
+func readConfigurationData(input string, noDecrypt bool) ([]byte, errors.E) {
+	var data []byte
+	var err error
+	if input != "-" {
+		data, err = os.ReadFile(kong.ExpandPath(input))
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot read configuration")
+		errors.Details(errE)["path"] = input
+		return nil, errE
+	}
+
+	if !noDecrypt {
+		decryptedInput, err := decrypt.Data(data, "yaml") //nolint:govet
+		if err == nil {
+			data = decryptedInput
+		} else if !errors.Is(err, sops.MetadataNotFound) {
+			var userErr sops.UserError
+			if errors.As(err, &userErr) {
+				err = errors.Errorf("%w\n\n%s", err, userErr.UserError())
+			}
+			errE := errors.WithMessage(err, "cannot decrypt configuration")
+			errors.Details(errE)["path"] = input
+			return nil, errE
+		}
+	}
+
+	return data, nil
 }
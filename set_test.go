@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xanzy/go-gitlab"
+	"gitlab.com/tozd/go/errors"
+)
+
+type testResource struct {
+	name         string
+	descriptions map[string]string
+	fail         bool
+	updated      *[]string
+}
+
+func (r testResource) Name() string {
+	return r.name
+}
+
+func (r testResource) Descriptions(context.Context, Docs) (map[string]string, errors.E) {
+	return r.descriptions, nil
+}
+
+func (testResource) Sensitive() []string {
+	return nil
+}
+
+func (testResource) Get(_ context.Context, _ *Getter, _ *gitlab.Client, _ *Configuration) errors.E {
+	return nil
+}
+
+func (r testResource) Update(_ context.Context, _ *Setter, _ *gitlab.Client, configuration *Configuration) errors.E {
+	*r.updated = append(*r.updated, r.name+"="+configuration.Section(r.name).(string)) //nolint:forcetypeassert,errcheck
+	if r.fail {
+		return errors.New("update failed")
+	}
+	return nil
+}
+
+func TestSnapshotPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, ".gitlab-conf.snapshot.yml", snapshotPath(".gitlab-conf.yml"))
+	assert.Equal(t, "projects/group/one.snapshot.yaml", snapshotPath("projects/group/one.yaml"))
+}
+
+func TestRollback(t *testing.T) {
+	t.Parallel()
+
+	updated := []string{}
+	snapshot := &Configuration{
+		Extra: map[string]interface{}{"one": "old", "two": "old"},
+	}
+	resources := []Resource{
+		testResource{name: "one", fail: false, updated: &updated},
+		testResource{name: "two", fail: false, updated: &updated},
+	}
+
+	s := &Setter{} //nolint:exhaustruct
+	rolledBack, errE := rollback(t.Context(), nil, s, resources, snapshot, errors.New("update failed"))
+	assert.EqualError(t, errE, "update failed")
+	assert.Equal(t, []string{"one", "two"}, rolledBack)
+	assert.Equal(t, []string{"one", "two"}, errors.Details(errE)["rolledBack"])
+	assert.Equal(t, []string{"one=old", "two=old"}, updated)
+
+	updated = []string{}
+	resources[1] = testResource{name: "two", fail: true, updated: &updated}
+	rolledBack, errE = rollback(t.Context(), nil, s, resources, snapshot, errors.New("update failed"))
+	require.Error(t, errE)
+	assert.Equal(t, []string{"one"}, rolledBack)
+	assert.ErrorContains(t, errE, "rollback failed")
+	assert.Equal(t, []string{"one=old", "two=old"}, updated)
+}
@@ -1,9 +1,9 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"slices"
 
 	mapset "github.com/deckarep/golang-set/v2"
@@ -11,33 +11,84 @@ import (
 	"gitlab.com/tozd/go/errors"
 )
 
+// sharedWithGroupsResource is a Resource for sharing the project with groups.
+type sharedWithGroupsResource struct{}
+
+// Name implements Resource interface.
+func (sharedWithGroupsResource) Name() string {
+	return "shared_with_groups"
+}
+
+// Descriptions implements Resource interface.
+func (sharedWithGroupsResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getSharedWithGroupsDescriptions(ctx, docs)
+}
+
+// Sensitive implements Resource interface.
+func (sharedWithGroupsResource) Sensitive() []string {
+	return nil
+}
+
+// Keys implements KeyedResource interface.
+func (sharedWithGroupsResource) Keys() [][]string {
+	return [][]string{{"group_id"}, {"group"}}
+}
+
+// Describe implements KeyedResource interface.
+func (sharedWithGroupsResource) Describe() []string {
+	return nil
+}
+
+// Required implements RequiredResource interface.
+func (sharedWithGroupsResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getSharedWithGroupsRequired(ctx, docs)
+}
+
+// Get implements Resource interface.
+func (sharedWithGroupsResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getSharedWithGroups(ctx, client, configuration)
+}
+
+// Resolve implements ReferencingResource interface.
+func (sharedWithGroupsResource) Resolve(ctx context.Context, t *Target, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	return t.resolveSharedWithGroups(ctx, client, configuration)
+}
+
+// Update implements Resource interface.
+func (sharedWithGroupsResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateSharedWithGroups(ctx, client, configuration)
+}
+
 // getSharedWithGroups populates configuration struct with GitLab's project's sharing
 // with groups available from GitLab projects API endpoint.
-func (c *GetCommand) getSharedWithGroups(
-	_ *gitlab.Client, project map[string]interface{}, configuration *Configuration,
-) (bool, errors.E) { //nolint:unparam
-	fmt.Fprintf(os.Stderr, "Getting sharing with groups...\n")
+func (g *Getter) getSharedWithGroups(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
+	g.Printf("Getting sharing with groups...\n")
 
 	configuration.SharedWithGroups = []map[string]interface{}{}
 
-	shareDescriptions, err := getSharedWithGroupsDescriptions(c.DocsRef)
-	if err != nil {
-		return false, err
+	shareDescriptions, errE := getSharedWithGroupsDescriptions(ctx, g.Docs)
+	if errE != nil {
+		return errE
 	}
 	configuration.SharedWithGroupsComment = formatDescriptions(shareDescriptions)
 
+	project, errE := getProject(ctx, client, g.Project)
+	if errE != nil {
+		return errE
+	}
+
 	sharedWithGroups, ok := project["shared_with_groups"]
 	if ok && sharedWithGroups != nil {
 		sharedWithGroups, ok := sharedWithGroups.([]interface{})
 		if !ok {
-			return false, errors.New(`invalid "shared_with_groups"`)
+			return errors.New(`invalid "shared_with_groups"`)
 		}
 		for i, sharedWithGroup := range sharedWithGroups {
 			sharedWithGroup, ok := sharedWithGroup.(map[string]interface{})
 			if !ok {
 				errE := errors.New(`invalid "shared_with_groups"`)
 				errors.Details(errE)["index"] = i
-				return false, errE
+				return errE
 			}
 			groupFullPath := sharedWithGroup["group_full_path"]
 			// Rename because share API has a different key than get project API.
@@ -55,16 +106,25 @@ func (c *GetCommand) getSharedWithGroups(
 				}
 			}
 
-			// Add comment for the sequence item itself.
-			if groupFullPath != nil {
-				sharedWithGroup["comment:"] = groupFullPath
+			if g.AccessLevelNames {
+				accessLevelToName(sharedWithGroup, "group_access")
+			}
+
+			if g.IDs || groupFullPath == nil {
+				// Add comment for the sequence item itself.
+				if groupFullPath != nil {
+					sharedWithGroup["comment:"] = groupFullPath
+				}
+			} else {
+				delete(sharedWithGroup, "group_id")
+				sharedWithGroup["group"] = groupFullPath
 			}
 
 			configuration.SharedWithGroups = append(configuration.SharedWithGroups, sharedWithGroup)
 		}
 	}
 
-	return false, nil
+	return nil
 }
 
 // parseSharedWithGroupsDocumentation parses GitLab's documentation in Markdown for
@@ -76,12 +136,60 @@ func parseSharedWithGroupsDocumentation(input []byte) (map[string]string, errors
 
 // getSharedWithGroupsDescriptions obtains description of fields used to describe payload for
 // sharing a project with a group from GitLab's documentation for projects API endpoint.
-func getSharedWithGroupsDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/projects.md", gitRef))
+func getSharedWithGroupsDescriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, "projects.md")
 	if err != nil {
 		return nil, errors.WithMessage(err, `failed to get share project descriptions`)
 	}
-	return parseSharedWithGroupsDocumentation(data)
+	descriptions, errE := parseSharedWithGroupsDocumentation(data)
+	if errE != nil {
+		return nil, errE
+	}
+	// Groups can be referenced by their full paths instead of IDs.
+	descriptions["group"] = "The full path of the group to share with, instead of group_id. Type: string"
+	allowAccessLevelNames(descriptions, "group_access")
+	return descriptions, nil
+}
+
+// getSharedWithGroupsRequired obtains fields required to create an individual project sharing with a group
+// from GitLab's documentation for projects API endpoint.
+func getSharedWithGroupsRequired(ctx context.Context, docs Docs) ([]string, errors.E) {
+	data, err := docs.Get(ctx, "projects.md")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get share project required fields")
+	}
+	required, errE := parseRequired(data, "Share project with group", nil)
+	if errE != nil {
+		return nil, errE
+	}
+	// The group can be provided with the "group" field instead.
+	return slices.DeleteFunc(required, func(field string) bool { return field == "group_id" }), nil
+}
+
+// resolveSharedWithGroups returns a copy of the configuration struct in which full paths
+// of groups in "group" fields are replaced with IDs in "group_id" fields, and names
+// of access levels in "group_access" fields with their values.
+func (t *Target) resolveSharedWithGroups(ctx context.Context, client *gitlab.Client, configuration *Configuration) (*Configuration, errors.E) {
+	if configuration.SharedWithGroups == nil {
+		return configuration, nil
+	}
+
+	resolved := *configuration
+	resolved.SharedWithGroups, _ = deepCopy(configuration.SharedWithGroups).([]map[string]interface{})
+	for i, group := range resolved.SharedWithGroups {
+		errE := resolveField(ctx, client, group, "group", "group_id", t.names.groupID)
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+		errE = accessLevelFromName(group, "group_access")
+		if errE != nil {
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+	}
+
+	return &resolved, nil
 }
 
 // updateSharedWithGroups updates GitLab project's sharing with groups using GitLab project's
@@ -91,20 +199,24 @@ func getSharedWithGroupsDescriptions(gitRef string) (map[string]string, errors.E
 // and then updates or adds groups for which the project should be shared with.
 // When updating an existing group it briefly removes the group and readds it with
 // new configuration.
-func (c *SetCommand) updateSharedWithGroups(client *gitlab.Client, configuration *Configuration) errors.E {
+func (s *Setter) updateSharedWithGroups(ctx context.Context, client *gitlab.Client, configuration *Configuration) errors.E {
 	if configuration.SharedWithGroups == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating sharing with groups...\n")
+	s.Printf("Updating sharing with groups...\n")
 
-	project, _, err := client.Projects.GetProject(c.Project, nil)
+	project, _, err := client.Projects.GetProject(s.Project, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return errors.WithMessage(err, "failed to get project")
 	}
 
 	existingGroupsSet := mapset.NewThreadUnsafeSet[int]()
 	for _, group := range project.SharedWithGroups {
+		// Groups matching ignore rules are neither updated nor removed.
+		if configuration.ignored("shared_with_groups", map[string]interface{}{"group_id": group.GroupID, "group": group.GroupFullPath}) {
+			continue
+		}
 		existingGroupsSet.Add(group.GroupID)
 	}
 
@@ -128,9 +240,13 @@ func (c *SetCommand) updateSharedWithGroups(client *gitlab.Client, configuration
 	}
 
 	extraGroups := existingGroupsSet.Difference(wantedGroupsSet).ToSlice()
+	if !configuration.prune("shared_with_groups", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraGroups = nil
+	}
 	slices.Sort(extraGroups)
 	for _, groupID := range extraGroups {
-		_, err := client.Projects.DeleteSharedProjectFromGroup(c.Project, groupID)
+		_, err := client.Projects.DeleteSharedProjectFromGroup(s.Project, groupID, gitlab.WithContext(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to unshare group")
 			errors.Details(errE)["group"] = groupID
@@ -138,7 +254,7 @@ func (c *SetCommand) updateSharedWithGroups(client *gitlab.Client, configuration
 		}
 	}
 
-	u := fmt.Sprintf("projects/%s/share", gitlab.PathEscape(c.Project))
+	u := fmt.Sprintf("projects/%s/share", gitlab.PathEscape(s.Project))
 
 	for i, group := range configuration.SharedWithGroups { //nolint:dupl
 		// We checked that group id is int above.
@@ -147,7 +263,7 @@ func (c *SetCommand) updateSharedWithGroups(client *gitlab.Client, configuration
 		// If project is already shared with this group, we have to
 		// first unshare to be able to update the share.
 		if existingGroupsSet.Contains(groupID) {
-			_, err := client.Projects.DeleteSharedProjectFromGroup(c.Project, groupID)
+			_, err := client.Projects.DeleteSharedProjectFromGroup(s.Project, groupID, gitlab.WithContext(ctx))
 			if err != nil {
 				errE := errors.WithMessage(err, "failed to unshare group before resharing")
 				errors.Details(errE)["index"] = i
@@ -156,7 +272,7 @@ func (c *SetCommand) updateSharedWithGroups(client *gitlab.Client, configuration
 			}
 		}
 
-		req, err := client.NewRequest(http.MethodPost, u, group, nil)
+		req, err := client.NewRequest(http.MethodPost, u, group, contextOptions(ctx))
 		if err != nil {
 			errE := errors.WithMessage(err, "failed to share group")
 			errors.Details(errE)["index"] = i
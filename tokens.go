@@ -0,0 +1,382 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/alecthomas/kong"
+	mapset "github.com/deckarep/golang-set/v2"
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// tokenField is the field of a token with its value. GitLab reveals the value
+	// only when the token is created or rotated, so set saves it.
+	tokenField = "token"
+
+	// expiresInField is the field of a token with the number of days for which
+	// a new or rotated token is valid. It is used instead of "expires_at".
+	expiresInField = "expires_in"
+
+	// tokenDateFormat is the format of expiration dates of new tokens.
+	tokenDateFormat = "2006-01-02"
+)
+
+// tokenChange describes what has to be done with an existing token so that it matches
+// the token in the configuration.
+type tokenChange int
+
+const (
+	// tokenKeep means that the existing token matches.
+	tokenKeep tokenChange = iota
+	// tokenCreate means that there is no existing token and a new one has to be created.
+	tokenCreate
+	// tokenReplace means that fields of the existing token which cannot be changed
+	// do not match, so a new token has to be created and the existing one revoked.
+	tokenReplace
+	// tokenRotate means that the existing token is about to expire and has to be rotated.
+	tokenRotate
+)
+
+// existingToken describes a token as available from GitLab.
+type existingToken struct {
+	ID        int
+	Scopes    []string
+	ExpiresAt *time.Time
+
+	// Fields are other fields of the token which cannot be changed.
+	Fields map[string]interface{}
+}
+
+// addTokenDescriptions adds descriptions of fields of tokens which are not
+// in GitLab's documentation.
+func addTokenDescriptions(descriptions map[string]string) {
+	descriptions[tokenField] = "The token. GitLab reveals it only when the token is created or rotated and set saves it then. Type: string"
+	descriptions[expiresInField] = "Number of days for which a new or rotated token is valid, instead of expires_at. " +
+		"The token is rotated when it is about to expire. Type: integer"
+}
+
+// resolveTokens returns a copy of tokens in which "expires_at" fields are removed from
+// tokens with "expires_in" field, because the latter is used instead. If accessLevelField
+// is not empty, names of access levels in that field are replaced with their values.
+func resolveTokens(tokens []map[string]interface{}, accessLevelField string) ([]map[string]interface{}, errors.E) {
+	resolved, _ := deepCopy(tokens).([]map[string]interface{})
+	for i, token := range resolved {
+		expiresIn, ok := token[expiresInField]
+		if ok {
+			days, ok := expiresIn.(int)
+			if !ok || days < 1 {
+				errE := errors.Errorf(`token's field "%s" is not a positive integer`, expiresInField)
+				errors.Details(errE)["index"] = i
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", expiresIn)
+				errors.Details(errE)["value"] = expiresIn
+				return nil, errE
+			}
+			delete(token, "expires_at")
+		}
+		if accessLevelField != "" {
+			errE := accessLevelFromName(token, accessLevelField)
+			if errE != nil {
+				errors.Details(errE)["index"] = i
+				return nil, errE
+			}
+		}
+	}
+	return resolved, nil
+}
+
+// tokenName returns the name of the token.
+func tokenName(token map[string]interface{}, i int) (string, errors.E) {
+	name, ok := token["name"]
+	if !ok {
+		errE := errors.New(`token is missing field "name"`)
+		errors.Details(errE)["index"] = i
+		return "", errE
+	}
+	n, ok := name.(string)
+	if !ok {
+		errE := errors.New(`token's field "name" is not a string`)
+		errors.Details(errE)["index"] = i
+		errors.Details(errE)["type"] = fmt.Sprintf("%T", name)
+		errors.Details(errE)["value"] = name
+		return "", errE
+	}
+	return n, nil
+}
+
+// parseTokenTime parses the expiration date (or time) of a token.
+func parseTokenTime(value interface{}) (time.Time, errors.E) {
+	s, ok := value.(string)
+	if !ok {
+		errE := errors.New("expiration date is not a string")
+		errors.Details(errE)["type"] = fmt.Sprintf("%T", value)
+		errors.Details(errE)["value"] = value
+		return time.Time{}, errE
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+	t, err = time.Parse(tokenDateFormat, s)
+	if err != nil {
+		errE := errors.WithMessage(err, "invalid expiration date")
+		errors.Details(errE)["value"] = s
+		return time.Time{}, errE
+	}
+	return t, nil
+}
+
+// tokenExpiresAt returns the expiration date for a new token as configured by
+// "expires_in" or "expires_at" fields, relative to now. It returns nil if the
+// token does not expire.
+func tokenExpiresAt(token map[string]interface{}, now time.Time) interface{} {
+	// We checked in resolveTokens that expires_in is an int.
+	days, ok := token[expiresInField].(int)
+	if ok {
+		return now.AddDate(0, 0, days).Format(tokenDateFormat)
+	}
+	return token["expires_at"]
+}
+
+// tokenChange returns what has to be done with the existing token (nil if there is none)
+// so that it matches the wanted token at now.
+//
+// Only fields present in the wanted token are compared. A token with "expires_in" field
+// is rotated when it expires sooner than s.RotateBefore.
+func (s *Setter) tokenChange(existing *existingToken, wanted map[string]interface{}, now time.Time) (tokenChange, errors.E) {
+	if existing == nil {
+		return tokenCreate, nil
+	}
+
+	if scopes, ok := wanted["scopes"]; ok {
+		s, ok := scopes.([]interface{})
+		if !ok {
+			errE := errors.New(`token's field "scopes" is not an array`)
+			errors.Details(errE)["type"] = fmt.Sprintf("%T", scopes)
+			errors.Details(errE)["value"] = scopes
+			return tokenKeep, errE
+		}
+		wantedScopes := mapset.NewThreadUnsafeSet[string]()
+		for _, scope := range s {
+			ss, ok := scope.(string)
+			if !ok {
+				errE := errors.New(`token's field "scopes" contains a value which is not a string`)
+				errors.Details(errE)["type"] = fmt.Sprintf("%T", scope)
+				errors.Details(errE)["value"] = scope
+				return tokenKeep, errE
+			}
+			wantedScopes.Add(ss)
+		}
+		if !wantedScopes.Equal(mapset.NewThreadUnsafeSet(existing.Scopes...)) {
+			return tokenReplace, nil
+		}
+	}
+
+	for field, value := range existing.Fields {
+		w, ok := wanted[field]
+		if ok && !equalValues(w, value) {
+			return tokenReplace, nil
+		}
+	}
+
+	if _, ok := wanted[expiresInField]; ok {
+		if existing.ExpiresAt == nil || existing.ExpiresAt.Sub(now) < s.RotateBefore {
+			return tokenRotate, nil
+		}
+	} else if expiresAt, ok := wanted["expires_at"]; ok && expiresAt != nil {
+		t, errE := parseTokenTime(expiresAt)
+		if errE != nil {
+			return tokenKeep, errE
+		}
+		if existing.ExpiresAt == nil || !existing.ExpiresAt.UTC().Truncate(24*time.Hour).Equal(t.UTC().Truncate(24*time.Hour)) { //nolint:mnd
+			return tokenReplace, nil
+		}
+	}
+
+	return tokenKeep, nil
+}
+
+// newTokenFields returns fields of the wanted token to be sent to GitLab
+// when creating it, with the expiration date relative to now.
+func newTokenFields(wanted map[string]interface{}, now time.Time) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for field, value := range wanted {
+		if field != tokenField && field != expiresInField {
+			fields[field] = value
+		}
+	}
+	if expiresAt := tokenExpiresAt(wanted, now); expiresAt != nil {
+		fields["expires_at"] = expiresAt
+	}
+	return fields
+}
+
+// checkTokensOutput returns an error if values of new tokens with names in the configuration
+// section cannot be saved. It should be called before tokens are created or rotated because
+// GitLab reveals their values only once.
+func (s *Setter) checkTokensOutput(section string, names []string) errors.E {
+	if len(names) == 0 || s.TokensOutput != "" {
+		return nil
+	}
+	if s.Input == "" || s.Input == "-" {
+		errE := errors.New("values of new tokens cannot be saved, tokens output is required")
+		errors.Details(errE)["section"] = section
+		errors.Details(errE)["tokens"] = names
+		return errE
+	}
+	document, errE := s.readTokensConfiguration()
+	if errE != nil {
+		return errE
+	}
+	for _, name := range names {
+		_, errE := findToken(s.Input, document, section, name)
+		if errE != nil {
+			return errE
+		}
+	}
+	return nil
+}
+
+// readTokensConfiguration reads s.Input to save values of new tokens into it.
+// It returns an error if the configuration is encrypted because values
+// would end up not encrypted.
+func (s *Setter) readTokensConfiguration() (*yaml.Node, errors.E) {
+	data, err := os.ReadFile(kong.ExpandPath(s.Input))
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot read configuration")
+		errors.Details(errE)["path"] = s.Input
+		return nil, errE
+	}
+	document, errE := parseDocument(s.Input, data)
+	if errE != nil {
+		return nil, errE
+	}
+	if document == nil {
+		errE := errors.New("configuration is empty")
+		errors.Details(errE)["path"] = s.Input
+		return nil, errE
+	}
+	if mappingIndex(document, "sops") >= 0 {
+		errE := errors.New("values of new tokens cannot be saved into an encrypted configuration, tokens output is required")
+		errors.Details(errE)["path"] = s.Input
+		return nil, errE
+	}
+	return document, nil
+}
+
+// findToken returns the node of the token with the name in the configuration section
+// of the configuration document read from path. Tokens from base files which
+// the configuration extends are not found.
+func findToken(path string, document *yaml.Node, section, name string) (*yaml.Node, errors.E) {
+	tokens := mappingValue(document, section)
+	if tokens != nil && tokens.Kind == yaml.SequenceNode {
+		i := slices.IndexFunc(tokens.Content, func(token *yaml.Node) bool {
+			n := mappingValue(token, "name")
+			return n != nil && n.Kind == yaml.ScalarNode && n.Value == name
+		})
+		if i >= 0 {
+			return tokens.Content[i], nil
+		}
+	}
+	errE := errors.New("token not found in configuration to save its value, tokens output is required")
+	errors.Details(errE)["path"] = path
+	errors.Details(errE)["section"] = section
+	errors.Details(errE)["token"] = name
+	return nil, errE
+}
+
+// saveToken saves the value of the new token with the name in the configuration section.
+//
+// If s.TokensOutput is set, it is saved there. Otherwise it is saved into the token
+// field of the token in s.Input, marked for encryption with SOPS.
+func (s *Setter) saveToken(section, name, value string) errors.E {
+	if s.TokensOutput != "" {
+		return s.saveTokensOutput(section, name, value)
+	}
+
+	document, errE := s.readTokensConfiguration()
+	if errE != nil {
+		return errE
+	}
+	token, errE := findToken(s.Input, document, section, name)
+	if errE != nil {
+		return errE
+	}
+
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value} //nolint:exhaustruct
+	field := tokenField + s.EncSuffix
+	i := mappingIndex(token, field)
+	if i >= 0 {
+		token.Content[i+1] = valueNode
+	} else {
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field} //nolint:exhaustruct
+		if s.EncSuffix == "" {
+			keyNode.HeadComment = "sops:enc"
+		}
+		token.Content = append(token.Content, keyNode, valueNode)
+	}
+
+	data, errE := toYAML(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{document}}) //nolint:exhaustruct
+	if errE != nil {
+		return errE
+	}
+	err := os.WriteFile(kong.ExpandPath(s.Input), data, fileMode)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot write configuration")
+		errors.Details(errE)["path"] = s.Input
+		return errE
+	}
+	return nil
+}
+
+// saveTokensOutput saves the value of the new token with the name in the configuration
+// section to s.TokensOutput, which maps names of configuration sections to names of
+// tokens and their values. Values of other tokens already in the file are kept.
+func (s *Setter) saveTokensOutput(section, name, value string) errors.E {
+	tokens := map[string]map[string]string{}
+
+	data, err := os.ReadFile(kong.ExpandPath(s.TokensOutput))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		errE := errors.WithMessage(err, "cannot read tokens output")
+		errors.Details(errE)["path"] = s.TokensOutput
+		return errE
+	} else if err == nil {
+		err = yaml.Unmarshal(data, &tokens)
+		if err != nil {
+			errE := errors.WithMessage(err, "cannot unmarshal tokens output")
+			errors.Details(errE)["path"] = s.TokensOutput
+			return errE
+		}
+		if tokens == nil {
+			tokens = map[string]map[string]string{}
+		}
+	}
+
+	if tokens[section] == nil {
+		tokens[section] = map[string]string{}
+	}
+	tokens[section][name] = value
+
+	var node yaml.Node
+	err = node.Encode(tokens)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot marshal tokens output")
+		errors.Details(errE)["path"] = s.TokensOutput
+		return errE
+	}
+	data, errE := toYAML(&node)
+	if errE != nil {
+		errors.Details(errE)["path"] = s.TokensOutput
+		return errE
+	}
+	err = os.WriteFile(kong.ExpandPath(s.TokensOutput), data, fileMode)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot write tokens output")
+		errors.Details(errE)["path"] = s.TokensOutput
+		return errE
+	}
+	return nil
+}
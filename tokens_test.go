@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenChange(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	soon := now.Add(48 * time.Hour)
+	later := now.AddDate(0, 3, 0)
+	existing := &existingToken{
+		ID:        1,
+		Scopes:    []string{"read_repository", "read_registry"},
+		ExpiresAt: &later,
+		Fields:    map[string]interface{}{"access_level": 40},
+	}
+	expiring := &existingToken{
+		ID:        1,
+		Scopes:    []string{"read_repository"},
+		ExpiresAt: &soon,
+		Fields:    map[string]interface{}{},
+	}
+
+	s := &Setter{RotateBefore: 7 * 24 * time.Hour} //nolint:exhaustruct
+
+	for _, tt := range []struct {
+		Existing *existingToken
+		Wanted   map[string]interface{}
+		Change   tokenChange
+	}{
+		{nil, map[string]interface{}{"name": "ci"}, tokenCreate},
+		{existing, map[string]interface{}{"name": "ci"}, tokenKeep},
+		{existing, map[string]interface{}{"name": "ci", "scopes": []interface{}{"read_registry", "read_repository"}, "access_level": 40}, tokenKeep},
+		{existing, map[string]interface{}{"name": "ci", "scopes": []interface{}{"read_repository"}}, tokenReplace},
+		{existing, map[string]interface{}{"name": "ci", "access_level": 30}, tokenReplace},
+		{existing, map[string]interface{}{"name": "ci", "expires_at": later.Format(tokenDateFormat)}, tokenKeep},
+		{existing, map[string]interface{}{"name": "ci", "expires_at": later.Format(time.RFC3339)}, tokenKeep},
+		{existing, map[string]interface{}{"name": "ci", "expires_at": "2031-01-01"}, tokenReplace},
+		{existing, map[string]interface{}{"name": "ci", "expires_in": 90}, tokenKeep},
+		{expiring, map[string]interface{}{"name": "ci", "expires_in": 90}, tokenRotate},
+		{expiring, map[string]interface{}{"name": "ci", "expires_at": soon.Format(tokenDateFormat)}, tokenKeep},
+		{expiring, map[string]interface{}{"name": "ci", "scopes": []interface{}{"read_registry"}, "expires_in": 90}, tokenReplace},
+	} {
+		change, errE := s.tokenChange(tt.Existing, tt.Wanted, now)
+		require.NoError(t, errE, "% -+#.1v", errE)
+		assert.Equal(t, tt.Change, change, tt.Wanted)
+	}
+
+	_, errE := s.tokenChange(existing, map[string]interface{}{"name": "ci", "scopes": "read_repository"}, now)
+	assert.EqualError(t, errE, `token's field "scopes" is not an array`)
+}
+
+func TestResolveTokens(t *testing.T) {
+	t.Parallel()
+
+	tokens := []map[string]interface{}{
+		{"name": "ci", "expires_at": "2030-01-01", "expires_in": 30, "access_level": "developer"},
+		{"name": "bot", "expires_at": "2030-01-01"},
+	}
+	resolved, errE := resolveTokens(tokens, "access_level")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "ci", "expires_in": 30, "access_level": 30},
+		{"name": "bot", "expires_at": "2030-01-01"},
+	}, resolved)
+	// Tokens themselves are not modified.
+	assert.Equal(t, "developer", tokens[0]["access_level"])
+
+	_, errE = resolveTokens([]map[string]interface{}{{"name": "ci", "expires_in": "30d"}}, "")
+	assert.EqualError(t, errE, `token's field "expires_in" is not a positive integer`)
+
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, map[string]interface{}{"name": "ci", "scopes": []interface{}{"api"}, "expires_at": "2030-01-31"},
+		newTokenFields(map[string]interface{}{"name": "ci", "scopes": []interface{}{"api"}, "expires_in": 30, "token": "old"}, now))
+}
+
+func TestSaveToken(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(input, []byte(`# Deploy tokens.
+deploy_tokens:
+  # Used by CI.
+  - name: ci
+    scopes: [read_repository]
+  - name: registry
+    scopes: [read_registry]
+    token: old
+`), 0o600))
+
+	s := &Setter{Input: input} //nolint:exhaustruct
+
+	errE := s.checkTokensOutput("deploy_tokens", []string{"ci", "registry"})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	errE = s.checkTokensOutput("deploy_tokens", []string{"other"})
+	assert.EqualError(t, errE, "token not found in configuration to save its value, tokens output is required")
+	errE = s.checkTokensOutput("project_access_tokens", []string{"ci"})
+	assert.EqualError(t, errE, "token not found in configuration to save its value, tokens output is required")
+
+	errE = s.saveToken("deploy_tokens", "ci", "new-ci")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	errE = s.saveToken("deploy_tokens", "registry", "new-registry")
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	data, err := os.ReadFile(input)
+	require.NoError(t, err)
+	assert.Equal(t, `# Deploy tokens.
+deploy_tokens:
+  # Used by CI.
+  - name: ci
+    scopes: [read_repository]
+    # sops:enc
+    token: new-ci
+  - name: registry
+    scopes: [read_registry]
+    token: new-registry
+`, string(data))
+
+	output := filepath.Join(dir, "tokens.yml")
+	s = &Setter{Input: "-", TokensOutput: output} //nolint:exhaustruct
+
+	errE = s.checkTokensOutput("deploy_tokens", []string{"other"})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	errE = s.saveToken("deploy_tokens", "ci", "new-ci")
+	require.NoError(t, errE, "% -+#.1v", errE)
+	errE = s.saveToken("project_access_tokens", "bot", "new-bot")
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	data, err = os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy_tokens:\n  ci: new-ci\nproject_access_tokens:\n  bot: new-bot\n", string(data))
+
+	s = &Setter{Input: "-"} //nolint:exhaustruct
+	errE = s.checkTokensOutput("deploy_tokens", []string{"ci"})
+	assert.EqualError(t, errE, "values of new tokens cannot be saved, tokens output is required")
+}
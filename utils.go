@@ -2,8 +2,10 @@ package config
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/xanzy/go-gitlab"
@@ -15,28 +17,113 @@ const (
 	maxGitLabPageSize = 100
 )
 
+// limitedTransport is a http.RoundTripper which limits the number of concurrent requests.
+type limitedTransport struct {
+	transport http.RoundTripper
+	slots     chan struct{}
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, errors.WithStack(req.Context().Err())
+	}
+	defer func() { <-t.slots }()
+	return t.transport.RoundTrip(req)
+}
+
+// parallel calls fn for every index from 0 to n-1, using at most workers
+// concurrent goroutines. After the first error, no new calls are made.
+// If more calls fail, the error for the lowest index is returned so that
+// the result is deterministic.
+func parallel(workers, n int, fn func(i int) errors.E) errors.E {
+	errs := make([]errors.E, n)
+	indices := make(chan int)
+	failed := make(chan struct{})
+	var failedOnce sync.Once
+	var wg sync.WaitGroup
+
+	for range min(workers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				errs[i] = fn(i)
+				if errs[i] != nil {
+					failedOnce.Do(func() { close(failed) })
+				}
+			}
+		}()
+	}
+
+Loop:
+	for i := range n {
+		select {
+		case indices <- i:
+		case <-failed:
+			break Loop
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	for _, errE := range errs {
+		if errE != nil {
+			return errE
+		}
+	}
+	return nil
+}
+
+// contextOptions returns request options which make the request use ctx.
+func contextOptions(ctx context.Context) []gitlab.RequestOptionFunc {
+	return []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)}
+}
+
 // downloadFile downloads a file from url URL.
-func downloadFile(url string) ([]byte, errors.E) {
+func downloadFile(ctx context.Context, url string) ([]byte, errors.E) {
+	data, _, errE := downloadFileIfModified(ctx, url, "")
+	return data, errE
+}
+
+// downloadFileIfModified downloads a file from url URL unless its ETag matches etag.
+// It returns nil data if the file has not been modified, together with the file's
+// current ETag (if any).
+func downloadFileIfModified(ctx context.Context, url, etag string) ([]byte, string, errors.E) {
 	client, _ := gitlab.NewClient("")
 
-	req, err := retryablehttp.NewRequest(http.MethodGet, url, nil)
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, "", errors.WithStack(err)
 	}
 	if client.UserAgent != "" {
 		req.Header.Set("User-Agent", client.UserAgent)
 	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 
 	buffer := bytes.Buffer{}
 
 	// TODO: Handle errors better.
 	//       On error this tries to parse the error response as API error, which fails for arbitrary HTTP requests.
-	_, err = client.Do(req, &buffer)
+	response, err := client.Do(req, &buffer)
+	if etag != "" && response != nil && response.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, "", errors.WithStack(err)
+	}
+
+	data := buffer.Bytes()
+	if data == nil {
+		// Data is nil only when the file has not been modified.
+		data = []byte{}
 	}
 
-	return buffer.Bytes(), nil
+	return data, response.Header.Get("ETag"), nil
 }
 
 // renameAnyField renames field named "from" to "to" anywhere in the arbitrary input
@@ -211,3 +298,64 @@ func convertNestedObjectsToIDs(input interface{}) ([]interface{}, error) {
 
 	return ids, nil
 }
+
+// convertNestedObjectsToNames converts a slice of objects to a slice of
+// names from their fields named "field".
+func convertNestedObjectsToNames(input interface{}, field string) ([]interface{}, error) {
+	names := []interface{}{}
+
+	if input == nil {
+		return names, nil
+	}
+
+	list, ok := input.([]interface{})
+	if !ok {
+		return nil, errors.New("not a list")
+	}
+
+	for i, element := range list {
+		el, ok := element.(map[string]interface{})
+		if !ok {
+			errE := errors.New("not an object")
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+
+		name, ok := el[field].(string)
+		if !ok {
+			errE := errors.Errorf(`"%s" is missing or not a string`, field)
+			errors.Details(errE)["index"] = i
+			return nil, errE
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// deepCopy returns a copy of the arbitrary input structure
+// in which all nested maps and slices are copied as well.
+func deepCopy(input interface{}) interface{} {
+	switch in := input.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(in))
+		for i, v := range in {
+			out[i] = deepCopy(v)
+		}
+		return out
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(in))
+		for i, v := range in {
+			out[i], _ = deepCopy(v).(map[string]interface{})
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(in))
+		for key, value := range in {
+			out[key] = deepCopy(value)
+		}
+		return out
+	default:
+		return input
+	}
+}
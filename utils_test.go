@@ -1,10 +1,15 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.com/tozd/go/errors"
 )
 
 func TestRenameMapField(t *testing.T) {
@@ -243,3 +248,62 @@ func TestRemoveField(t *testing.T) {
 		})
 	}
 }
+
+func TestParallel(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning atomic.Int32
+	results := make([]int, 20)
+	errE := parallel(3, len(results), func(i int) errors.E {
+		r := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if r <= m || maxRunning.CompareAndSwap(m, r) {
+				break
+			}
+		}
+		results[i] = i * i
+		return nil
+	})
+	assert.NoError(t, errE)
+	assert.LessOrEqual(t, maxRunning.Load(), int32(3))
+	for i, r := range results {
+		assert.Equal(t, i*i, r)
+	}
+
+	errE = parallel(3, 0, func(_ int) errors.E {
+		return errors.New("called")
+	})
+	assert.NoError(t, errE)
+
+	errE = parallel(1, 10, func(i int) errors.E {
+		if i >= 2 {
+			errE := errors.New("failed")
+			errors.Details(errE)["index"] = i
+			return errE
+		}
+		return nil
+	})
+	assert.EqualError(t, errE, "failed")
+	assert.Equal(t, 2, errors.Details(errE)["index"])
+}
+
+func TestLimitedTransportCanceled(t *testing.T) {
+	t.Parallel()
+
+	transport := &limitedTransport{
+		transport: http.DefaultTransport,
+		slots:     make(chan struct{}, 1),
+	}
+	// The only slot is taken.
+	transport.slots <- struct{}{}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost", nil)
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req) //nolint:bodyclose
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,617 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"gitlab.com/tozd/go/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalid is returned by the validate command when the configuration
+// file has any issues.
+var ErrInvalid = errors.Base("invalid configuration")
+
+// maxSuggestionDistance is the maximum edit distance between an unknown
+// field and a known field for the known field to be suggested instead.
+const maxSuggestionDistance = 3
+
+// We do not use type=path for Input because we want a relative path.
+
+// ValidateCommand describes parameters for the validate command.
+//
+//nolint:lll
+type ValidateCommand struct {
+	Documentation
+
+	Input     string `default:".gitlab-conf.yml" help:"Where to load the configuration from. Can be \"-\" for stdin. Default is \"${default}\"." placeholder:"PATH" short:"i"`
+	EncSuffix string `                           help:"Remove the suffix from field names before validating. Disabled by default."                                   short:"S"`
+	NoDecrypt bool   `                           help:"Do not attempt to decrypt the configuration."`
+	Group     bool   `                           help:"Validate group configuration instead of project configuration."`
+}
+
+// validationIssue is an issue found in a configuration file.
+type validationIssue struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// String returns the issue in the "<path>:<line>:<column>: <message>" format.
+func (i validationIssue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", i.Path, i.Line, i.Column, i.Message)
+}
+
+// Run runs the validate command.
+func (c *ValidateCommand) Run(_ *Globals) errors.E {
+	resources := Resources()
+	if c.Group {
+		resources = GroupResources()
+	}
+
+	v := validator{
+		resources:    resources,
+		docs:         c.docs(),
+		encSuffix:    c.EncSuffix,
+		noDecrypt:    c.NoDecrypt,
+		descriptions: map[string]map[string]string{},
+		issues:       []validationIssue{},
+	}
+	ctx, stop := c.newContext()
+	defer stop()
+
+	errE := v.validateFile(ctx, c.Input, nil)
+	if errE != nil {
+		return errE
+	}
+
+	errE = writeIssues(os.Stdout, v.issues)
+	if errE != nil {
+		return errE
+	}
+
+	if len(v.issues) > 0 {
+		errE = errors.WithStack(ErrInvalid)
+		errors.Details(errE)["issues"] = len(v.issues)
+		return errE
+	}
+
+	fmt.Fprintf(os.Stderr, "Configuration is valid.\n")
+
+	return nil
+}
+
+// writeIssues writes issues sorted by their position, one per line, to w.
+func writeIssues(w io.Writer, issues []validationIssue) errors.E {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Path != issues[j].Path {
+			return issues[i].Path < issues[j].Path
+		}
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	for _, issue := range issues {
+		_, err := fmt.Fprintln(w, issue.String())
+		if err != nil {
+			return errors.WithMessage(err, "cannot write issues")
+		}
+	}
+	return nil
+}
+
+// validator validates configuration files against descriptions of fields
+// extracted from GitLab's documentation and collects found issues.
+type validator struct {
+	resources []Resource
+	docs      Docs
+	encSuffix string
+	noDecrypt bool
+
+	// descriptions caches descriptions per configuration section.
+	descriptions map[string]map[string]string
+	issues       []validationIssue
+}
+
+// issue records an issue at the node in the configuration file at path.
+func (v *validator) issue(path string, node *yaml.Node, format string, args ...interface{}) {
+	v.issues = append(v.issues, validationIssue{
+		Path:    path,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateFile validates the configuration file at input (or stdin if input is "-")
+// and base configuration files it extends. Seen contains paths of files currently
+// being validated and is used to detect cycles.
+//
+// Issues with the configuration are recorded while an error is returned only
+// if the configuration cannot be read at all.
+func (v *validator) validateFile(ctx context.Context, input string, seen []string) errors.E {
+	if input != "-" {
+		seen = append(slices.Clone(seen), filepath.Clean(kong.ExpandPath(input)))
+	}
+
+	data, errE := readConfigurationData(input, v.noDecrypt)
+	if errE != nil {
+		return errE
+	}
+
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot unmarshal configuration")
+		errors.Details(errE)["path"] = input
+		return errE
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	document := root.Content[0]
+	if document.Kind != yaml.MappingNode {
+		v.issue(input, document, "configuration must be an object")
+		return nil
+	}
+
+	for i := 0; i < len(document.Content); i += 2 {
+		key, value := document.Content[i], document.Content[i+1]
+		name := key.Value
+
+		if strings.HasPrefix(name, "comment:") || name == "sops" {
+			continue
+		}
+
+		if name == extendsKey {
+			errE := v.validateExtends(ctx, input, value, seen)
+			if errE != nil {
+				return errE
+			}
+			continue
+		}
+
+		if name == pruneKey {
+			v.validatePrune(input, value)
+			continue
+		}
+
+		if name == ignoreKey {
+			v.validateIgnore(input, value)
+			continue
+		}
+
+		index := slices.IndexFunc(v.resources, func(r Resource) bool { return r.Name() == name })
+		if index < 0 {
+			v.issue(input, key, "unknown configuration section %q", name)
+			continue
+		}
+
+		errE := v.validateSection(ctx, input, v.resources[index], value)
+		if errE != nil {
+			return errE
+		}
+	}
+
+	return nil
+}
+
+// validatePrune validates that the value of the "prune" key maps
+// names of configuration sections to booleans.
+func (v *validator) validatePrune(path string, value *yaml.Node) {
+	value = resolveAlias(value)
+	if isNull(value) {
+		return
+	}
+	if value.Kind != yaml.MappingNode {
+		v.issue(path, value, "%q must be an object, not %s", pruneKey, nodeType(value))
+		return
+	}
+
+	for i := 0; i < len(value.Content); i += 2 {
+		key, section := value.Content[i], resolveAlias(value.Content[i+1])
+		if !slices.ContainsFunc(v.resources, func(r Resource) bool { return r.Name() == key.Value }) {
+			v.issue(path, key, "unknown configuration section %q in %q", key.Value, pruneKey)
+			continue
+		}
+		if nodeType(section) != "boolean" {
+			v.issue(path, section, "%q for configuration section %q must be a boolean, not %s", pruneKey, key.Value, nodeType(section))
+		}
+	}
+}
+
+// validateIgnore validates that the value of the "ignore" key maps names
+// of configuration sections to lists of ignore rules, each an object
+// mapping field names to valid patterns.
+func (v *validator) validateIgnore(path string, value *yaml.Node) {
+	value = resolveAlias(value)
+	if isNull(value) {
+		return
+	}
+	if value.Kind != yaml.MappingNode {
+		v.issue(path, value, "%q must be an object, not %s", ignoreKey, nodeType(value))
+		return
+	}
+
+	for i := 0; i < len(value.Content); i += 2 {
+		key, rules := value.Content[i], resolveAlias(value.Content[i+1])
+		if !slices.ContainsFunc(v.resources, func(r Resource) bool { return r.Name() == key.Value }) {
+			v.issue(path, key, "unknown configuration section %q in %q", key.Value, ignoreKey)
+			continue
+		}
+		if isNull(rules) {
+			continue
+		}
+		if rules.Kind != yaml.SequenceNode {
+			v.issue(path, rules, "%q for configuration section %q must be an array, not %s", ignoreKey, key.Value, nodeType(rules))
+			continue
+		}
+		for j, rule := range rules.Content {
+			rule = resolveAlias(rule)
+			if rule.Kind != yaml.MappingNode {
+				v.issue(path, rule, "%q rule %d for configuration section %q must be an object, not %s", ignoreKey, j, key.Value, nodeType(rule))
+				continue
+			}
+			for k := 0; k < len(rule.Content); k += 2 {
+				field, pattern := rule.Content[k], resolveAlias(rule.Content[k+1])
+				if pattern.Kind != yaml.ScalarNode || isNull(pattern) {
+					v.issue(path, pattern, "pattern for field %q must be a string, not %s", field.Value, nodeType(pattern))
+					continue
+				}
+				_, errE := compilePattern(pattern.Value)
+				if errE != nil {
+					v.issue(path, pattern, "pattern for field %q is %s", field.Value, errE.Error())
+				}
+			}
+		}
+	}
+}
+
+// validateExtends validates base configuration files listed in the value
+// of the "extends" key in the configuration file at input.
+func (v *validator) validateExtends(ctx context.Context, input string, value *yaml.Node, seen []string) errors.E {
+	var extends interface{}
+	err := value.Decode(&extends)
+	if err != nil {
+		errE := errors.WithMessage(err, "cannot decode extends")
+		errors.Details(errE)["path"] = input
+		return errE
+	}
+	paths, errE := getExtends(map[string]interface{}{extendsKey: extends})
+	if errE != nil {
+		v.issue(input, value, "%s", errE.Error())
+		return nil
+	}
+
+	dir := "."
+	if input != "-" {
+		dir = filepath.Dir(kong.ExpandPath(input))
+	}
+
+	for _, base := range paths {
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(dir, base)
+		}
+		base = filepath.Clean(base)
+		if slices.Contains(seen, base) {
+			v.issue(input, value, "configuration extends itself through %q", base)
+			continue
+		}
+		errE := v.validateFile(ctx, base, seen)
+		if errE != nil {
+			return errE
+		}
+	}
+
+	return nil
+}
+
+// getDescriptions returns descriptions of fields of the resource's configuration section.
+func (v *validator) getDescriptions(ctx context.Context, resource Resource) (map[string]string, errors.E) {
+	descriptions, ok := v.descriptions[resource.Name()]
+	if ok {
+		return descriptions, nil
+	}
+	descriptions, errE := resource.Descriptions(ctx, v.docs)
+	if errE != nil {
+		errors.Details(errE)["section"] = resource.Name()
+		return nil, errE
+	}
+	v.descriptions[resource.Name()] = descriptions
+	return descriptions, nil
+}
+
+// validateSection validates the value of the resource's configuration section
+// in the configuration file at path.
+//
+// The expected shape of the configuration section is determined from the type of the
+// corresponding Configuration field, the same as for the schema command.
+func (v *validator) validateSection(ctx context.Context, path string, resource Resource, value *yaml.Node) errors.E {
+	name := resource.Name()
+
+	descriptions, errE := v.getDescriptions(ctx, resource)
+	if errE != nil {
+		return errE
+	}
+
+	value = resolveAlias(value)
+	if isNull(value) {
+		return nil
+	}
+
+	switch sectionType(name) {
+	case reflect.TypeFor[map[string]interface{}]():
+		if value.Kind != yaml.MappingNode {
+			v.issue(path, value, "configuration section %q must be an object", name)
+			return nil
+		}
+		v.validateObject(path, name, value, descriptions)
+	case reflect.TypeFor[[]map[string]interface{}]():
+		if value.Kind != yaml.SequenceNode {
+			v.issue(path, value, "configuration section %q must be a list", name)
+			return nil
+		}
+		v.validateList(path, resource, value, descriptions)
+	case reflect.TypeFor[*string]():
+		if nodeType(value) != "string" {
+			v.issue(path, value, "configuration section %q must be a string, not %s", name, nodeType(value))
+		}
+	case reflect.TypeFor[interface{}]():
+		// An ID or a full path.
+		if t := nodeType(value); t != "integer" && t != "string" {
+			v.issue(path, value, "configuration section %q must be an integer or a string, not %s", name, t)
+		}
+	case nil:
+		// Configuration sections of registered resources can be an object or a list of objects.
+		if descriptions == nil {
+			return nil
+		}
+		switch value.Kind { //nolint:exhaustive
+		case yaml.MappingNode:
+			v.validateObject(path, name, value, descriptions)
+		case yaml.SequenceNode:
+			v.validateList(path, resource, value, descriptions)
+		default:
+			v.issue(path, value, "configuration section %q must be an object or a list", name)
+		}
+	}
+
+	return nil
+}
+
+// validateList validates objects in the configuration section which is a list of objects,
+// including that objects have identity fields and that no two objects are the same.
+//
+// Identity fields are fields from the resource's Keys, except "id" which is assigned
+// by GitLab and is missing for new objects. When there are multiple sets of identity
+// fields, objects have to have all fields of at least one of them.
+func (v *validator) validateList(path string, resource Resource, list *yaml.Node, descriptions map[string]string) {
+	name := resource.Name()
+	spec := getSectionSpec(resource)
+
+	// Sets of identity fields, at least one of which has to be present.
+	identities := [][]string{}
+	for _, keys := range spec.Keys {
+		identity := slices.DeleteFunc(slices.Clone(keys), func(key string) bool { return key == "id" })
+		if len(identity) > 0 {
+			identities = append(identities, identity)
+		}
+	}
+
+	// For every set of keys we map values of those keys to the first object with them.
+	seen := make([]map[string]*yaml.Node, len(spec.Keys))
+	for i := range seen {
+		seen[i] = map[string]*yaml.Node{}
+	}
+
+	for _, item := range list.Content {
+		item = resolveAlias(item)
+		if item.Kind != yaml.MappingNode {
+			v.issue(path, item, "item in configuration section %q must be an object, not %s", name, nodeType(item))
+			continue
+		}
+
+		v.validateObject(path, name, item, descriptions)
+
+		values := map[string]interface{}{}
+		for i := 0; i < len(item.Content); i += 2 {
+			var value interface{}
+			if item.Content[i+1].Decode(&value) == nil && value != nil {
+				values[v.fieldName(item.Content[i].Value)] = value
+			}
+		}
+
+		v.validateIdentity(path, name, item, values, identities)
+
+	KEYS:
+		for i, keys := range spec.Keys {
+			parts := []string{}
+			for _, key := range keys {
+				value, ok := values[key]
+				if !ok {
+					continue KEYS
+				}
+				parts = append(parts, key+"="+formatValue(value))
+			}
+			key := strings.Join(parts, " ")
+			first, ok := seen[i][key]
+			if ok {
+				v.issue(path, item, "duplicate item in configuration section %q with %s, first at line %d", name, key, first.Line)
+				break
+			}
+			seen[i][key] = item
+		}
+	}
+}
+
+// validateIdentity validates that the object has all fields of at least one of identities.
+func (v *validator) validateIdentity(path, section string, item *yaml.Node, values map[string]interface{}, identities [][]string) {
+	if len(identities) == 0 {
+		return
+	}
+
+	missing := []string{}
+	for _, identity := range identities {
+		m := []string{}
+		for _, field := range identity {
+			if _, ok := values[field]; !ok {
+				m = append(m, field)
+			}
+		}
+		if len(m) == 0 {
+			return
+		}
+		if len(identities) == 1 {
+			missing = m
+		} else {
+			quoted := []string{}
+			for _, field := range identity {
+				quoted = append(quoted, strconv.Quote(field))
+			}
+			missing = append(missing, strings.Join(quoted, " and "))
+		}
+	}
+
+	if len(identities) == 1 {
+		for _, field := range missing {
+			v.issue(path, item, "item in configuration section %q is missing field %q", section, field)
+		}
+		return
+	}
+	v.issue(path, item, "item in configuration section %q is missing field %s", section, strings.Join(missing, " or "))
+}
+
+// validateObject validates that all fields of the object are known and have the expected type.
+//
+// Fields with "comment:" prefix are ignored. Values encrypted with SOPS are not type checked.
+func (v *validator) validateObject(path, section string, object *yaml.Node, descriptions map[string]string) {
+	if descriptions == nil {
+		return
+	}
+
+	for i := 0; i < len(object.Content); i += 2 {
+		key, value := object.Content[i], resolveAlias(object.Content[i+1])
+		if strings.HasPrefix(key.Value, "comment:") {
+			continue
+		}
+		field := v.fieldName(key.Value)
+
+		description, ok := descriptions[field]
+		if !ok {
+			suggestion := closestField(field, descriptions)
+			if suggestion != "" {
+				v.issue(path, key, "unknown field %q in configuration section %q, did you mean %q?", field, section, suggestion)
+			} else {
+				v.issue(path, key, "unknown field %q in configuration section %q", field, section)
+			}
+			continue
+		}
+
+		if isNull(value) || (value.Kind == yaml.ScalarNode && strings.HasPrefix(value.Value, "ENC[")) {
+			continue
+		}
+		_, types := descriptionTypes(description)
+		if types == nil {
+			continue
+		}
+		t := nodeType(value)
+		if !slices.Contains(types, t) && (t != "integer" || !slices.Contains(types, "number")) {
+			v.issue(path, value, "field %q in configuration section %q must be %s, not %s", field, section, strings.Join(types, " or "), t)
+		}
+	}
+}
+
+// fieldName returns the name of the field with encSuffix removed.
+func (v *validator) fieldName(key string) string {
+	if v.encSuffix != "" {
+		return strings.TrimSuffix(key, v.encSuffix)
+	}
+	return key
+}
+
+// resolveAlias returns the node the alias node points to, or the node itself.
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// isNull returns true if the node is a null value.
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
+
+// nodeType returns the JSON Schema type of the node's value.
+func nodeType(node *yaml.Node) string {
+	node = resolveAlias(node)
+	switch node.Kind { //nolint:exhaustive
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	default:
+		return "string"
+	}
+}
+
+// closestField returns the field from descriptions which is the closest to the field,
+// or an empty string if no field is close enough.
+func closestField(field string, descriptions map[string]string) string {
+	closest := ""
+	closestDistance := maxSuggestionDistance + 1
+	for f := range descriptions {
+		d := editDistance(field, f)
+		if d < closestDistance || (d == closestDistance && f < closest) {
+			closest = f
+			closestDistance = d
+		}
+	}
+	if closestDistance > maxSuggestionDistance {
+		return ""
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
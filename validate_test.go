@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.yml"), []byte(""+
+		"protected_branches:\n"+
+		"  - allow_force_push: false\n",
+	), 0o600))
+	input := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(input, []byte(""+
+		"extends: base.yml\n"+
+		"project:\n"+
+		"  only_allow_merge_if_pipline_succeeds: true\n"+
+		"  description: 123\n"+
+		"  lfs_enabled: null\n"+
+		"labels:\n"+
+		"  - name: bug\n"+
+		"    color: '#FF0000'\n"+
+		"  - name: bug\n"+
+		"    color: red\n"+
+		"  - color: blue\n"+
+		"variables:\n"+
+		"  - key: FOO\n"+
+		"    value_sops: ENC[AES256_GCM,data:abc,type:str]\n"+
+		"    environment_scope: '*'\n"+
+		"  - key: BAR\n"+
+		"    value: bar\n"+
+		"unknown: true\n"+
+		"avatar: 5\n"+
+		"sops:\n"+
+		"  version: 3.7.3\n",
+	), 0o600))
+
+	v := validator{
+		resources:    Resources(),
+		docs:         Docs{Ref: DefaultDocsRef, Dir: "", CacheDir: ""},
+		encSuffix:    "_sops",
+		noDecrypt:    true,
+		descriptions: map[string]map[string]string{},
+		issues:       []validationIssue{},
+	}
+	errE := v.validateFile(t.Context(), input, nil)
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	var buffer bytes.Buffer
+	errE = writeIssues(&buffer, v.issues)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, ""+
+		input+`:3:3: unknown field "only_allow_merge_if_pipline_succeeds" in configuration section "project", did you mean "only_allow_merge_if_pipeline_succeeds"?`+"\n"+
+		input+`:4:16: field "description" in configuration section "project" must be string, not integer`+"\n"+
+		input+`:9:5: duplicate item in configuration section "labels" with name="bug", first at line 7`+"\n"+
+		input+`:11:5: item in configuration section "labels" is missing field "name"`+"\n"+
+		input+`:16:5: item in configuration section "variables" is missing field "environment_scope"`+"\n"+
+		input+`:18:1: unknown configuration section "unknown"`+"\n"+
+		input+`:19:9: configuration section "avatar" must be a string, not integer`+"\n"+
+		filepath.Join(dir, "base.yml")+`:2:5: item in configuration section "protected_branches" is missing field "name"`+"\n",
+		buffer.String(),
+	)
+}
+
+func TestValidateIdentities(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(input, []byte(""+
+		"shared_with_groups:\n"+
+		"  - group: acme/devs\n"+
+		"    group_access: 30\n"+
+		"  - group_id: 7\n"+
+		"    group_access: 30\n"+
+		"  - group_access: 30\n"+
+		"forked_from_project: acme/upstream\n",
+	), 0o600))
+
+	v := validator{
+		resources:    Resources(),
+		docs:         Docs{Ref: DefaultDocsRef, Dir: "", CacheDir: ""},
+		encSuffix:    "",
+		noDecrypt:    true,
+		descriptions: map[string]map[string]string{},
+		issues:       []validationIssue{},
+	}
+	errE := v.validateFile(t.Context(), input, nil)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	require.Len(t, v.issues, 1)
+	assert.Equal(t, 6, v.issues[0].Line)
+	assert.Equal(t, `item in configuration section "shared_with_groups" is missing field "group_id" or "group"`, v.issues[0].Message)
+}
+
+func TestValidatePrune(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(input, []byte(""+
+		"prune:\n"+
+		"  variables: false\n"+
+		"  labels: no\n"+
+		"  lables: false\n",
+	), 0o600))
+
+	v := validator{
+		resources:    Resources(),
+		docs:         Docs{Ref: DefaultDocsRef, Dir: "", CacheDir: ""},
+		encSuffix:    "",
+		noDecrypt:    true,
+		descriptions: map[string]map[string]string{},
+		issues:       []validationIssue{},
+	}
+	errE := v.validateFile(t.Context(), input, nil)
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	var buffer bytes.Buffer
+	errE = writeIssues(&buffer, v.issues)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, ""+
+		input+`:3:11: "prune" for configuration section "labels" must be a boolean, not string`+"\n"+
+		input+`:4:3: unknown configuration section "lables" in "prune"`+"\n",
+		buffer.String(),
+	)
+}
+
+func TestValidateIgnore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(input, []byte(""+
+		"ignore:\n"+
+		"  variables:\n"+
+		"    - key: DEPLOY_*\n"+
+		"      environment_scope: /^prod/\n"+
+		"    - key: /(/\n"+
+		"  labels:\n"+
+		"    - name: [a]\n"+
+		"  lables: []\n"+
+		"  protected_tags: foo\n",
+	), 0o600))
+
+	v := validator{
+		resources:    Resources(),
+		docs:         Docs{Ref: DefaultDocsRef, Dir: "", CacheDir: ""},
+		encSuffix:    "",
+		noDecrypt:    true,
+		descriptions: map[string]map[string]string{},
+		issues:       []validationIssue{},
+	}
+	errE := v.validateFile(t.Context(), input, nil)
+	require.NoError(t, errE, "% -+#.1v", errE)
+
+	var buffer bytes.Buffer
+	errE = writeIssues(&buffer, v.issues)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, ""+
+		input+`:5:12: pattern for field "key" is invalid regular expression: error parsing regexp: missing closing ): `+"`(`"+"\n"+
+		input+`:7:13: pattern for field "name" must be a string, not array`+"\n"+
+		input+`:8:3: unknown configuration section "lables" in "ignore"`+"\n"+
+		input+`:9:19: "ignore" for configuration section "protected_tags" must be an array, not string`+"\n",
+		buffer.String(),
+	)
+}
+
+func TestValidateExtendsItself(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, ".gitlab-conf.yml")
+	require.NoError(t, os.WriteFile(input, []byte("extends: .gitlab-conf.yml\n"), 0o600))
+
+	v := validator{
+		resources:    Resources(),
+		docs:         Docs{Ref: DefaultDocsRef, Dir: "", CacheDir: ""},
+		encSuffix:    "",
+		noDecrypt:    true,
+		descriptions: map[string]map[string]string{},
+		issues:       []validationIssue{},
+	}
+	errE := v.validateFile(t.Context(), input, nil)
+	require.NoError(t, errE, "% -+#.1v", errE)
+	require.Len(t, v.issues, 1)
+	assert.Equal(t, `configuration extends itself through "`+input+`"`, v.issues[0].Message)
+}
+
+func TestClosestField(t *testing.T) {
+	t.Parallel()
+
+	descriptions := map[string]string{"name": "", "color": "", "description": ""}
+	assert.Equal(t, "color", closestField("colour", descriptions))
+	assert.Equal(t, "name", closestField("nme", descriptions))
+	assert.Equal(t, "", closestField("priority", descriptions))
+}
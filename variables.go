@@ -2,9 +2,9 @@ package config
 
 import (
 	"cmp"
+	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"slices"
 	"sort"
 
@@ -14,6 +14,105 @@ import (
 	"gitlab.com/tozd/go/errors"
 )
 
+// variablesEndpoint describes GitLab API endpoint for CI/CD variables of
+// a project or of a group, together with its documentation.
+type variablesEndpoint struct {
+	// Owner is "project" or "group".
+	Owner string
+	// Doc is the file with GitLab's documentation of the API endpoint.
+	Doc string
+	// Create is the heading of documentation section describing creating a variable.
+	Create string
+}
+
+//nolint:gochecknoglobals
+var (
+	projectVariables = variablesEndpoint{
+		Owner:  "project",
+		Doc:    "project_level_variables.md",
+		Create: "Create a variable",
+	}
+	groupVariables = variablesEndpoint{
+		Owner:  "group",
+		Doc:    "group_level_variables.md",
+		Create: "Create variable",
+	}
+)
+
+// variablesResource is a Resource for project CI/CD variables.
+type variablesResource struct{}
+
+// Name implements Resource interface.
+func (variablesResource) Name() string {
+	return "variables"
+}
+
+// Descriptions implements Resource interface.
+func (variablesResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getVariablesDescriptions(ctx, docs, projectVariables)
+}
+
+// Sensitive implements Resource interface.
+func (variablesResource) Sensitive() []string {
+	return []string{"value"}
+}
+
+// Keys implements KeyedResource interface.
+func (variablesResource) Keys() [][]string {
+	return [][]string{{"key", "environment_scope"}}
+}
+
+// Describe implements KeyedResource interface.
+func (variablesResource) Describe() []string {
+	return nil
+}
+
+// KeyDefaults implements DefaultedResource interface.
+func (variablesResource) KeyDefaults() map[string]interface{} {
+	return map[string]interface{}{"environment_scope": "*"}
+}
+
+// Required implements RequiredResource interface.
+func (variablesResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getVariablesRequired(ctx, docs, projectVariables)
+}
+
+// Get implements Resource interface.
+func (variablesResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getVariables(ctx, client, configuration, projectVariables)
+}
+
+// Update implements Resource interface.
+func (variablesResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateVariables(ctx, client, configuration, projectVariables)
+}
+
+// groupVariablesResource is a Resource for group CI/CD variables. It differs
+// from variablesResource only in the API endpoint used.
+type groupVariablesResource struct {
+	variablesResource
+}
+
+// Descriptions implements Resource interface.
+func (groupVariablesResource) Descriptions(ctx context.Context, docs Docs) (map[string]string, errors.E) {
+	return getVariablesDescriptions(ctx, docs, groupVariables)
+}
+
+// Required implements RequiredResource interface.
+func (groupVariablesResource) Required(ctx context.Context, docs Docs) ([]string, errors.E) {
+	return getVariablesRequired(ctx, docs, groupVariables)
+}
+
+// Get implements Resource interface.
+func (groupVariablesResource) Get(ctx context.Context, g *Getter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return g.getVariables(ctx, client, configuration, groupVariables)
+}
+
+// Update implements Resource interface.
+func (groupVariablesResource) Update(ctx context.Context, s *Setter, client *gitlab.Client, configuration *Configuration) errors.E {
+	return s.updateVariables(ctx, client, configuration, groupVariables)
+}
+
 type filter struct {
 	EnvironmentScope string `url:"environment_scope"`
 }
@@ -23,34 +122,34 @@ type opts struct {
 }
 
 // getVariables populates configuration struct with configuration available
-// from GitLab project level variables API endpoint.
-func (c *GetCommand) getVariables(client *gitlab.Client, configuration *Configuration) (bool, errors.E) {
-	fmt.Fprintf(os.Stderr, "Getting project variables...\n")
+// from GitLab project or group level variables API endpoint.
+func (g *Getter) getVariables(ctx context.Context, client *gitlab.Client, configuration *Configuration, endpoint variablesEndpoint) errors.E {
+	g.Printf("Getting %s variables...\n", endpoint.Owner)
 
 	configuration.Variables = []map[string]interface{}{}
 
-	descriptions, errE := getVariablesDescriptions(c.DocsRef)
+	descriptions, errE := getVariablesDescriptions(ctx, g.Docs, endpoint)
 	if errE != nil {
-		return false, errE
+		return errE
 	}
 	// We need "key" later on.
 	if _, ok := descriptions["key"]; !ok {
-		return false, errors.New(`"key" field is missing in project variables descriptions`)
+		return errors.Errorf(`"key" field is missing in %s variables descriptions`, endpoint.Owner)
 	}
 	configuration.VariablesComment = formatDescriptions(descriptions)
 
-	u := fmt.Sprintf("projects/%s/variables", gitlab.PathEscape(c.Project))
-	options := &gitlab.ListProjectVariablesOptions{
+	u := g.OwnerPath(endpoint.Owner) + "/variables"
+	options := &gitlab.ListOptions{
 		PerPage: maxGitLabPageSize,
 		Page:    1,
 	}
 
 	for {
-		req, err := client.NewRequest(http.MethodGet, u, options, nil)
+		req, err := client.NewRequest(http.MethodGet, u, options, contextOptions(ctx))
 		if err != nil {
-			errE := errors.WithMessage(err, "failed to get project variables")
+			errE := errors.WithMessagef(err, "failed to get %s variables", endpoint.Owner)
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		variables := []map[string]interface{}{}
@@ -61,9 +160,9 @@ func (c *GetCommand) getVariables(client *gitlab.Client, configuration *Configur
 			if response.StatusCode == http.StatusForbidden && options.Page == 1 {
 				break
 			}
-			errE := errors.WithMessage(err, "failed to get project variables")
+			errE := errors.WithMessagef(err, "failed to get %s variables", endpoint.Owner)
 			errors.Details(errE)["page"] = options.Page
-			return false, errE
+			return errE
 		}
 
 		if len(variables) == 0 {
@@ -80,24 +179,16 @@ func (c *GetCommand) getVariables(client *gitlab.Client, configuration *Configur
 				}
 			}
 
-			if c.EncComment != "" {
-				variable["comment:value"+c.EncSuffix] = c.EncComment
-			}
-			if c.EncSuffix != "" {
-				variable["value"+c.EncSuffix] = variable["value"]
-				delete(variable, "value")
-			}
-
 			key, ok := variable["key"]
 			if !ok {
-				return false, errors.New(`project variable is missing field "key"`)
+				return errors.Errorf(`%s variable is missing field "key"`, endpoint.Owner)
 			}
 			_, ok = key.(string)
 			if !ok {
-				errE := errors.New(`project variable's field "key" is not a string`)
+				errE := errors.Errorf(`%s variable's field "key" is not a string`, endpoint.Owner)
 				errors.Details(errE)["type"] = fmt.Sprintf("%T", key)
 				errors.Details(errE)["value"] = key
-				return false, errE
+				return errE
 			}
 
 			configuration.Variables = append(configuration.Variables, variable)
@@ -116,36 +207,46 @@ func (c *GetCommand) getVariables(client *gitlab.Client, configuration *Configur
 		return configuration.Variables[i]["key"].(string) < configuration.Variables[j]["key"].(string) //nolint:forcetypeassert,errcheck
 	})
 
-	return len(configuration.Variables) > 0, nil
+	return nil
 }
 
 // parseVariablesDocumentation parses GitLab's documentation in Markdown for
-// project level variables API endpoint and extracts description of fields
+// project or group level variables API endpoint and extracts description of fields
 // used to describe an individual variable.
-func parseVariablesDocumentation(input []byte) (map[string]string, errors.E) {
-	return parseTable(input, "Create a variable", nil)
+func parseVariablesDocumentation(input []byte, endpoint variablesEndpoint) (map[string]string, errors.E) {
+	return parseTable(input, endpoint.Create, nil)
 }
 
 // getVariablesDescriptions obtains description of fields used to describe an individual
-// variable from GitLab's documentation for project level variables API endpoint.
-func getVariablesDescriptions(gitRef string) (map[string]string, errors.E) {
-	data, err := downloadFile(fmt.Sprintf("https://gitlab.com/gitlab-org/gitlab/-/raw/%s/doc/api/project_level_variables.md", gitRef))
+// variable from GitLab's documentation for project or group level variables API endpoint.
+func getVariablesDescriptions(ctx context.Context, docs Docs, endpoint variablesEndpoint) (map[string]string, errors.E) {
+	data, err := docs.Get(ctx, endpoint.Doc)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get %s variables descriptions", endpoint.Owner)
+	}
+	return parseVariablesDocumentation(data, endpoint)
+}
+
+// getVariablesRequired obtains fields required to create an individual variable
+// from GitLab's documentation for project or group level variables API endpoint.
+func getVariablesRequired(ctx context.Context, docs Docs, endpoint variablesEndpoint) ([]string, errors.E) {
+	data, err := docs.Get(ctx, endpoint.Doc)
 	if err != nil {
-		return nil, errors.WithMessage(err, "failed to get project variables descriptions")
+		return nil, errors.WithMessagef(err, "failed to get %s variables required fields", endpoint.Owner)
 	}
-	return parseVariablesDocumentation(data)
+	return parseRequired(data, endpoint.Create, nil)
 }
 
-// updateVariables updates GitLab project's variables using GitLab project level
-// variables API endpoint based on the configuration struct.
-func (c *SetCommand) updateVariables(client *gitlab.Client, configuration *Configuration) errors.E {
+// updateVariables updates GitLab project's or group's variables using GitLab
+// project or group level variables API endpoint based on the configuration struct.
+func (s *Setter) updateVariables(ctx context.Context, client *gitlab.Client, configuration *Configuration, endpoint variablesEndpoint) errors.E {
 	if configuration.Variables == nil {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Updating project variables...\n")
+	s.Printf("Updating %s variables...\n", endpoint.Owner)
 
-	options := &gitlab.ListProjectVariablesOptions{
+	options := &gitlab.ListOptions{
 		PerPage: maxGitLabPageSize,
 		Page:    1,
 	}
@@ -153,9 +254,19 @@ func (c *SetCommand) updateVariables(client *gitlab.Client, configuration *Confi
 	variables := []*gitlab.ProjectVariable{}
 
 	for {
-		vs, response, err := client.ProjectVariables.ListVariables(c.Project, options)
+		// We do not use go-gitlab's functions because they differ between projects and groups.
+		req, err := client.NewRequest(http.MethodGet, s.OwnerPath(endpoint.Owner)+"/variables", options, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessagef(err, "failed to get %s variables", endpoint.Owner)
+			errors.Details(errE)["page"] = options.Page
+			return errE
+		}
+
+		vs := []*gitlab.ProjectVariable{}
+
+		response, err := client.Do(req, &vs)
 		if err != nil {
-			errE := errors.WithMessage(err, "failed to get project variables")
+			errE := errors.WithMessagef(err, "failed to get %s variables", endpoint.Owner)
 			errors.Details(errE)["page"] = options.Page
 			return errE
 		}
@@ -176,6 +287,10 @@ func (c *SetCommand) updateVariables(client *gitlab.Client, configuration *Confi
 
 	existingVariablesSet := mapset.NewThreadUnsafeSet[Variable]()
 	for _, variable := range variables {
+		// Variables matching ignore rules are neither updated nor deleted.
+		if configuration.ignored("variables", map[string]interface{}{"key": variable.Key, "environment_scope": variable.EnvironmentScope}) {
+			continue
+		}
 		existingVariablesSet.Add(Variable{
 			Key:              variable.Key,
 			EnvironmentScope: variable.EnvironmentScope,
@@ -186,13 +301,13 @@ func (c *SetCommand) updateVariables(client *gitlab.Client, configuration *Confi
 	for i, variable := range configuration.Variables {
 		key, ok := variable["key"]
 		if !ok {
-			errE := errors.Errorf(`project variable is missing field "key"`)
+			errE := errors.Errorf(`%s variable is missing field "key"`, endpoint.Owner)
 			errors.Details(errE)["index"] = i
 			return errE
 		}
 		k, ok := key.(string)
 		if !ok {
-			errE := errors.New(`project variable's field "key" is not a string`)
+			errE := errors.Errorf(`%s variable's field "key" is not a string`, endpoint.Owner)
 			errors.Details(errE)["index"] = i
 			errors.Details(errE)["type"] = fmt.Sprintf("%T", key)
 			errors.Details(errE)["value"] = key
@@ -200,13 +315,13 @@ func (c *SetCommand) updateVariables(client *gitlab.Client, configuration *Confi
 		}
 		environmentScope, ok := variable["environment_scope"]
 		if !ok {
-			errE := errors.Errorf(`project variable is missing field "environment_scope"`)
+			errE := errors.Errorf(`%s variable is missing field "environment_scope"`, endpoint.Owner)
 			errors.Details(errE)["index"] = i
 			return errE
 		}
 		e, ok := environmentScope.(string)
 		if !ok {
-			errE := errors.New(`project variable's field "environment_scope" is not a string`)
+			errE := errors.Errorf(`%s variable's field "environment_scope" is not a string`, endpoint.Owner)
 			errors.Details(errE)["index"] = i
 			errors.Details(errE)["type"] = fmt.Sprintf("%T", environmentScope)
 			errors.Details(errE)["value"] = environmentScope
@@ -219,6 +334,10 @@ func (c *SetCommand) updateVariables(client *gitlab.Client, configuration *Confi
 	}
 
 	extraVariables := existingVariablesSet.Difference(wantedVariablesSet).ToSlice()
+	if !configuration.prune("variables", s.NoPrune) {
+		// Objects missing from the configuration are kept.
+		extraVariables = nil
+	}
 	slices.SortFunc(extraVariables, func(a Variable, b Variable) int {
 		res := cmp.Compare(a.Key, b.Key)
 		if res != 0 {
@@ -228,14 +347,19 @@ func (c *SetCommand) updateVariables(client *gitlab.Client, configuration *Confi
 		return cmp.Compare(a.EnvironmentScope, b.EnvironmentScope)
 	})
 	for _, variable := range extraVariables {
-		_, err := client.ProjectVariables.RemoveVariable(
-			c.Project,
-			variable.Key,
-			&gitlab.RemoveProjectVariableOptions{Filter: &gitlab.VariableFilter{EnvironmentScope: variable.EnvironmentScope}},
-			nil,
-		)
+		// We do not use go-gitlab's functions because GroupVariables.RemoveVariable
+		// does not support filtering by environment scope.
+		u := fmt.Sprintf("%s/variables/%s", s.OwnerPath(endpoint.Owner), gitlab.PathEscape(variable.Key))
+		req, err := client.NewRequest(http.MethodDelete, u, opts{filter{variable.EnvironmentScope}}, contextOptions(ctx))
+		if err != nil {
+			errE := errors.WithMessagef(err, "failed to remove %s variable", endpoint.Owner)
+			errors.Details(errE)["key"] = variable.Key
+			errors.Details(errE)["environmentScope"] = variable.EnvironmentScope
+			return errE
+		}
+		_, err = client.Do(req, nil)
 		if err != nil {
-			errE := errors.WithMessage(err, "failed to remove project variable")
+			errE := errors.WithMessagef(err, "failed to remove %s variable", endpoint.Owner)
 			errors.Details(errE)["key"] = variable.Key
 			errors.Details(errE)["environmentScope"] = variable.EnvironmentScope
 			return errE
@@ -252,42 +376,46 @@ func (c *SetCommand) updateVariables(client *gitlab.Client, configuration *Confi
 			EnvironmentScope: environmentScope,
 		}) {
 			// Update existing variable.
-			u := fmt.Sprintf("projects/%s/variables/%s", gitlab.PathEscape(c.Project), gitlab.PathEscape(key))
-			req, err := client.NewRequest(http.MethodPut, u, variable, nil)
+			u := fmt.Sprintf("%s/variables/%s", s.OwnerPath(endpoint.Owner), gitlab.PathEscape(key))
+			req, err := client.NewRequest(http.MethodPut, u, variable, contextOptions(ctx))
 			if err != nil {
-				errE := errors.WithMessage(err, "failed to update project variable")
+				errE := errors.WithMessagef(err, "failed to update %s variable", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["key"] = key
 				errors.Details(errE)["environmentScope"] = environmentScope
+				return errE
 			}
 			q, err := query.Values(opts{filter{environmentScope}})
 			if err != nil {
-				errE := errors.WithMessage(err, "failed to update project variable")
+				errE := errors.WithMessagef(err, "failed to update %s variable", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["key"] = key
 				errors.Details(errE)["environmentScope"] = environmentScope
+				return errE
 			}
 			req.URL.RawQuery = q.Encode()
 			_, err = client.Do(req, nil)
 			if err != nil {
-				errE := errors.WithMessage(err, "failed to update project variable")
+				errE := errors.WithMessagef(err, "failed to update %s variable", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["key"] = key
 				errors.Details(errE)["environmentScope"] = environmentScope
+				return errE
 			}
 		} else {
 			// Create new variable.
-			u := fmt.Sprintf("projects/%s/variables", gitlab.PathEscape(c.Project))
-			req, err := client.NewRequest(http.MethodPost, u, variable, nil)
+			u := s.OwnerPath(endpoint.Owner) + "/variables"
+			req, err := client.NewRequest(http.MethodPost, u, variable, contextOptions(ctx))
 			if err != nil {
-				errE := errors.WithMessage(err, "failed to create project variable")
+				errE := errors.WithMessagef(err, "failed to create %s variable", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["key"] = key
 				errors.Details(errE)["environmentScope"] = environmentScope
+				return errE
 			}
 			_, err = client.Do(req, nil)
 			if err != nil {
-				errE := errors.WithMessage(err, "failed to create project variable")
+				errE := errors.WithMessagef(err, "failed to create %s variable", endpoint.Owner)
 				errors.Details(errE)["index"] = i
 				errors.Details(errE)["key"] = key
 				errors.Details(errE)["environmentScope"] = environmentScope
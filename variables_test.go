@@ -16,7 +16,7 @@ var testVariables []byte
 func TestParseVariablesDocumentation(t *testing.T) {
 	t.Parallel()
 
-	data, errE := parseVariablesDocumentation(testVariables)
+	data, errE := parseVariablesDocumentation(testVariables, projectVariables)
 	require.NoError(t, errE, "% -+#.1v", errE)
 	assert.Equal(t, map[string]string{
 		"environment_scope": "The environment_scope of the variable. Default: *. Type: string",
@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"slices"
 	"sort"
 	"strings"
 
@@ -34,16 +35,36 @@ func formatDescriptions(descriptions map[string]string) string {
 
 // toConfigurationYAML returns configuration as YAML.
 //
-// YAML contains configuration comments.
-func toConfigurationYAML(configuration *Configuration) ([]byte, errors.E) {
+// YAML contains configuration comments. Configuration sections listed in exclude
+// (and their comments) are omitted.
+func toConfigurationYAML(configuration *Configuration, exclude []string) ([]byte, errors.E) {
 	var node yaml.Node
 	err := (&node).Encode(configuration)
 	if err != nil {
 		return nil, errors.WithMessage(err, "cannot encode configuration")
 	}
+	removeYAMLSections(&node, exclude)
 	return toYAML(&node)
 }
 
+// removeYAMLSections removes configuration sections with names and their
+// comments from the top-level YAML mapping node.
+func removeYAMLSections(node *yaml.Node, names []string) {
+	if len(names) == 0 || node.Kind != yaml.MappingNode {
+		return
+	}
+
+	content := []*yaml.Node{}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := strings.TrimPrefix(node.Content[i].Value, "comment:")
+		if slices.Contains(names, key) {
+			continue
+		}
+		content = append(content, node.Content[i], node.Content[i+1])
+	}
+	node.Content = content
+}
+
 // setYAMLComments modifies YAML node by moving comments in children nodes which have
 // "comment:" prefix in object field names to corresponding data fields (and their nodes).
 func setYAMLComments(node *yaml.Node) {
@@ -57,7 +57,12 @@ func TestToConfigurationYAML(t *testing.T) {
 				"protected_branches: []\n" +
 				"protected_tags: []\n" +
 				"variables: []\n" +
-				"pipeline_schedules: []\n",
+				"pipeline_schedules: []\n" +
+				"hooks: []\n" +
+				"deploy_keys: []\n" +
+				"deploy_tokens: []\n" +
+				"project_access_tokens: []\n" +
+				"members: []\n",
 		},
 		{
 			&Configuration{
@@ -124,7 +129,12 @@ func TestToConfigurationYAML(t *testing.T) {
 				"protected_branches: []\n" +
 				"protected_tags: []\n" +
 				"variables: []\n" +
-				"pipeline_schedules: []\n",
+				"pipeline_schedules: []\n" +
+				"hooks: []\n" +
+				"deploy_keys: []\n" +
+				"deploy_tokens: []\n" +
+				"project_access_tokens: []\n" +
+				"members: []\n",
 		},
 	}
 
@@ -132,9 +142,20 @@ func TestToConfigurationYAML(t *testing.T) {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
 			t.Parallel()
 
-			data, errE := toConfigurationYAML(tt.config)
+			data, errE := toConfigurationYAML(tt.config, nil)
 			require.NoError(t, errE, "% -+#.1v", errE)
 			assert.Equal(t, tt.output, string(data))
 		})
 	}
 }
+
+func TestToConfigurationYAMLExclude(t *testing.T) {
+	t.Parallel()
+
+	data, errE := toConfigurationYAML(&Configuration{
+		LabelsComment: "Labels.",
+		Labels:        []map[string]interface{}{{"name": "bug"}},
+	}, []string{"project", "avatar", "shared_with_groups", "approvals", "approval_rules", "push_rules", "forked_from_project", "protected_branches", "protected_tags", "variables", "pipeline_schedules", "hooks", "deploy_keys", "deploy_tokens", "project_access_tokens", "members"})
+	require.NoError(t, errE, "% -+#.1v", errE)
+	assert.Equal(t, "# Labels.\nlabels:\n  - name: bug\n", string(data))
+}